@@ -0,0 +1,47 @@
+package blob
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDir(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	store := NewDir(filepath.Join(root, "blobs"))
+
+	require.NoError(t, store.Put(ctx, "orders/o-1/photo.jpg", strings.NewReader("first")))
+	require.NoError(t, store.Put(ctx, "orders/o-1/photo.jpg", strings.NewReader("second")))
+
+	blob, err := store.Open(ctx, "orders/o-1/photo.jpg")
+	require.NoError(t, err)
+	content, err := io.ReadAll(blob)
+	blob.Close()
+	require.NoError(t, err)
+	assert.Equal(t, "second", string(content), "putting a key again replaces the blob")
+
+	entries, err := os.ReadDir(filepath.Join(root, "blobs", "orders", "o-1"))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "no temporary files are left behind")
+
+	require.NoError(t, store.Delete(ctx, "orders/o-1/photo.jpg"))
+	require.NoError(t, store.Delete(ctx, "orders/o-1/photo.jpg"))
+	_, err = store.Open(ctx, "orders/o-1/photo.jpg")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestDirInvalidKeys(t *testing.T) {
+	store := NewDir(t.TempDir())
+	for _, key := range []string{"", "/etc/passwd", "../outside", "orders/../../outside", "orders//photo.jpg", `orders\photo.jpg`, ".."} {
+		assert.ErrorIs(t, store.Put(context.Background(), key, strings.NewReader("x")), ErrInvalidKey, key)
+		_, err := store.Open(context.Background(), key)
+		assert.ErrorIs(t, err, ErrInvalidKey, key)
+	}
+}
@@ -0,0 +1,233 @@
+// Package bulkimport streams orders from JSONL or CSV documents through the
+// same validation and creation path as a single placed order.
+package bulkimport
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"weservefood/models"
+	"weservefood/repository"
+	"weservefood/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Format is the encoding of an import document
+type Format string
+
+const (
+	FormatJSONL Format = "jsonl"
+	FormatCSV   Format = "csv"
+)
+
+var tracer = tracing.Tracer("weservefood/bulkimport")
+
+// maxLineSize bounds a single JSONL record
+const maxLineSize = 1024 * 1024
+
+// ParseFormat accepts a format name or a matching content type
+func ParseFormat(value string) (Format, error) {
+	switch strings.ToLower(strings.TrimSpace(strings.Split(value, ";")[0])) {
+	case "jsonl", "ndjson", "application/x-ndjson", "application/jsonl":
+		return FormatJSONL, nil
+	case "csv", "text/csv":
+		return FormatCSV, nil
+	default:
+		return "", fmt.Errorf("unsupported import format %q", value)
+	}
+}
+
+// Options controls an import run
+type Options struct {
+	// DryRun validates every record without creating orders
+	DryRun bool
+	// ResumeAfter skips every record on or before this line, so an interrupted
+	// import can be restarted from the last line it reported
+	ResumeAfter int
+}
+
+// LineResult is the outcome of importing one record
+type LineResult struct {
+	Line    int    `json:"line"`
+	OK      bool   `json:"ok"`
+	OrderID string `json:"order_id,omitempty"`
+	// DeliveryPIN is the placed order's PIN, for the importer to pass on to the customer
+	DeliveryPIN string `json:"delivery_pin,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// Summary totals an import run
+type Summary struct {
+	Succeeded int  `json:"succeeded"`
+	Failed    int  `json:"failed"`
+	Skipped   int  `json:"skipped"`
+	LastLine  int  `json:"last_line"`
+	DryRun    bool `json:"dry_run"`
+}
+
+// record is one decoded order, or the error decoding it
+type record struct {
+	line  int
+	order models.Order
+	err   error
+}
+
+// Import reads orders from r one record at a time, validating and (unless
+// DryRun) creating each, and calls report with the outcome of every record.
+// Malformed records are reported as failures; only an unreadable document or
+// an error returned by report stops the import.
+func Import(ctx context.Context, r io.Reader, format Format, opts Options, report func(LineResult) error) (_ Summary, err error) {
+	ctx, span := tracer.Start(ctx, "bulkimport.Import", trace.WithAttributes(
+		attribute.String("import.format", string(format)),
+		attribute.Bool("import.dry_run", opts.DryRun),
+	))
+	defer func() { tracing.End(span, err) }()
+
+	summary := Summary{DryRun: opts.DryRun}
+	defer func() {
+		span.SetAttributes(
+			attribute.Int("import.succeeded", summary.Succeeded),
+			attribute.Int("import.failed", summary.Failed),
+			attribute.Int("import.skipped", summary.Skipped),
+		)
+	}()
+
+	handle := func(rec record) error {
+		summary.LastLine = rec.line
+		if rec.line <= opts.ResumeAfter {
+			summary.Skipped++
+			return nil
+		}
+
+		result := LineResult{Line: rec.line}
+		err := rec.err
+		if err == nil {
+			if opts.DryRun {
+				err = repository.ValidateOrder(ctx, rec.order)
+			} else {
+				var order models.Order
+				if order, err = repository.CreateOrder(ctx, rec.order); err == nil {
+					result.OrderID = order.ID
+					result.DeliveryPIN = order.DeliveryPIN
+				}
+			}
+		}
+
+		if err != nil {
+			summary.Failed++
+			result.Error = err.Error()
+		} else {
+			summary.Succeeded++
+			result.OK = true
+		}
+		return report(result)
+	}
+
+	switch format {
+	case FormatJSONL:
+		err = readJSONL(r, handle)
+	case FormatCSV:
+		err = readCSV(r, handle)
+	default:
+		err = fmt.Errorf("unsupported import format %q", format)
+	}
+
+	return summary, err
+}
+
+// readJSONL decodes one order per non-blank line
+func readJSONL(r io.Reader, handle func(record) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		rec := record{line: line}
+		rec.err = json.Unmarshal([]byte(text), &rec.order)
+		if err := handle(rec); err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
+
+// readCSV decodes one order per row after a header naming the columns
+// name, email, address, items and restaurant_id. Items are separated by ";".
+func readCSV(r io.Reader, handle func(record) error) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("reading csv header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"email", "address"} {
+		if _, ok := columns[required]; !ok {
+			return fmt.Errorf("csv header is missing the %q column", required)
+		}
+	}
+
+	for {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		var rec record
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			rec = record{line: parseErr.StartLine, err: parseErr}
+		} else if err != nil {
+			return err
+		} else {
+			rec.line, _ = reader.FieldPos(0)
+			rec.order = orderFromRow(row, columns)
+		}
+
+		if err := handle(rec); err != nil {
+			return err
+		}
+	}
+}
+
+func orderFromRow(row []string, columns map[string]int) models.Order {
+	field := func(name string) string {
+		if i, ok := columns[name]; ok && i < len(row) {
+			return strings.TrimSpace(row[i])
+		}
+		return ""
+	}
+
+	order := models.Order{
+		Name:         field("name"),
+		Email:        field("email"),
+		Address:      field("address"),
+		RestaurantID: field("restaurant_id"),
+	}
+	for _, item := range strings.Split(field("items"), ";") {
+		if item = strings.TrimSpace(item); item != "" {
+			order.Items = append(order.Items, item)
+		}
+	}
+
+	return order
+}
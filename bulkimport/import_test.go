@@ -0,0 +1,145 @@
+package bulkimport
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"weservefood/models"
+	"weservefood/payments"
+	"weservefood/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func collect(t *testing.T, document string, format Format, opts Options) ([]LineResult, Summary) {
+	var results []LineResult
+	summary, err := Import(context.Background(), strings.NewReader(document), format, opts, func(result LineResult) error {
+		results = append(results, result)
+		return nil
+	})
+	require.NoError(t, err)
+	return results, summary
+}
+
+func TestImportJSONL(t *testing.T) {
+	document := `{"email": "import@example.com", "address": "1 Main St", "items": ["Soup"]}
+
+{"email": "", "address": "2 Main St"}
+not json
+{"email": "import@example.com", "address": "3 Main St"}
+`
+	results, summary := collect(t, document, FormatJSONL, Options{})
+
+	require.Len(t, results, 4)
+	assert.True(t, results[0].OK)
+	assert.Equal(t, 1, results[0].Line)
+	assert.False(t, results[1].OK)
+	assert.Equal(t, 3, results[1].Line)
+	assert.Contains(t, results[1].Error, "email is required")
+	assert.False(t, results[2].OK)
+	assert.True(t, results[3].OK)
+	assert.Equal(t, Summary{Succeeded: 2, Failed: 2, LastLine: 5}, summary)
+
+	order, err := repository.GetOrderByID(context.Background(), results[0].OrderID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Soup"}, order.Items)
+}
+
+func TestImportCSV(t *testing.T) {
+	repository.AddRestaurant(models.Restaurant{ID: "r-import", Name: "Import Inn", Menu: []models.MenuItem{{Name: "Tea"}, {Name: "Cake"}}})
+	document := "name,email,address,items,restaurant_id\n" +
+		"Ann,csv@example.com,1 Main St,Tea; Cake,r-import\n" +
+		"Bob,csv@example.com,2 Main St,Steak,r-import\n" +
+		"\"Cy,csv@example.com,3 Main St,,\n"
+
+	results, summary := collect(t, document, FormatCSV, Options{})
+
+	require.Len(t, results, 3)
+	assert.True(t, results[0].OK)
+	assert.Equal(t, 2, results[0].Line)
+	assert.Contains(t, results[1].Error, "not on the menu")
+	assert.False(t, results[2].OK)
+	assert.Equal(t, 2, summary.Failed)
+
+	order, err := repository.GetOrderByID(context.Background(), results[0].OrderID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Tea", "Cake"}, order.Items)
+	assert.Equal(t, "Ann", order.Name)
+}
+
+func TestImportedOrderDeliveredWithPIN(t *testing.T) {
+	ctx := context.Background()
+	repository.AddRestaurant(models.Restaurant{ID: "r-import-pin", Name: "Import Pins", Menu: []models.MenuItem{{Name: "Burger", PriceCents: 1000}}})
+	repository.AddCourier(models.Courier{ID: "c-import-pin", Name: "Ira", Available: true})
+	document := `{"email": "import-pin@example.com", "address": "1 Pin St", "restaurant_id": "r-import-pin", "items": ["Burger"]}`
+
+	results, _ := collect(t, document, FormatJSONL, Options{})
+
+	require.Len(t, results, 1)
+	require.True(t, results[0].OK, results[0].Error)
+	id, pin := results[0].OrderID, results[0].DeliveryPIN
+	require.Regexp(t, `^\d{4}$`, pin)
+
+	_, err := repository.ConfirmOrder(ctx, "import-pin@example.com", id, payments.MethodVisa)
+	require.NoError(t, err)
+	_, err = repository.AssignCourier(ctx, id, "c-import-pin")
+	require.NoError(t, err)
+	for _, status := range []models.OrderStatus{models.StatusPreparing, models.StatusOutForDelivery} {
+		_, err = repository.AdvanceOrder(ctx, id, status)
+		require.NoError(t, err)
+	}
+	delivered, err := repository.DeliverOrder(ctx, id, models.DeliveryConfirmation{CourierID: "c-import-pin", PIN: pin}, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusDelivered, delivered.Status)
+}
+
+func TestImportCSVMissingColumn(t *testing.T) {
+	_, err := Import(context.Background(), strings.NewReader("name,email\nAnn,a@example.com\n"), FormatCSV, Options{}, func(LineResult) error { return nil })
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `"address"`)
+}
+
+func TestImportDryRun(t *testing.T) {
+	document := `{"email": "dry-run@example.com", "address": "1 Main St"}`
+
+	results, summary := collect(t, document, FormatJSONL, Options{DryRun: true})
+
+	require.Len(t, results, 1)
+	assert.True(t, results[0].OK)
+	assert.Empty(t, results[0].OrderID)
+	assert.True(t, summary.DryRun)
+
+	_, err := repository.GetOrderByEmail(context.Background(), "dry-run@example.com")
+	assert.Error(t, err)
+}
+
+func TestImportResume(t *testing.T) {
+	document := `{"email": "resume@example.com", "address": "1 Main St"}
+{"email": "resume@example.com", "address": "2 Main St"}
+{"email": "resume@example.com", "address": "3 Main St"}
+`
+	results, summary := collect(t, document, FormatJSONL, Options{ResumeAfter: 2})
+
+	require.Len(t, results, 1)
+	assert.Equal(t, 3, results[0].Line)
+	assert.Equal(t, 2, summary.Skipped)
+
+	orders, err := repository.GetOrderByEmail(context.Background(), "resume@example.com")
+	require.NoError(t, err)
+	require.Len(t, orders, 1)
+	assert.Equal(t, "3 Main St", orders[0].Address)
+}
+
+func TestParseFormat(t *testing.T) {
+	format, err := ParseFormat("application/x-ndjson; charset=utf-8")
+	assert.NoError(t, err)
+	assert.Equal(t, FormatJSONL, format)
+
+	format, err = ParseFormat("text/csv")
+	assert.NoError(t, err)
+	assert.Equal(t, FormatCSV, format)
+
+	_, err = ParseFormat("application/xml")
+	assert.Error(t, err)
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"strings"
+	"weservefood/models"
+)
+
+// stringList collects a repeatable string flag
+type stringList []string
+
+func (s *stringList) String() string { return strings.Join(*s, ",") }
+
+func (s *stringList) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+// newFlagSet returns a flag set for a subcommand reporting errors on stderr
+func newFlagSet(e *env, name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(e.stderr)
+	return flags
+}
+
+// parseWithID parses subcommand flags around a leading positional ID
+func parseWithID(flags *flag.FlagSet, args []string) (string, error) {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return "", fmt.Errorf("%s: missing order id", flags.Name())
+	}
+	if err := flags.Parse(args[1:]); err != nil {
+		return "", err
+	}
+	return args[0], nil
+}
+
+func runOrders(e *env, args []string) error {
+	if len(args) == 0 {
+		return errors.New("orders: expected place, get, list, cancel, update-address, import or export")
+	}
+
+	switch args[0] {
+	case "place":
+		var newOrder models.Order
+		var items stringList
+		flags := newFlagSet(e, "orders place")
+		flags.StringVar(&newOrder.Name, "name", "", "customer name")
+		flags.StringVar(&newOrder.Email, "email", "", "customer email")
+		flags.StringVar(&newOrder.Address, "address", "", "delivery address")
+		flags.StringVar(&newOrder.RestaurantID, "restaurant", "", "restaurant ID")
+		flags.Var(&items, "item", "ordered item (repeatable)")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		if newOrder.Email == "" || newOrder.Address == "" {
+			return errors.New("orders place: --email and --address are required")
+		}
+		newOrder.Items = items
+
+		var placed models.PlacedOrder
+		if err := e.client.do("POST", "/v1/orders", newOrder, &placed); err != nil {
+			return err
+		}
+		return e.printer.print(placed)
+
+	case "get":
+		id, err := parseWithID(newFlagSet(e, "orders get"), args[1:])
+		if err != nil {
+			return err
+		}
+
+		var order models.Order
+		if err := e.client.do("GET", "/v1/orders/"+url.PathEscape(id), nil, &order); err != nil {
+			return err
+		}
+		return e.printer.print(order)
+
+	case "list":
+		flags := newFlagSet(e, "orders list")
+		email := flags.String("email", "", "only list orders of this customer")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+
+		path := "/v1/orders"
+		if *email != "" {
+			path += "?email=" + url.QueryEscape(*email)
+		}
+		var orders []models.Order
+		if err := e.client.do("GET", path, nil, &orders); err != nil {
+			return err
+		}
+		return e.printer.print(orders)
+
+	case "cancel":
+		flags := newFlagSet(e, "orders cancel")
+		email := flags.String("email", "", "customer email")
+		reason := flags.String("reason", "", "why the order is cancelled")
+		id, err := parseWithID(flags, args[1:])
+		if err != nil {
+			return err
+		}
+		if *email == "" || *reason == "" {
+			return errors.New("orders cancel: --email and --reason are required")
+		}
+
+		var message string
+		cancellation := models.OrderCancellation{Email: *email, Reason: *reason}
+		if err := e.client.do("POST", "/v1/orders/"+url.PathEscape(id)+"/cancel", cancellation, &message); err != nil {
+			return err
+		}
+		return e.printer.print(message)
+
+	case "update-address":
+		var patch models.OrderPatch
+		flags := newFlagSet(e, "orders update-address")
+		flags.StringVar(&patch.Email, "email", "", "customer email")
+		flags.StringVar(&patch.Address, "address", "", "new delivery address")
+		flags.IntVar(&patch.ConfirmTotalCents, "confirm-total", 0, "new total in cents, when the address changes the price")
+		id, err := parseWithID(flags, args[1:])
+		if err != nil {
+			return err
+		}
+		if patch.Email == "" || patch.Address == "" {
+			return errors.New("orders update-address: --email and --address are required")
+		}
+
+		var order models.Order
+		if err := e.client.do("PATCH", "/v1/orders/"+url.PathEscape(id), patch, &order); err != nil {
+			return err
+		}
+		return e.printer.print(order)
+
+	case "import":
+		return runImport(e, args[1:])
+
+	case "export":
+		return runExport(e, args[1:])
+
+	default:
+		return fmt.Errorf("orders: unknown subcommand %q", args[0])
+	}
+}
+
+func runMenu(e *env, args []string) error {
+	if len(args) == 0 {
+		var restaurants []models.Restaurant
+		if err := e.client.do("GET", "/v1/restaurants", nil, &restaurants); err != nil {
+			return err
+		}
+		return e.printer.print(restaurants)
+	}
+
+	var menu []models.MenuItem
+	if err := e.client.do("GET", "/v1/restaurants/"+url.PathEscape(args[0])+"/menu", nil, &menu); err != nil {
+		return err
+	}
+	return e.printer.print(menu)
+}
+
+func runCouriers(e *env, args []string) error {
+	var couriers []models.Courier
+	if err := e.client.do("GET", "/v1/couriers", nil, &couriers); err != nil {
+		return err
+	}
+	return e.printer.print(couriers)
+}
+
+func runConfig(e *env, args []string) error {
+	if len(args) == 0 {
+		return errors.New("config: expected list, set or use")
+	}
+
+	switch args[0] {
+	case "list":
+		profiles := make(map[string]Profile, len(e.config.Profiles))
+		for name, profile := range e.config.Profiles {
+			profile.Token = maskToken(profile.Token)
+			profiles[name] = profile
+		}
+		return e.printer.print(profiles)
+
+	case "set":
+		if len(args) < 2 || strings.HasPrefix(args[1], "-") {
+			return errors.New("config set: missing profile name")
+		}
+		name := args[1]
+		profile := e.config.Profiles[name]
+		flags := newFlagSet(e, "config set")
+		flags.StringVar(&profile.Host, "host", profile.Host, "API base URL")
+		flags.StringVar(&profile.Token, "token", profile.Token, "API auth token")
+		flags.StringVar(&profile.Output, "output", profile.Output, "output format: json or table")
+		if err := flags.Parse(args[2:]); err != nil {
+			return err
+		}
+
+		e.config.Profiles[name] = profile
+		if e.config.CurrentProfile == "" {
+			e.config.CurrentProfile = name
+		}
+		return saveConfig(e.configPath, e.config)
+
+	case "use":
+		if len(args) < 2 {
+			return errors.New("config use: missing profile name")
+		}
+		if _, exist := e.config.Profiles[args[1]]; !exist {
+			return fmt.Errorf("config use: unknown profile %q", args[1])
+		}
+		e.config.CurrentProfile = args[1]
+		return saveConfig(e.configPath, e.config)
+
+	default:
+		return fmt.Errorf("config: unknown subcommand %q", args[0])
+	}
+}
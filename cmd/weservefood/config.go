@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+const defaultHost = "http://localhost:8383"
+
+// Profile holds the connection settings for one WeServeFood deployment
+type Profile struct {
+	Host   string `json:"host"`
+	Token  string `json:"token,omitempty"`
+	Output string `json:"output,omitempty"`
+}
+
+// maskToken hides a token for display, keeping the last four characters of
+// long tokens so profiles can still be told apart
+func maskToken(token string) string {
+	switch {
+	case token == "":
+		return ""
+	case len(token) < 12:
+		return "****"
+	default:
+		return "****" + token[len(token)-4:]
+	}
+}
+
+// Config is the CLI configuration file holding the named profiles
+type Config struct {
+	CurrentProfile string             `json:"current_profile"`
+	Profiles       map[string]Profile `json:"profiles"`
+}
+
+// defaultConfigPath returns the configuration file location, honouring WESERVEFOOD_CONFIG
+func defaultConfigPath() string {
+	if path := os.Getenv("WESERVEFOOD_CONFIG"); path != "" {
+		return path
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ".weservefood.json"
+	}
+	return filepath.Join(dir, "weservefood", "config.json")
+}
+
+// loadConfig reads the configuration file; a missing file yields an empty configuration
+func loadConfig(path string) (Config, error) {
+	config := Config{Profiles: make(map[string]Profile)}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return config, nil
+	}
+	if err != nil {
+		return config, err
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return config, err
+	}
+	if config.Profiles == nil {
+		config.Profiles = make(map[string]Profile)
+	}
+
+	return config, nil
+}
+
+// saveConfig writes the configuration file, readable only by the current user as it holds tokens
+func saveConfig(path string, config Config) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o600)
+}
+
+// resolveProfile merges the selected profile with environment variables and flags.
+// Flags win over environment variables, which win over the profile.
+func resolveProfile(config Config, name string, flags Profile) Profile {
+	if name == "" {
+		name = os.Getenv("WESERVEFOOD_PROFILE")
+	}
+	if name == "" {
+		name = config.CurrentProfile
+	}
+	if name == "" {
+		name = "default"
+	}
+
+	profile := config.Profiles[name]
+	if host := os.Getenv("WESERVEFOOD_HOST"); host != "" {
+		profile.Host = host
+	}
+	if token := os.Getenv("WESERVEFOOD_TOKEN"); token != "" {
+		profile.Token = token
+	}
+	if flags.Host != "" {
+		profile.Host = flags.Host
+	}
+	if flags.Token != "" {
+		profile.Token = flags.Token
+	}
+	if flags.Output != "" {
+		profile.Output = flags.Output
+	}
+
+	if profile.Host == "" {
+		profile.Host = defaultHost
+	}
+	if profile.Output == "" {
+		profile.Output = "table"
+	}
+
+	return profile
+}
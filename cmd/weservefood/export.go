@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+)
+
+// runExport downloads the orders matching the filter flags to a file, or to
+// stdout when no file is given. A failed download leaves no partial file behind.
+func runExport(e *env, args []string) error {
+	flags := newFlagSet(e, "orders export")
+	format := flags.String("format", "csv", "csv, ndjson or parquet")
+	from := flags.String("from", "", "orders created at or after this RFC 3339 time or date")
+	to := flags.String("to", "", "orders created before this RFC 3339 time, or on or before this date")
+	status := flags.String("status", "", "only orders in this status")
+	restaurant := flags.String("restaurant", "", "only orders placed with this restaurant")
+	out := flags.String("out", "", "output file (default: stdout)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	query := url.Values{}
+	query.Set("format", *format)
+	for name, value := range map[string]string{"from": *from, "to": *to, "status": *status, "restaurant_id": *restaurant} {
+		if value != "" {
+			query.Set(name, value)
+		}
+	}
+	path := "/v1/admin/orders/export?" + query.Encode()
+
+	if *out == "" {
+		_, err := e.client.download(path, e.stdout)
+		return err
+	}
+
+	file, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	written, err := e.client.download(path, file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(*out)
+		return err
+	}
+
+	fmt.Fprintf(e.stderr, "exported %d bytes to %s\n", written, *out)
+	return nil
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"weservefood/bulkimport"
+)
+
+// importTrailer is the summary line closing an import response
+type importTrailer struct {
+	Summary *bulkimport.Summary `json:"summary"`
+	Error   string              `json:"error"`
+}
+
+// runImport streams a JSONL or CSV file to the import endpoint. The last line
+// reported by the server is recorded in a checkpoint file, so rerunning the same
+// command after an interruption resumes where it stopped.
+func runImport(e *env, args []string) error {
+	flags := newFlagSet(e, "orders import")
+	formatName := flags.String("format", "", "jsonl or csv (default: from the file extension)")
+	dryRun := flags.Bool("dry-run", false, "validate the orders without placing them")
+	checkpointPath := flags.String("checkpoint", "", "checkpoint file (default: <file>.checkpoint)")
+	noResume := flags.Bool("no-resume", false, "ignore an existing checkpoint and start from the first line")
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return errors.New("orders import: missing file")
+	}
+	path := args[0]
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	if *formatName == "" {
+		*formatName = strings.TrimPrefix(filepath.Ext(path), ".")
+	}
+	format, err := bulkimport.ParseFormat(*formatName)
+	if err != nil {
+		return err
+	}
+	if *checkpointPath == "" {
+		*checkpointPath = path + ".checkpoint"
+	}
+
+	resumeAfter := 0
+	if !*noResume && !*dryRun {
+		if resumeAfter, err = readCheckpoint(*checkpointPath); err != nil {
+			return err
+		}
+		if resumeAfter > 0 {
+			fmt.Fprintf(e.stderr, "resuming %s after line %d\n", path, resumeAfter)
+		}
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	query := url.Values{}
+	query.Set("format", string(format))
+	query.Set("dry_run", strconv.FormatBool(*dryRun))
+	query.Set("resume_after", strconv.Itoa(resumeAfter))
+
+	var trailer importTrailer
+	err = e.client.stream("POST", "/v1/admin/orders/import?"+query.Encode(), "text/plain", file, func(line []byte) error {
+		if e.profile.Output == "json" {
+			fmt.Fprintln(e.stdout, string(line))
+		}
+
+		var last importTrailer
+		if err := json.Unmarshal(line, &last); err == nil && last.Summary != nil {
+			trailer = last
+			return nil
+		}
+
+		var result bulkimport.LineResult
+		if err := json.Unmarshal(line, &result); err != nil {
+			return fmt.Errorf("unexpected import response: %s", line)
+		}
+		if e.profile.Output == "table" {
+			if result.OK {
+				fmt.Fprintf(e.stdout, "line %d\tok\t%s\t%s\n", result.Line, result.OrderID, result.DeliveryPIN)
+			} else {
+				fmt.Fprintf(e.stdout, "line %d\tfailed\t%s\n", result.Line, result.Error)
+			}
+		}
+		if *dryRun {
+			return nil
+		}
+		return writeCheckpoint(*checkpointPath, result.Line)
+	})
+	if err != nil {
+		return err
+	}
+	if trailer.Summary == nil {
+		return fmt.Errorf("import interrupted; rerun to resume from %s", *checkpointPath)
+	}
+	if trailer.Error != "" {
+		return fmt.Errorf("import stopped after line %d: %s", trailer.Summary.LastLine, trailer.Error)
+	}
+
+	if e.profile.Output == "table" {
+		fmt.Fprintf(e.stdout, "%d succeeded, %d failed, %d skipped\n", trailer.Summary.Succeeded, trailer.Summary.Failed, trailer.Summary.Skipped)
+	}
+	if !*dryRun {
+		if err := os.Remove(*checkpointPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// readCheckpoint returns the last imported line, or 0 when there is no checkpoint
+func readCheckpoint(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	line, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0, fmt.Errorf("invalid checkpoint %s: %w", path, err)
+	}
+	return line, nil
+}
+
+func writeCheckpoint(path string, line int) error {
+	return os.WriteFile(path, []byte(strconv.Itoa(line)+"\n"), 0o644)
+}
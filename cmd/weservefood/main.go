@@ -0,0 +1,130 @@
+// Command weservefood is a command-line client for the WeServeFood order API.
+//
+// Usage:
+//
+//	weservefood [global flags] <command> [arguments]
+//
+// Commands:
+//
+//	orders place|get|list|cancel|update-address   manage orders
+//	orders import <file.jsonl|file.csv>           bulk import orders
+//	orders export [--format csv|ndjson|parquet]   export orders for reporting
+//	menu [restaurant-id]                          list restaurants or show a menu
+//	couriers                                      list couriers
+//	config list|set|use                           manage connection profiles
+//
+// Global flags select a profile from the configuration file and may override
+// its host, token and output format (json or table).
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// env carries the resolved settings and output streams of one invocation
+type env struct {
+	configPath string
+	config     Config
+	profile    Profile
+	client     *Client
+	printer    printer
+	stdout     io.Writer
+	stderr     io.Writer
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run executes the CLI with the given arguments and returns the process exit code
+func run(args []string, stdout, stderr io.Writer) int {
+	global := flag.NewFlagSet("weservefood", flag.ContinueOnError)
+	global.SetOutput(stderr)
+	global.Usage = func() { usage(stderr) }
+
+	var flags Profile
+	configPath := global.String("config", defaultConfigPath(), "configuration file")
+	profileName := global.String("profile", "", "profile to use (default: current profile)")
+	global.StringVar(&flags.Host, "host", "", "API base URL")
+	global.StringVar(&flags.Token, "token", "", "API auth token")
+	global.StringVar(&flags.Output, "output", "", "output format: json or table")
+
+	if err := global.Parse(args); err != nil {
+		return 2
+	}
+	if global.NArg() == 0 {
+		usage(stderr)
+		return 2
+	}
+
+	config, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "weservefood: reading %s: %v\n", *configPath, err)
+		return 1
+	}
+
+	profile := resolveProfile(config, *profileName, flags)
+	if profile.Output != "json" && profile.Output != "table" {
+		fmt.Fprintf(stderr, "weservefood: unknown output format %q\n", profile.Output)
+		return 2
+	}
+
+	e := &env{
+		configPath: *configPath,
+		config:     config,
+		profile:    profile,
+		client:     newClient(profile),
+		printer:    printer{out: stdout, format: profile.Output},
+		stdout:     stdout,
+		stderr:     stderr,
+	}
+
+	command, rest := global.Arg(0), global.Args()[1:]
+	switch command {
+	case "orders":
+		err = runOrders(e, rest)
+	case "menu":
+		err = runMenu(e, rest)
+	case "couriers":
+		err = runCouriers(e, rest)
+	case "config":
+		err = runConfig(e, rest)
+	case "help":
+		usage(stdout)
+		return 0
+	default:
+		fmt.Fprintf(stderr, "weservefood: unknown command %q\n", command)
+		usage(stderr)
+		return 2
+	}
+
+	if err != nil {
+		if err != flag.ErrHelp {
+			fmt.Fprintf(stderr, "weservefood: %v\n", err)
+		}
+		return 1
+	}
+	return 0
+}
+
+func usage(w io.Writer) {
+	fmt.Fprint(w, `Usage: weservefood [--config file] [--profile name] [--host url] [--token token] [--output json|table] <command>
+
+Commands:
+  orders place --email E --address A [--name N] [--item I ...] [--restaurant R]
+  orders get <id>
+  orders list [--email E]
+  orders cancel <id> --email E --reason R
+  orders update-address <id> --email E --address A [--confirm-total C]
+  orders import <file> [--format jsonl|csv] [--dry-run] [--checkpoint file] [--no-resume]
+  orders export [--format csv|ndjson|parquet] [--from T] [--to T] [--status S] [--restaurant R] [--out file]
+  menu [restaurant-id]
+  couriers
+  config list
+  config set <profile> [--host url] [--token token] [--output json|table]
+  config use <profile>
+`)
+}
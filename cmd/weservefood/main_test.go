@@ -0,0 +1,258 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"weservefood/config"
+	"weservefood/handler"
+	"weservefood/middleware"
+	"weservefood/models"
+	"weservefood/payments"
+	"weservefood/repository"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// adminToken is the admin bearer token the test server accepts
+const adminToken = "cli-admin-secret"
+
+func newTestServer(t *testing.T) *httptest.Server {
+	router := mux.NewRouter()
+	router.Use(middleware.AuthMiddleware(config.Auth{AdminTokens: []string{adminToken}}))
+	router.HandleFunc("/v1/orders", handler.CreateOrderV1).Methods("POST")
+	router.HandleFunc("/v1/orders", handler.ListOrdersV1).Methods("GET")
+	router.HandleFunc("/v1/orders/{id}", handler.GetOrderV1).Methods("GET")
+	router.HandleFunc("/v1/orders/{id}", handler.PatchOrderV1).Methods("PATCH")
+	router.HandleFunc("/v1/orders/{id}/cancel", handler.CancelOrderV1).Methods("POST")
+	router.HandleFunc("/v1/restaurants", handler.ListRestaurantsV1).Methods("GET")
+	router.HandleFunc("/v1/restaurants/{id}/menu", handler.GetMenuV1).Methods("GET")
+	router.HandleFunc("/v1/couriers", handler.ListCouriersV1).Methods("GET")
+
+	admin := router.PathPrefix("/v1/admin").Subrouter()
+	admin.Use(middleware.RequireAdmin)
+	admin.HandleFunc("/orders/import", handler.ImportOrdersV1).Methods("POST")
+	admin.HandleFunc("/orders/export", handler.ExportOrdersV1).Methods("GET")
+
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// runCLI runs the CLI against the test server with an isolated configuration file
+func runCLI(t *testing.T, server *httptest.Server, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	base := []string{"--config", filepath.Join(t.TempDir(), "config.json"), "--host", server.URL}
+	code := run(append(base, args...), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestOrdersPlaceAndGet(t *testing.T) {
+	server := newTestServer(t)
+
+	code, stdout, stderr := runCLI(t, server, "--output", "json", "orders", "place", "--email", "cli@example.com", "--address", "123 Test St", "--item", "Soup", "--item", "Bread")
+	require.Equal(t, 0, code, stderr)
+
+	var placed models.Order
+	require.NoError(t, json.Unmarshal([]byte(stdout), &placed))
+	assert.Equal(t, []string{"Soup", "Bread"}, placed.Items)
+
+	code, stdout, stderr = runCLI(t, server, "orders", "get", placed.ID)
+	require.Equal(t, 0, code, stderr)
+	assert.Contains(t, stdout, "STATUS")
+	assert.Contains(t, stdout, placed.ID)
+	assert.Contains(t, stdout, "Soup, Bread")
+}
+
+func TestPlacedOrderDeliveredWithPIN(t *testing.T) {
+	server := newTestServer(t)
+	ctx := context.Background()
+	repository.AddRestaurant(models.Restaurant{ID: "r-cli-pin", Name: "CLI Pins", Menu: []models.MenuItem{{Name: "Burger", PriceCents: 1000}}})
+	repository.AddCourier(models.Courier{ID: "c-cli-pin", Name: "Cleo", Available: true})
+
+	code, stdout, stderr := runCLI(t, server, "orders", "place", "--email", "cli-pin@example.com", "--address", "1 Pin St", "--restaurant", "r-cli-pin", "--item", "Burger")
+	require.Equal(t, 0, code, stderr)
+	assert.Contains(t, stdout, "PIN")
+
+	code, stdout, stderr = runCLI(t, server, "--output", "json", "orders", "place", "--email", "cli-pin@example.com", "--address", "1 Pin St", "--restaurant", "r-cli-pin", "--item", "Burger")
+	require.Equal(t, 0, code, stderr)
+	var placed models.PlacedOrder
+	require.NoError(t, json.Unmarshal([]byte(stdout), &placed))
+	require.Regexp(t, `^\d{4}$`, placed.DeliveryPIN)
+
+	_, err := repository.ConfirmOrder(ctx, "cli-pin@example.com", placed.ID, payments.MethodVisa)
+	require.NoError(t, err)
+	_, err = repository.AssignCourier(ctx, placed.ID, "c-cli-pin")
+	require.NoError(t, err)
+	for _, status := range []models.OrderStatus{models.StatusPreparing, models.StatusOutForDelivery} {
+		_, err = repository.AdvanceOrder(ctx, placed.ID, status)
+		require.NoError(t, err)
+	}
+	delivered, err := repository.DeliverOrder(ctx, placed.ID, models.DeliveryConfirmation{CourierID: "c-cli-pin", PIN: placed.DeliveryPIN}, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusDelivered, delivered.Status)
+}
+
+func TestOrdersListUpdateAndCancel(t *testing.T) {
+	server := newTestServer(t)
+	placed, err := repository.CreateOrder(context.Background(), models.Order{Email: "cli-list@example.com", Address: "123 Test St"})
+	require.NoError(t, err)
+
+	code, stdout, stderr := runCLI(t, server, "orders", "list", "--email", "cli-list@example.com")
+	require.Equal(t, 0, code, stderr)
+	assert.Contains(t, stdout, placed.ID)
+
+	code, stdout, stderr = runCLI(t, server, "--output", "json", "orders", "update-address", placed.ID, "--email", "cli-list@example.com", "--address", "456 New St")
+	require.Equal(t, 0, code, stderr)
+	assert.Contains(t, stdout, "456 New St")
+
+	code, stdout, stderr = runCLI(t, server, "orders", "cancel", placed.ID, "--email", "cli-list@example.com", "--reason", "changed my mind")
+	require.Equal(t, 0, code, stderr)
+	assert.Contains(t, stdout, "Order Cancelled Successfully")
+}
+
+func TestOrdersGetNotFound(t *testing.T) {
+	server := newTestServer(t)
+
+	code, _, stderr := runCLI(t, server, "orders", "get", "nonexistentID")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "404")
+	assert.Contains(t, stderr, "order not found")
+}
+
+func TestOrdersPlaceMissingFlags(t *testing.T) {
+	server := newTestServer(t)
+
+	code, _, stderr := runCLI(t, server, "orders", "place", "--name", "No Email")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "--email and --address are required")
+}
+
+func TestMenuAndCouriers(t *testing.T) {
+	server := newTestServer(t)
+	repository.AddRestaurant(models.Restaurant{ID: "r-cli", Name: "CLI Cafe", Menu: []models.MenuItem{{Name: "Latte", PriceCents: 450}}})
+	repository.AddCourier(models.Courier{ID: "c-cli", Name: "Cleo", Available: true})
+
+	code, stdout, stderr := runCLI(t, server, "menu")
+	require.Equal(t, 0, code, stderr)
+	assert.Contains(t, stdout, "CLI Cafe")
+
+	code, stdout, stderr = runCLI(t, server, "menu", "r-cli")
+	require.Equal(t, 0, code, stderr)
+	assert.Contains(t, stdout, "Latte")
+	assert.Contains(t, stdout, "4.50")
+
+	code, stdout, stderr = runCLI(t, server, "couriers")
+	require.Equal(t, 0, code, stderr)
+	assert.Contains(t, stdout, "Cleo")
+}
+
+func TestConfigProfiles(t *testing.T) {
+	var tokens []string
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		tokens = append(tokens, req.Header.Get("Authorization"))
+		rw.Write([]byte("[]"))
+	}))
+	defer server.Close()
+
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	cli := func(args ...string) int {
+		var stdout, stderr bytes.Buffer
+		return run(append([]string{"--config", configPath}, args...), &stdout, &stderr)
+	}
+
+	assert.Equal(t, 0, cli("config", "set", "staging", "--host", server.URL, "--token", "staging-token"))
+	assert.Equal(t, 0, cli("config", "set", "other", "--host", "http://127.0.0.1:1", "--token", "other-token"))
+	assert.Equal(t, 0, cli("couriers"))
+	assert.Equal(t, 0, cli("--profile", "staging", "--token", "override", "couriers"))
+	assert.Equal(t, 1, cli("config", "use", "missing"))
+
+	config, err := loadConfig(configPath)
+	require.NoError(t, err)
+	assert.Equal(t, "staging", config.CurrentProfile)
+	assert.Equal(t, []string{"Bearer staging-token", "Bearer override"}, tokens)
+
+	var stdout, stderr bytes.Buffer
+	require.Equal(t, 0, run([]string{"--config", configPath, "--output", "json", "config", "list"}, &stdout, &stderr), stderr.String())
+	assert.NotContains(t, stdout.String(), "staging-token")
+	assert.NotContains(t, stdout.String(), "other-token")
+	assert.Contains(t, stdout.String(), `"token": "****oken"`)
+}
+
+func TestUnknownCommand(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"--config", filepath.Join(t.TempDir(), "config.json"), "deliver"}, &stdout, &stderr)
+	assert.Equal(t, 2, code)
+	assert.Contains(t, stderr.String(), `unknown command "deliver"`)
+}
+
+func TestOrdersImportResumesFromCheckpoint(t *testing.T) {
+	server := newTestServer(t)
+	path := filepath.Join(t.TempDir(), "orders.jsonl")
+	document := `{"email": "cli-import@example.com", "address": "1 Main St"}
+{"email": "cli-import@example.com", "address": "2 Main St"}
+{"email": "", "address": "3 Main St"}
+`
+	require.NoError(t, os.WriteFile(path, []byte(document), 0o644))
+	require.NoError(t, os.WriteFile(path+".checkpoint", []byte("1\n"), 0o644))
+
+	code, stdout, stderr := runCLI(t, server, "--token", adminToken, "orders", "import", path)
+	require.Equal(t, 0, code, stderr)
+	assert.Contains(t, stderr, "resuming")
+	assert.Contains(t, stdout, "line 3\tfailed")
+	assert.Contains(t, stdout, "1 succeeded, 1 failed, 1 skipped")
+	assert.NoFileExists(t, path+".checkpoint")
+
+	orders, err := repository.GetOrderByEmail(context.Background(), "cli-import@example.com")
+	require.NoError(t, err)
+	require.Len(t, orders, 1)
+	assert.Equal(t, "2 Main St", orders[0].Address)
+}
+
+func TestOrdersImportDryRun(t *testing.T) {
+	server := newTestServer(t)
+	path := filepath.Join(t.TempDir(), "orders.csv")
+	require.NoError(t, os.WriteFile(path, []byte("email,address\ncli-dry@example.com,1 Main St\n"), 0o644))
+
+	code, stdout, stderr := runCLI(t, server, "--token", adminToken, "--output", "json", "orders", "import", path, "--dry-run")
+	require.Equal(t, 0, code, stderr)
+	assert.Contains(t, stdout, `{"line":2,"ok":true}`)
+	assert.Contains(t, stdout, `"dry_run":true`)
+
+	_, err := repository.GetOrderByEmail(context.Background(), "cli-dry@example.com")
+	assert.Error(t, err)
+}
+
+func TestOrdersExport(t *testing.T) {
+	server := newTestServer(t)
+	placed, err := repository.CreateOrder(context.Background(), models.Order{Email: "cli-export@example.com", Address: "1 Main St"})
+	require.NoError(t, err)
+
+	code, _, stderr := runCLI(t, server, "orders", "export", "--format", "ndjson")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "401")
+
+	code, stdout, stderr := runCLI(t, server, "--token", adminToken, "orders", "export", "--format", "ndjson", "--status", "placed")
+	require.Equal(t, 0, code, stderr)
+	assert.Contains(t, stdout, placed.ID)
+
+	out := filepath.Join(t.TempDir(), "orders.csv")
+	code, _, stderr = runCLI(t, server, "--token", adminToken, "orders", "export", "--from", "2000-01-01", "--out", out)
+	require.Equal(t, 0, code, stderr)
+	assert.Contains(t, stderr, "exported")
+	data, err := os.ReadFile(out)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), placed.ID)
+
+	code, _, stderr = runCLI(t, server, "--token", adminToken, "orders", "export", "--status", "lost", "--out", out+".bad")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "400")
+	assert.NoFileExists(t, out+".bad")
+}
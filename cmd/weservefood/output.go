@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"weservefood/models"
+)
+
+// printer renders command results as indented JSON or as an aligned table
+type printer struct {
+	out    io.Writer
+	format string
+}
+
+func (p printer) print(value interface{}) error {
+	if p.format == "json" {
+		encoder := json.NewEncoder(p.out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(value)
+	}
+
+	header, rows := tableRows(value)
+	table := tabwriter.NewWriter(p.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(table, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(table, strings.Join(row, "\t"))
+	}
+	return table.Flush()
+}
+
+// tableRows converts a command result into a table header and rows
+func tableRows(value interface{}) ([]string, [][]string) {
+	switch v := value.(type) {
+	case models.Order:
+		return tableRows([]models.Order{v})
+	case models.PlacedOrder:
+		header, rows := tableRows(v.Order)
+		return append(header, "PIN"), [][]string{append(rows[0], v.DeliveryPIN)}
+	case []models.Order:
+		rows := make([][]string, 0, len(v))
+		for _, order := range v {
+			rows = append(rows, []string{order.ID, order.Name, order.Email, order.Address, strings.Join(order.Items, ", "), order.DeliveryTime, string(order.Status)})
+		}
+		return []string{"ID", "NAME", "EMAIL", "ADDRESS", "ITEMS", "DELIVERY", "STATUS"}, rows
+	case []models.Restaurant:
+		rows := make([][]string, 0, len(v))
+		for _, restaurant := range v {
+			rows = append(rows, []string{restaurant.ID, restaurant.Name, restaurant.Address, strconv.Itoa(len(restaurant.Menu))})
+		}
+		return []string{"ID", "NAME", "ADDRESS", "ITEMS"}, rows
+	case []models.MenuItem:
+		rows := make([][]string, 0, len(v))
+		for _, item := range v {
+			rows = append(rows, []string{item.Name, formatCents(item.PriceCents)})
+		}
+		return []string{"ITEM", "PRICE"}, rows
+	case []models.Courier:
+		rows := make([][]string, 0, len(v))
+		for _, courier := range v {
+			rows = append(rows, []string{courier.ID, courier.Name, courier.Phone, strconv.FormatBool(courier.Available)})
+		}
+		return []string{"ID", "NAME", "PHONE", "AVAILABLE"}, rows
+	case map[string]Profile:
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		rows := make([][]string, 0, len(v))
+		for _, name := range names {
+			profile := v[name]
+			token := ""
+			if profile.Token != "" {
+				token = "set"
+			}
+			rows = append(rows, []string{name, profile.Host, token, profile.Output})
+		}
+		return []string{"PROFILE", "HOST", "TOKEN", "OUTPUT"}, rows
+	default:
+		return []string{"RESULT"}, [][]string{{fmt.Sprint(v)}}
+	}
+}
+
+// formatCents renders an amount in cents as a decimal price
+func formatCents(cents int) string {
+	return fmt.Sprintf("%d.%02d", cents/100, cents%100)
+}
@@ -0,0 +1,498 @@
+// Package config loads the server settings. Values come from the defaults, then
+// a YAML or TOML file, then WESERVEFOOD_* environment variables, then command
+// line flags, each overriding the one before.
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"weservefood/models"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix starts the environment variable of every setting, followed by the
+// section and key in upper case, e.g. WESERVEFOOD_SERVER_HTTP_ADDR
+const EnvPrefix = "WESERVEFOOD_"
+
+// FileEnv names the configuration file when the -config flag is not given
+const FileEnv = "WESERVEFOOD_SERVER_CONFIG"
+
+// Config holds every server setting, grouped in sections
+type Config struct {
+	Server       Server       `yaml:"server" toml:"server"`
+	Auth         Auth         `yaml:"auth" toml:"auth"`
+	Storage      Storage      `yaml:"storage" toml:"storage"`
+	Timeouts     Timeouts     `yaml:"timeouts" toml:"timeouts"`
+	Business     Business     `yaml:"business" toml:"business"`
+	Cancellation Cancellation `yaml:"cancellation" toml:"cancellation"`
+	ETA          ETA          `yaml:"eta" toml:"eta"`
+	Dispatch     Dispatch     `yaml:"dispatch" toml:"dispatch"`
+	Tracking     Tracking     `yaml:"tracking" toml:"tracking"`
+	TLS          TLS          `yaml:"tls" toml:"tls"`
+	CORS         CORS         `yaml:"cors" toml:"cors"`
+	HTTP         HTTP         `yaml:"http" toml:"http"`
+	Log          Log          `yaml:"log" toml:"log"`
+	Traces       Traces       `yaml:"traces" toml:"traces"`
+}
+
+// Server configures the listeners
+type Server struct {
+	// HTTPAddr serves the API, or only redirects to HTTPSAddr when TLS is enabled
+	HTTPAddr  string `yaml:"http_addr" toml:"http_addr"`
+	HTTPSAddr string `yaml:"https_addr" toml:"https_addr"`
+	GRPCAddr  string `yaml:"grpc_addr" toml:"grpc_addr"`
+}
+
+// Auth lists the bearer tokens of privileged callers. Lists are comma
+// separated in environment variables and flags.
+type Auth struct {
+	// AdminTokens unlock the /v1/admin routes and the GraphQL fields over
+	// every order; without any, those are refused to everyone
+	AdminTokens []string `yaml:"admin_tokens" toml:"admin_tokens"`
+	// RestaurantTokens are written "restaurant_id=token" and let a
+	// restaurant act on the orders placed with it
+	RestaurantTokens []string `yaml:"restaurant_tokens" toml:"restaurant_tokens"`
+	// CourierTokens are written "courier_id=token" and let a courier
+	// deliver the orders it carries
+	CourierTokens []string `yaml:"courier_tokens" toml:"courier_tokens"`
+}
+
+// Storage selects where orders, the catalog and the gazetteer come from
+type Storage struct {
+	// Backend is where orders are kept: "memory", or "file" to also save them
+	// to Path every FlushInterval and on shutdown, and replay them at startup
+	Backend       string        `yaml:"backend" toml:"backend"`
+	Path          string        `yaml:"path" toml:"path"`
+	FlushInterval time.Duration `yaml:"flush_interval" toml:"flush_interval"`
+	// CatalogPath holds the restaurants and couriers; delivery zone changes
+	// are saved back to it
+	CatalogPath string `yaml:"catalog_path" toml:"catalog_path"`
+	// GazetteerPath is a postal code dataset to geocode delivery addresses
+	// with instead of the one built into the binary
+	GazetteerPath string `yaml:"gazetteer_path" toml:"gazetteer_path"`
+	// BlobPath is the directory proof of delivery photos and signatures are kept in
+	BlobPath string `yaml:"blob_path" toml:"blob_path"`
+}
+
+// Storage backends accepted in storage.backend
+const (
+	BackendMemory = "memory"
+	BackendFile   = "file"
+)
+
+// Timeouts bound how long the HTTP server waits on clients
+type Timeouts struct {
+	ReadHeader time.Duration `yaml:"read_header" toml:"read_header"`
+	Read       time.Duration `yaml:"read" toml:"read"`
+	Write      time.Duration `yaml:"write" toml:"write"`
+	Idle       time.Duration `yaml:"idle" toml:"idle"`
+	// DrainDelay keeps serving after a shutdown signal, with /readyz failing,
+	// so load balancers stop routing before connections are closed
+	DrainDelay time.Duration `yaml:"drain_delay" toml:"drain_delay"`
+	Shutdown   time.Duration `yaml:"shutdown" toml:"shutdown"`
+}
+
+// Business holds the pricing and delivery settings
+type Business struct {
+	DeliveryFeeCents int           `yaml:"delivery_fee_cents" toml:"delivery_fee_cents"`
+	DeliveryOffset   time.Duration `yaml:"delivery_offset" toml:"delivery_offset"`
+	// SlotCapacity is how many active orders a courier may carry at once. It
+	// also bounds dispatch batches, so the default of 1 leaves every batch a
+	// single stop; raise it to batch deliveries.
+	SlotCapacity int `yaml:"slot_capacity" toml:"slot_capacity"`
+	// LateRefundPercent is refunded when an order is cancelled during preparation
+	LateRefundPercent int `yaml:"late_refund_percent" toml:"late_refund_percent"`
+	// ProofOfDelivery is required of orders that do not choose: pin, photo or signature
+	ProofOfDelivery string `yaml:"proof_of_delivery" toml:"proof_of_delivery"`
+}
+
+// Cancellation lists the order statuses each initiator may cancel, and how
+// long before delivery they must do so; a zero cutoff sets no time limit
+type Cancellation struct {
+	CustomerStatuses   []string      `yaml:"customer_statuses" toml:"customer_statuses"`
+	CustomerCutoff     time.Duration `yaml:"customer_cutoff" toml:"customer_cutoff"`
+	RestaurantStatuses []string      `yaml:"restaurant_statuses" toml:"restaurant_statuses"`
+	RestaurantCutoff   time.Duration `yaml:"restaurant_cutoff" toml:"restaurant_cutoff"`
+	SupportStatuses    []string      `yaml:"support_statuses" toml:"support_statuses"`
+	SupportCutoff      time.Duration `yaml:"support_cutoff" toml:"support_cutoff"`
+}
+
+// ETA tunes the delivery estimates of orders placed against a restaurant
+type ETA struct {
+	// PrepTime is used for menu items without prep_minutes
+	PrepTime time.Duration `yaml:"prep_time" toml:"prep_time"`
+	// KitchenSlots is how many orders a kitchen prepares at once
+	KitchenSlots    int `yaml:"kitchen_slots" toml:"kitchen_slots"`
+	CourierSpeedKmh int `yaml:"courier_speed_kmh" toml:"courier_speed_kmh"`
+	// DetourPercent turns straight-line distances into road distances
+	DetourPercent int           `yaml:"detour_percent" toml:"detour_percent"`
+	Handover      time.Duration `yaml:"handover" toml:"handover"`
+	// CourierWait is added when no courier is free to take an order
+	CourierWait time.Duration `yaml:"courier_wait" toml:"courier_wait"`
+}
+
+// Dispatch bounds which orders waiting at a restaurant may share a courier
+type Dispatch struct {
+	MaxStops int `yaml:"max_stops" toml:"max_stops"`
+	// RadiusMetres is how far a drop-off may be from the batch's first one
+	RadiusMetres int `yaml:"radius_metres" toml:"radius_metres"`
+	// Window is how much later than the batch's first order another may be due
+	Window time.Duration `yaml:"window" toml:"window"`
+}
+
+// Tracking bounds the courier locations kept for each delivery
+type Tracking struct {
+	// TrailLength is how many pings a delivery keeps; longer trails are thinned
+	TrailLength int `yaml:"trail_length" toml:"trail_length"`
+}
+
+// TLS configures the HTTPS listener
+type TLS struct {
+	Enabled  bool   `yaml:"enabled" toml:"enabled"`
+	CertFile string `yaml:"cert_file" toml:"cert_file"`
+	KeyFile  string `yaml:"key_file" toml:"key_file"`
+	// MinVersion is "1.2" or "1.3"
+	MinVersion   string `yaml:"min_version" toml:"min_version"`
+	CipherPolicy string `yaml:"cipher_policy" toml:"cipher_policy"`
+	// ReloadInterval is how often the certificate files are checked for rotation
+	ReloadInterval time.Duration `yaml:"reload_interval" toml:"reload_interval"`
+	// Redirect serves HTTP→HTTPS redirects on server.http_addr; without it
+	// nothing listens there
+	Redirect bool `yaml:"redirect" toml:"redirect"`
+}
+
+// Cipher policies accepted in tls.cipher_policy: the Go defaults, or only
+// forward-secret AEAD suites for TLS 1.2
+const (
+	CipherPolicyDefault = "default"
+	CipherPolicyStrict  = "strict"
+)
+
+// CORS configures which browser origins may call the API. Lists are comma
+// separated in environment variables and flags.
+type CORS struct {
+	// AllowedOrigins lists exact origins, "*" for any origin, or patterns such
+	// as "https://*.example.com"; when empty no CORS headers are sent
+	AllowedOrigins   []string      `yaml:"allowed_origins" toml:"allowed_origins"`
+	AllowedMethods   []string      `yaml:"allowed_methods" toml:"allowed_methods"`
+	AllowedHeaders   []string      `yaml:"allowed_headers" toml:"allowed_headers"`
+	AllowCredentials bool          `yaml:"allow_credentials" toml:"allow_credentials"`
+	MaxAge           time.Duration `yaml:"max_age" toml:"max_age"`
+}
+
+// HTTP configures request and response bodies
+type HTTP struct {
+	// MaxBodyBytes limits request bodies on routes without their own limit
+	MaxBodyBytes int64 `yaml:"max_body_bytes" toml:"max_body_bytes"`
+	// RouteMaxBodyBytes limits request bodies per route template, written
+	// "/v1/admin/orders/import=268435456" in environment variables and flags. Entries
+	// add to the defaults; 0 lifts the limit of a route.
+	RouteMaxBodyBytes map[string]int64 `yaml:"route_max_body_bytes" toml:"route_max_body_bytes"`
+	// CompressMinBytes is the smallest response worth compressing
+	CompressMinBytes int `yaml:"compress_min_bytes" toml:"compress_min_bytes"`
+}
+
+// Log configures the structured logger
+type Log struct {
+	Level string `yaml:"level" toml:"level"`
+}
+
+// Traces selects the trace exporter: none, otlp or stdout
+type Traces struct {
+	Exporter string `yaml:"exporter" toml:"exporter"`
+}
+
+// Default returns the settings used when nothing overrides them
+func Default() Config {
+	return Config{
+		Server:   Server{HTTPAddr: ":8383", HTTPSAddr: ":8443", GRPCAddr: ":9393"},
+		Storage:  Storage{Backend: BackendMemory, Path: "data/orders.json", FlushInterval: time.Minute, CatalogPath: "data/catalog.json", BlobPath: "data/blobs"},
+		Timeouts: Timeouts{ReadHeader: 5 * time.Second, Read: 30 * time.Second, Write: 30 * time.Second, Idle: 2 * time.Minute, Shutdown: 30 * time.Second},
+		Business: Business{DeliveryFeeCents: 299, DeliveryOffset: 30 * time.Minute, SlotCapacity: 1, LateRefundPercent: 50, ProofOfDelivery: "pin"},
+		Cancellation: Cancellation{
+			CustomerStatuses:   []string{"placed", "confirmed", "preparing"},
+			CustomerCutoff:     10 * time.Minute,
+			RestaurantStatuses: []string{"placed", "confirmed", "preparing"},
+			SupportStatuses:    []string{"placed", "confirmed", "preparing", "out_for_delivery"},
+		},
+		ETA:      ETA{PrepTime: 10 * time.Minute, KitchenSlots: 2, CourierSpeedKmh: 15, DetourPercent: 130, Handover: 3 * time.Minute, CourierWait: 15 * time.Minute},
+		Dispatch: Dispatch{MaxStops: 3, RadiusMetres: 2000, Window: 20 * time.Minute},
+		Tracking: Tracking{TrailLength: 500},
+		TLS:      TLS{MinVersion: "1.2", CipherPolicy: CipherPolicyDefault, ReloadInterval: 30 * time.Second, Redirect: true},
+		CORS: CORS{
+			AllowedMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE"},
+			AllowedHeaders: []string{"Authorization", "Content-Type", "X-Request-ID"},
+			MaxAge:         10 * time.Minute,
+		},
+		HTTP: HTTP{
+			MaxBodyBytes:      1 << 20,
+			RouteMaxBodyBytes: map[string]int64{"/v1/admin/orders/import": 256 << 20, "/v1/orders/{id}/delivery": 16 << 20},
+			CompressMinBytes:  1024,
+		},
+		Log:    Log{Level: "info"},
+		Traces: Traces{Exporter: "none"},
+	}
+}
+
+// Load builds the configuration from the file named by -config (or
+// WESERVEFOOD_SERVER_CONFIG), the environment and the flags in args, and
+// validates the result
+func Load(args []string, getenv func(string) string, stderr io.Writer) (Config, error) {
+	config := Default()
+	settings := settingsOf(&config)
+
+	flags := flag.NewFlagSet("weservefood-server", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	path := flags.String("config", getenv(FileEnv), "YAML or TOML configuration file")
+	overrides := make(map[string]string)
+	for _, s := range settings {
+		usage := fmt.Sprintf("%s (env %s, default %v)", s.key, s.envName(), s.value.Interface())
+		override := func(value string) error {
+			overrides[s.key] = value
+			return nil
+		}
+		if s.value.Kind() == reflect.Bool {
+			flags.BoolFunc(s.flagName(), usage, override)
+		} else {
+			flags.Func(s.flagName(), usage, override)
+		}
+	}
+	if err := flags.Parse(args); err != nil {
+		return Config{}, err
+	}
+
+	if *path != "" {
+		if err := loadFile(*path, &config); err != nil {
+			return Config{}, err
+		}
+	}
+	for _, s := range settings {
+		if value := getenv(s.envName()); value != "" {
+			if err := s.set(value); err != nil {
+				return Config{}, fmt.Errorf("%s: %w", s.envName(), err)
+			}
+		}
+	}
+	for _, s := range settings {
+		if value, ok := overrides[s.key]; ok {
+			if err := s.set(value); err != nil {
+				return Config{}, fmt.Errorf("-%s: %w", s.flagName(), err)
+			}
+		}
+	}
+
+	if err := config.Validate(); err != nil {
+		return Config{}, err
+	}
+	return config, nil
+}
+
+// loadFile decodes a YAML or TOML file, chosen by its extension. Unknown keys
+// are rejected so a typo does not silently leave a default in place.
+func loadFile(path string, config *Config) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(file)
+		decoder.KnownFields(true)
+		if err := decoder.Decode(config); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	case ".toml":
+		metadata, err := toml.NewDecoder(file).Decode(config)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if undecoded := metadata.Undecoded(); len(undecoded) > 0 {
+			return fmt.Errorf("%s: unknown setting %q", path, undecoded[0].String())
+		}
+	default:
+		return fmt.Errorf("%s: configuration files must be .yaml, .yml or .toml", path)
+	}
+	return nil
+}
+
+// Validate reports every invalid setting at once
+func (c Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	for key, addr := range map[string]string{"server.http_addr": c.Server.HTTPAddr, "server.https_addr": c.Server.HTTPSAddr, "server.grpc_addr": c.Server.GRPCAddr} {
+		_, port, err := net.SplitHostPort(addr)
+		if err == nil {
+			_, err = strconv.ParseUint(port, 10, 16)
+		}
+		check(err == nil, "%s: %q is not a host:port address", key, addr)
+	}
+	for _, entry := range c.Auth.RestaurantTokens {
+		restaurantID, token, _ := strings.Cut(entry, "=")
+		check(restaurantID != "" && token != "", "auth.restaurant_tokens: entries must be restaurant_id=token")
+	}
+	for _, entry := range c.Auth.CourierTokens {
+		courierID, token, _ := strings.Cut(entry, "=")
+		check(courierID != "" && token != "", "auth.courier_tokens: entries must be courier_id=token")
+	}
+	check(c.Storage.Backend == BackendMemory || c.Storage.Backend == BackendFile, "storage.backend: unknown backend %q", c.Storage.Backend)
+	check(c.Storage.Backend != BackendFile || c.Storage.Path != "", "storage.path is required by the file backend")
+	check(c.Storage.FlushInterval > 0, "storage.flush_interval must be positive")
+	check(c.Timeouts.ReadHeader > 0, "timeouts.read_header must be positive")
+	check(c.Timeouts.Read > 0, "timeouts.read must be positive")
+	check(c.Timeouts.Write > 0, "timeouts.write must be positive")
+	check(c.Timeouts.Idle > 0, "timeouts.idle must be positive")
+	check(c.Timeouts.DrainDelay >= 0, "timeouts.drain_delay must not be negative")
+	check(c.Timeouts.Shutdown > 0, "timeouts.shutdown must be positive")
+	check(c.Business.DeliveryFeeCents >= 0, "business.delivery_fee_cents must not be negative")
+	check(c.Business.DeliveryOffset > 0, "business.delivery_offset must be positive")
+	check(c.Business.SlotCapacity >= 1, "business.slot_capacity must be at least 1")
+	check(c.Business.LateRefundPercent >= 0 && c.Business.LateRefundPercent <= 100, "business.late_refund_percent must be between 0 and 100")
+	check(models.ProofMethod(c.Business.ProofOfDelivery).Valid(), "business.proof_of_delivery: unknown proof %q", c.Business.ProofOfDelivery)
+	for key, statuses := range map[string][]string{"cancellation.customer_statuses": c.Cancellation.CustomerStatuses,
+		"cancellation.restaurant_statuses": c.Cancellation.RestaurantStatuses, "cancellation.support_statuses": c.Cancellation.SupportStatuses} {
+		for _, status := range statuses {
+			check(models.OrderStatus(status).Valid() && !models.OrderStatus(status).Final(), "%s: %q is not a status orders can be cancelled from", key, status)
+		}
+	}
+	check(c.Cancellation.CustomerCutoff >= 0 && c.Cancellation.RestaurantCutoff >= 0 && c.Cancellation.SupportCutoff >= 0,
+		"cancellation cutoffs must not be negative")
+	check(c.ETA.PrepTime > 0, "eta.prep_time must be positive")
+	check(c.ETA.KitchenSlots >= 1, "eta.kitchen_slots must be at least 1")
+	check(c.ETA.CourierSpeedKmh >= 1, "eta.courier_speed_kmh must be at least 1")
+	check(c.ETA.DetourPercent >= 100, "eta.detour_percent must be at least 100")
+	check(c.ETA.Handover >= 0 && c.ETA.CourierWait >= 0, "eta.handover and eta.courier_wait must not be negative")
+	check(c.Dispatch.MaxStops >= 1, "dispatch.max_stops must be at least 1")
+	check(c.Dispatch.RadiusMetres >= 0 && c.Dispatch.Window >= 0, "dispatch.radius_metres and dispatch.window must not be negative")
+	check(c.Tracking.TrailLength >= 2, "tracking.trail_length must be at least 2")
+	check(!c.TLS.Enabled || c.TLS.CertFile != "" && c.TLS.KeyFile != "", "tls.cert_file and tls.key_file are required when TLS is enabled")
+	check(c.TLS.MinVersion == "1.2" || c.TLS.MinVersion == "1.3", "tls.min_version: unknown version %q", c.TLS.MinVersion)
+	check(c.TLS.CipherPolicy == CipherPolicyDefault || c.TLS.CipherPolicy == CipherPolicyStrict, "tls.cipher_policy: unknown policy %q", c.TLS.CipherPolicy)
+	check(c.TLS.ReloadInterval > 0, "tls.reload_interval must be positive")
+	check(!c.CORS.AllowCredentials || !slices.Contains(c.CORS.AllowedOrigins, "*"), "cors.allow_credentials cannot be combined with the \"*\" origin")
+	check(c.CORS.MaxAge >= 0, "cors.max_age must not be negative")
+	check(c.HTTP.MaxBodyBytes > 0, "http.max_body_bytes must be positive")
+	for route, limit := range c.HTTP.RouteMaxBodyBytes {
+		check(limit >= 0, "http.route_max_body_bytes: limit of %s must not be negative", route)
+	}
+	check(c.HTTP.CompressMinBytes >= 0, "http.compress_min_bytes must not be negative")
+	var level slog.Level
+	check(level.UnmarshalText([]byte(c.Log.Level)) == nil, "log.level: unknown level %q", c.Log.Level)
+	check(c.Traces.Exporter == "none" || c.Traces.Exporter == "otlp" || c.Traces.Exporter == "stdout",
+		"traces.exporter: unknown exporter %q", c.Traces.Exporter)
+
+	return errors.Join(errs...)
+}
+
+// LogLevel is the parsed log level; Validate has already rejected bad names
+func (c Config) LogLevel() slog.Level {
+	var level slog.Level
+	level.UnmarshalText([]byte(c.Log.Level))
+	return level
+}
+
+// setting is one addressable field of Config
+type setting struct {
+	key   string
+	value reflect.Value
+}
+
+// settingsOf lists the fields of every section, keyed section.key after their
+// yaml tags
+func settingsOf(config *Config) []setting {
+	var settings []setting
+	sections := reflect.ValueOf(config).Elem()
+	for i := 0; i < sections.NumField(); i++ {
+		section := sections.Field(i)
+		sectionName := sections.Type().Field(i).Tag.Get("yaml")
+		for j := 0; j < section.NumField(); j++ {
+			name := section.Type().Field(j).Tag.Get("yaml")
+			settings = append(settings, setting{key: sectionName + "." + name, value: section.Field(j)})
+		}
+	}
+	return settings
+}
+
+// flagName is the key with hyphens, e.g. server.http-addr
+func (s setting) flagName() string {
+	return strings.ReplaceAll(s.key, "_", "-")
+}
+
+func (s setting) envName() string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(s.key, ".", "_"))
+}
+
+func (s setting) set(value string) error {
+	switch {
+	case s.value.Type() == reflect.TypeOf(time.Duration(0)):
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		s.value.SetInt(int64(duration))
+	case s.value.Kind() == reflect.Bool:
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		s.value.SetBool(enabled)
+	case s.value.Kind() == reflect.Slice:
+		var values []string
+		for _, value := range strings.Split(value, ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				values = append(values, value)
+			}
+		}
+		s.value.Set(reflect.ValueOf(values))
+	case s.value.Kind() == reflect.Map:
+		// entries add to or override the limits set so far, as in files
+		values := maps.Clone(s.value.Interface().(map[string]int64))
+		if values == nil {
+			values = make(map[string]int64)
+		}
+		for _, entry := range strings.Split(value, ",") {
+			if entry = strings.TrimSpace(entry); entry == "" {
+				continue
+			}
+			key, number, ok := strings.Cut(entry, "=")
+			if !ok {
+				return fmt.Errorf("%q is not key=value", entry)
+			}
+			parsed, err := strconv.ParseInt(number, 10, 64)
+			if err != nil {
+				return err
+			}
+			values[strings.TrimSpace(key)] = parsed
+		}
+		s.value.Set(reflect.ValueOf(values))
+	case s.value.Kind() == reflect.Int, s.value.Kind() == reflect.Int64:
+		number, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return err
+		}
+		s.value.SetInt(number)
+	default:
+		s.value.SetString(value)
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package config
+
+import (
+	"flag"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func env(vars map[string]string) func(string) string {
+	return func(key string) string { return vars[key] }
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func TestLoadDefaults(t *testing.T) {
+	config, err := Load(nil, env(nil), io.Discard)
+	require.NoError(t, err)
+	assert.Equal(t, Default(), config)
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := writeFile(t, "weservefood.yaml", `
+server:
+  http_addr: ":8000"
+  grpc_addr: ":9000"
+timeouts:
+  write: 10s
+business:
+  delivery_fee_cents: 199
+`)
+	vars := map[string]string{
+		"WESERVEFOOD_SERVER_GRPC_ADDR":            ":9100",
+		"WESERVEFOOD_BUSINESS_DELIVERY_FEE_CENTS": "99",
+	}
+
+	config, err := Load([]string{"-config", path, "-business.delivery-fee-cents", "0"}, env(vars), io.Discard)
+	require.NoError(t, err)
+
+	assert.Equal(t, ":8000", config.Server.HTTPAddr)
+	assert.Equal(t, ":9100", config.Server.GRPCAddr)
+	assert.Equal(t, 10*time.Second, config.Timeouts.Write)
+	assert.Equal(t, 0, config.Business.DeliveryFeeCents)
+	assert.Equal(t, 30*time.Second, config.Timeouts.Read)
+}
+
+func TestLoadFileFromEnv(t *testing.T) {
+	path := writeFile(t, "weservefood.toml", `
+[business]
+slot_capacity = 3
+delivery_offset = "45m"
+
+[log]
+level = "debug"
+`)
+
+	config, err := Load(nil, env(map[string]string{FileEnv: path}), io.Discard)
+	require.NoError(t, err)
+
+	assert.Equal(t, 3, config.Business.SlotCapacity)
+	assert.Equal(t, 45*time.Minute, config.Business.DeliveryOffset)
+	assert.Equal(t, slog.LevelDebug, config.LogLevel())
+}
+
+func TestLoadRejectsUnknownKeys(t *testing.T) {
+	for name, content := range map[string]string{
+		"typo.yaml": "server:\n  http_adr: \":8000\"\n",
+		"typo.toml": "[server]\nhttp_adr = \":8000\"\n",
+	} {
+		_, err := Load([]string{"-config", writeFile(t, name, content)}, env(nil), io.Discard)
+		assert.Error(t, err, name)
+	}
+
+	_, err := Load([]string{"-config", writeFile(t, "weservefood.json", "{}")}, env(nil), io.Discard)
+	assert.Error(t, err)
+}
+
+func TestLoadInvalidValues(t *testing.T) {
+	_, err := Load(nil, env(map[string]string{"WESERVEFOOD_TIMEOUTS_READ": "soon"}), io.Discard)
+	assert.Error(t, err)
+
+	_, err = Load([]string{"-business.slot-capacity", "many"}, env(nil), io.Discard)
+	assert.Error(t, err)
+
+	_, err = Load([]string{"-help"}, env(nil), io.Discard)
+	assert.ErrorIs(t, err, flag.ErrHelp)
+}
+
+func TestValidate(t *testing.T) {
+	config := Default()
+	config.Server.HTTPAddr = "8383"
+	config.Auth.RestaurantTokens = []string{"r-curry-house"}
+	config.Auth.CourierTokens = []string{"=courier-secret"}
+	config.Storage.Backend = "postgres"
+	config.Timeouts.Shutdown = 0
+	config.Business.DeliveryFeeCents = -1
+	config.Business.SlotCapacity = 0
+	config.Business.LateRefundPercent = 150
+	config.Business.ProofOfDelivery = "selfie"
+	config.ETA.KitchenSlots = 0
+	config.ETA.DetourPercent = 90
+	config.Dispatch.MaxStops = 0
+	config.Tracking.TrailLength = 1
+	config.Log.Level = "loud"
+	config.Traces.Exporter = "zipkin"
+
+	err := config.Validate()
+	require.Error(t, err)
+	for _, key := range []string{"server.http_addr", "auth.restaurant_tokens", "auth.courier_tokens", "storage.backend", "timeouts.shutdown", "business.delivery_fee_cents", "business.slot_capacity", "business.late_refund_percent", "business.proof_of_delivery", "eta.kitchen_slots", "eta.detour_percent", "dispatch.max_stops", "tracking.trail_length", "log.level", "traces.exporter"} {
+		assert.Contains(t, err.Error(), key)
+	}
+	assert.NoError(t, Default().Validate())
+}
+
+func TestLoadBoolSettings(t *testing.T) {
+	config, err := Load([]string{"-tls.enabled", "-tls.cert-file", "server.crt", "-tls.key-file", "server.key"},
+		env(map[string]string{"WESERVEFOOD_TLS_REDIRECT": "false"}), io.Discard)
+	require.NoError(t, err)
+	assert.True(t, config.TLS.Enabled)
+	assert.False(t, config.TLS.Redirect)
+
+	_, err = Load([]string{"-tls.enabled"}, env(nil), io.Discard)
+	assert.Error(t, err)
+}
+
+func TestLoadListSettings(t *testing.T) {
+	path := writeFile(t, "weservefood.yaml", `
+cors:
+  allowed_origins: ["https://app.example.com"]
+  allowed_methods: [GET]
+`)
+	config, err := Load([]string{"-config", path, "-cors.allowed-headers", "Content-Type, X-Trace"}, env(nil), io.Discard)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"https://app.example.com"}, config.CORS.AllowedOrigins)
+	assert.Equal(t, []string{"GET"}, config.CORS.AllowedMethods)
+	assert.Equal(t, []string{"Content-Type", "X-Trace"}, config.CORS.AllowedHeaders)
+
+	_, err = Load([]string{"-cors.allowed-origins", "*", "-cors.allow-credentials"}, env(nil), io.Discard)
+	assert.Error(t, err)
+}
+
+func TestLoadRouteLimits(t *testing.T) {
+	path := writeFile(t, "weservefood.toml", `
+[http.route_max_body_bytes]
+"/v1/orders" = 4096
+`)
+	config, err := Load([]string{"-config", path, "-http.route-max-body-bytes", "/graphql=65536"}, env(nil), io.Discard)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int64{"/v1/orders": 4096, "/v1/admin/orders/import": 256 << 20, "/v1/orders/{id}/delivery": 16 << 20, "/graphql": 65536}, config.HTTP.RouteMaxBodyBytes)
+	assert.Equal(t, map[string]int64{"/v1/admin/orders/import": 256 << 20, "/v1/orders/{id}/delivery": 16 << 20}, Default().HTTP.RouteMaxBodyBytes)
+
+	_, err = Load([]string{"-http.route-max-body-bytes", "/graphql"}, env(nil), io.Discard)
+	assert.Error(t, err)
+}
+
+func TestLoadCancellationSettings(t *testing.T) {
+	config, err := Load([]string{"-cancellation.customer-statuses", "placed", "-cancellation.customer-cutoff", "20m"},
+		env(map[string]string{"WESERVEFOOD_CANCELLATION_SUPPORT_STATUSES": "placed,confirmed"}), io.Discard)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"placed"}, config.Cancellation.CustomerStatuses)
+	assert.Equal(t, 20*time.Minute, config.Cancellation.CustomerCutoff)
+	assert.Equal(t, []string{"placed", "confirmed"}, config.Cancellation.SupportStatuses)
+	assert.Equal(t, Default().Cancellation.RestaurantStatuses, config.Cancellation.RestaurantStatuses)
+
+	_, err = Load([]string{"-cancellation.restaurant-statuses", "placed,cancelled"}, env(nil), io.Discard)
+	assert.ErrorContains(t, err, "cancellation.restaurant_statuses")
+}
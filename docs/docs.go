@@ -36,6 +36,13 @@ const docTemplate = `{
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cancellation reason",
+                        "name": "reason",
+                        "in": "query",
+                        "required": true
                     }
                 ],
                 "responses": {
@@ -109,6 +116,74 @@ const docTemplate = `{
                 }
             }
         },
+        "/graphql": {
+            "post": {
+                "description": "Execute GraphQL queries and mutations over orders, menus and couriers.\nSubscriptions are streamed as server-sent events.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json",
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "graphql"
+                ],
+                "summary": "GraphQL endpoint",
+                "parameters": [
+                    {
+                        "description": "GraphQL request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/graphqlapi.Request"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "GraphQL result with data and errors",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid Request Payload",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "413": {
+                        "description": "request body too large",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/healthz": {
+            "get": {
+                "description": "Report that the process is running; it never depends on other components",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Liveness probe",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handler.liveness"
+                        }
+                    }
+                }
+            }
+        },
         "/ping": {
             "get": {
                 "description": "Check server availability",
@@ -149,9 +224,9 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "200": {
-                        "description": "Order Details",
+                        "description": "Order Details, with the delivery PIN",
                         "schema": {
-                            "$ref": "#/definitions/models.Order"
+                            "$ref": "#/definitions/models.PlacedOrder"
                         }
                     },
                     "400": {
@@ -160,6 +235,12 @@ const docTemplate = `{
                             "type": "string"
                         }
                     },
+                    "413": {
+                        "description": "request body too large",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -169,6 +250,32 @@ const docTemplate = `{
                 }
             }
         },
+        "/readyz": {
+            "get": {
+                "description": "Report whether the server should receive traffic, with the health of the storage backend and background workers. It is unavailable while orders are replayed at startup and while draining on shutdown.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Readiness probe",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/health.Report"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/health.Report"
+                        }
+                    }
+                }
+            }
+        },
         "/update-address/{email}/{id}": {
             "put": {
                 "description": "Update the delivery address for an order",
@@ -211,37 +318,2077 @@ const docTemplate = `{
                         "schema": {
                             "type": "string"
                         }
+                    },
+                    "413": {
+                        "description": "request body too large",
+                        "schema": {
+                            "type": "string"
+                        }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "models.Order": {
-            "type": "object",
-            "properties": {
-                "address": {
-                    "type": "string"
-                },
-                "delivery_time": {
-                    "type": "string"
-                },
-                "email": {
-                    "type": "string"
-                },
-                "id": {
-                    "type": "string"
-                },
-                "items": {
-                    "type": "array",
-                    "items": {
-                        "type": "string"
+        },
+        "/v1/admin/dispatch": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Plan batches as the preview does and assign each batch a courier is free for to that courier. The delivery estimates of its orders follow the planned route. Batches no courier is free for are left for the next dispatch.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Dispatch batched deliveries",
+                "responses": {
+                    "200": {
+                        "description": "dispatched batches",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Batch"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "a valid bearer token is required",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/admin/dispatch/batches/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieve a dispatched batch with its stops in the order the courier makes them",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get a batch",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Batch ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Batch"
+                        }
+                    },
+                    "401": {
+                        "description": "a valid bearer token is required",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "batch not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/admin/dispatch/plan": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Group the orders being prepared into courier runs and order the stops of each run, without dispatching anything. Orders only share a courier when their drop-offs are close together, they are due close together and every stop stays on time. A batch without a courier_id has no courier free to take it.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Preview batched deliveries",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Batch"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "a valid bearer token is required",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/admin/orders/export": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Stream every order matching the filter, cancelled ones included, oldest first.\nRows carry the price breakdown in cents and the time the order entered each status.",
+                "produces": [
+                    "text/csv",
+                    "application/x-ndjson",
+                    "application/vnd.apache.parquet"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Export orders",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "csv (default), ndjson or parquet",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Orders created at or after this RFC 3339 time or date",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Orders created before this RFC 3339 time, or on or before this date",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only orders in this status",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only orders placed with this restaurant",
+                        "name": "restaurant_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "The exported orders",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid export parameters",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "a valid bearer token is required",
+                        "schema": {
+                            "type": "string"
+                        }
                     }
-                },
-                "name": {
-                    "type": "string"
                 }
             }
+        },
+        "/v1/admin/orders/import": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Stream orders from a JSONL or CSV document through the same validation as placing an order.\nThe response is NDJSON: one result per record followed by a line holding the summary.\nRestart an interrupted import with resume_after set to the last reported line.",
+                "consumes": [
+                    "text/plain"
+                ],
+                "produces": [
+                    "application/x-ndjson"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Import orders",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "jsonl or csv (defaults to the Content-Type)",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Validate without creating orders",
+                        "name": "dry_run",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Skip records on or before this line",
+                        "name": "resume_after",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "One line per record, then the summary",
+                        "schema": {
+                            "$ref": "#/definitions/bulkimport.LineResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid import parameters",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "a valid bearer token is required",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "413": {
+                        "description": "request body too large",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/admin/orders/{id}/cancel": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Cancel any order by order ID, giving a reason; the email is not needed. The cancellation policy decides which statuses support may cancel, and until how long before delivery.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Cancel an order as support",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Order ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Cancellation details",
+                        "name": "cancellation",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.OrderCancellation"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Order Cancelled Successfully",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid Request Payload",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "a valid bearer token is required",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "order not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "cancellation rejected: support cannot cancel an order that is delivered",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "413": {
+                        "description": "request body too large",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/admin/orders/{id}/proof/{kind}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Download the photo or signature a courier gave as proof of delivery, to settle disputes",
+                "produces": [
+                    "image/jpeg",
+                    "image/png"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get a proof of delivery",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Order ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "photo or signature",
+                        "name": "kind",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "401": {
+                        "description": "a valid bearer token is required",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "proof of delivery not found: the order has no photo",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/admin/orders/{id}/trail": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieve the pings the courier reported while the order was out for delivery, oldest first, to settle disputes about the delivery. Long rides are thinned to tracking.trail_length pings, keeping the first and the latest.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Replay a delivery route",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Order ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.DeliveryTrail"
+                        }
+                    },
+                    "401": {
+                        "description": "a valid bearer token is required",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "order not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/admin/restaurants/{id}/zones": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieve the delivery zones of a restaurant in the order they are matched against addresses",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "List delivery zones",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Restaurant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.DeliveryZone"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "a valid bearer token is required",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "restaurant not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/admin/restaurants/{id}/zones/{name}": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Add a delivery zone to a restaurant, or replace the zone of the same name. The zone covers the postal codes or districts in areas and the addresses located inside the GeoJSON polygon in boundary, and orders delivered there pay its fee and must reach its minimum order. Orders already placed keep their price. The change is saved to the catalog file, and not applied when that fails.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Create or replace a delivery zone",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Restaurant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Zone name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Delivery zone",
+                        "name": "zone",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.DeliveryZone"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "zone replaced",
+                        "schema": {
+                            "$ref": "#/definitions/models.DeliveryZone"
+                        }
+                    },
+                    "201": {
+                        "description": "zone added",
+                        "schema": {
+                            "$ref": "#/definitions/models.DeliveryZone"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid delivery zone: zone centre: invalid polygon: ring 0 is not closed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "a valid bearer token is required",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "restaurant not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "saving the catalog: permission denied",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Stop delivering to a zone of a restaurant. Orders already placed there are not affected. The change is saved to the catalog file, and not applied when that fails.",
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Delete a delivery zone",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Restaurant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Zone name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "zone deleted"
+                    },
+                    "401": {
+                        "description": "a valid bearer token is required",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "delivery zone not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "saving the catalog: permission denied",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/couriers": {
+            "get": {
+                "description": "Retrieve all couriers and their availability",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "v1"
+                ],
+                "summary": "List couriers",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Courier"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/couriers/{id}/pings": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Record GPS pings from a courier's app against every order the courier has out for delivery. Pings buffered while offline may be sent together and in any order; pings older than the last one recorded for an order are dropped. The latest position is shown on the order as courier_position. Only the courier's own token is accepted.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "v1"
+                ],
+                "summary": "Report courier locations",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Courier ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "GPS pings",
+                        "name": "pings",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.CourierPings"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "orders the pings were recorded against",
+                        "schema": {
+                            "$ref": "#/definitions/models.PingReceipt"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid location ping: ping 0 is recorded in the future",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "a valid bearer token is required",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "the token belongs to another courier",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "courier not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/orders": {
+            "get": {
+                "description": "Retrieve all active orders, optionally filtered by the customer email",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "v1"
+                ],
+                "summary": "List orders",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User Email",
+                        "name": "email",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Order"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Create a new food order. The address may be given as a structured delivery_address instead, which is geocoded from its postal code; a location it carries must be near the postal code and refines it.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "v1"
+                ],
+                "summary": "Create an order",
+                "parameters": [
+                    {
+                        "description": "Order Details",
+                        "name": "order",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Order"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Order Details, with the delivery PIN",
+                        "schema": {
+                            "$ref": "#/definitions/models.PlacedOrder"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid Request Payload",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "413": {
+                        "description": "request body too large",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/orders/{id}": {
+            "get": {
+                "description": "Retrieve a single order by its ID",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "v1"
+                ],
+                "summary": "Get an order",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Order ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Order"
+                        }
+                    },
+                    "404": {
+                        "description": "order not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Update the delivery address of an order until it is out for delivery. The address must be in a delivery zone of the restaurant; the delivery fee and due time follow the zone, and a change of total is only applied when confirm_total_cents repeats the new total. A structured delivery_address is geocoded from its postal code, refined by a location near it, and replaces address.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "v1"
+                ],
+                "summary": "Update an order",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Order ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Order changes",
+                        "name": "patch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.OrderPatch"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Order"
+                        }
+                    },
+                    "400": {
+                        "description": "address is outside the delivery zone",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "email does not match",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "order not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "address change alters the price: the total becomes 1498 cents instead of 1299; confirm the new total to go ahead",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "413": {
+                        "description": "request body too large",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/orders/{id}/cancel": {
+            "post": {
+                "description": "Cancel an order as the customer who placed it, by order ID and the email it was placed with, giving a reason. The cancellation policy decides which statuses customers may cancel, and until how long before delivery.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "v1"
+                ],
+                "summary": "Cancel an order",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Order ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Cancellation details",
+                        "name": "cancellation",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.OrderCancellation"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Order Cancelled Successfully",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid Request Payload",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "order not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "cancellation rejected: customer cannot cancel an order that is out_for_delivery",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "413": {
+                        "description": "request body too large",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/orders/{id}/delivery": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Complete an order out for delivery with the proof it requires (proof_required on the order): the PIN shown to the customer, a photo of the handover or the customer's signature. Send JSON when only the PIN is given, or multipart/form-data with the fields pin, lat and lng and the JPEG or PNG files photo and signature. The courier is the one the bearer token belongs to, and only the courier carrying the order may deliver it; five wrong PINs lock the order until support steps in.",
+                "consumes": [
+                    "application/json",
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "v1"
+                ],
+                "summary": "Deliver an order",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Order ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "PIN and location, when not sent as multipart/form-data",
+                        "name": "confirmation",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/models.DeliveryConfirmation"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Order"
+                        }
+                    },
+                    "400": {
+                        "description": "proof of delivery required: the order requires a photo of the handover",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "a valid bearer token is required",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "delivery PIN does not match: 4 attempts left",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "order not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "delivery PIN locked after too many wrong attempts",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "413": {
+                        "description": "request body too large",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/orders/{id}/payment": {
+            "post": {
+                "description": "Authorize the order total on a payment method and confirm the order. A declined payment leaves the order placed, with the reason on its payment, so it can be retried.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "v1"
+                ],
+                "summary": "Pay for an order",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Order ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Payment details",
+                        "name": "payment",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.PaymentRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Order"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid Request Payload",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "402": {
+                        "description": "payment declined",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "email does not match",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "order not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "order is already confirmed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "413": {
+                        "description": "request body too large",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/restaurants": {
+            "get": {
+                "description": "Retrieve all restaurants together with their menus",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "v1"
+                ],
+                "summary": "List restaurants",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Restaurant"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/restaurants/{id}/menu": {
+            "get": {
+                "description": "Retrieve the menu of a restaurant",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "v1"
+                ],
+                "summary": "Get a menu",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Restaurant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.MenuItem"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "restaurant not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/restaurants/{id}/orders/{orderId}/cancel": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Cancel an order placed with the restaurant, giving a reason; the email is not needed. Only the restaurant's own token is accepted. The cancellation policy decides which statuses restaurants may cancel, and until how long before delivery.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "v1"
+                ],
+                "summary": "Cancel an order as the restaurant",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Restaurant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Order ID",
+                        "name": "orderId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Cancellation details",
+                        "name": "cancellation",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.OrderCancellation"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Order Cancelled Successfully",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid Request Payload",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "a valid bearer token is required",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "the token belongs to another restaurant",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "order not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "cancellation rejected: restaurant cannot cancel an order that is out_for_delivery",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "413": {
+                        "description": "request body too large",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "bulkimport.LineResult": {
+            "type": "object",
+            "properties": {
+                "delivery_pin": {
+                    "description": "DeliveryPIN is the placed order's PIN, for the importer to pass on to the customer",
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "line": {
+                    "type": "integer"
+                },
+                "ok": {
+                    "type": "boolean"
+                },
+                "order_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "graphqlapi.Request": {
+            "type": "object",
+            "properties": {
+                "operationName": {
+                    "type": "string"
+                },
+                "query": {
+                    "type": "string"
+                },
+                "variables": {
+                    "type": "object",
+                    "additionalProperties": true
+                }
+            }
+        },
+        "handler.liveness": {
+            "type": "object",
+            "properties": {
+                "phase": {
+                    "type": "string",
+                    "example": "serving"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "ok"
+                }
+            }
+        },
+        "health.CheckResult": {
+            "type": "object",
+            "properties": {
+                "details": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "error": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "ok"
+                }
+            }
+        },
+        "health.Report": {
+            "type": "object",
+            "properties": {
+                "checks": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/health.CheckResult"
+                    }
+                },
+                "phase": {
+                    "type": "string",
+                    "example": "serving"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "ok"
+                }
+            }
+        },
+        "models.Address": {
+            "type": "object",
+            "properties": {
+                "city": {
+                    "type": "string",
+                    "example": "Berlin"
+                },
+                "country": {
+                    "description": "Country is an ISO 3166-1 alpha-2 code",
+                    "type": "string",
+                    "example": "DE"
+                },
+                "delivery_instructions": {
+                    "type": "string",
+                    "example": "ring twice"
+                },
+                "location": {
+                    "description": "Location is filled in by geocoding unless the customer gives it",
+                    "$ref": "#/definitions/models.LatLng"
+                },
+                "postal_code": {
+                    "type": "string",
+                    "example": "10115"
+                },
+                "street": {
+                    "type": "string",
+                    "example": "Invalidenstraße 43"
+                },
+                "unit": {
+                    "type": "string",
+                    "example": "3rd floor"
+                }
+            }
+        },
+        "models.AddressChange": {
+            "type": "object",
+            "properties": {
+                "at": {
+                    "type": "string"
+                },
+                "delivery_fee_cents": {
+                    "type": "integer"
+                },
+                "from": {
+                    "type": "string"
+                },
+                "to": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Batch": {
+            "type": "object",
+            "properties": {
+                "courier_id": {
+                    "description": "CourierID is empty when no courier is free to take the batch",
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "depart_at": {
+                    "type": "string"
+                },
+                "distance_metres": {
+                    "type": "integer"
+                },
+                "id": {
+                    "description": "ID is only set once the batch is dispatched",
+                    "type": "string"
+                },
+                "restaurant_id": {
+                    "type": "string"
+                },
+                "stops": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.BatchStop"
+                    }
+                }
+            }
+        },
+        "models.BatchStop": {
+            "type": "object",
+            "properties": {
+                "arrive_at": {
+                    "type": "string"
+                },
+                "due_at": {
+                    "type": "string"
+                },
+                "leg_metres": {
+                    "description": "LegMetres is the ride from the previous stop, or from the restaurant",
+                    "type": "integer"
+                },
+                "location": {
+                    "$ref": "#/definitions/models.LatLng"
+                },
+                "on_time": {
+                    "type": "boolean"
+                },
+                "order_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Cancellation": {
+            "type": "object",
+            "properties": {
+                "at": {
+                    "type": "string"
+                },
+                "initiator": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Courier": {
+            "type": "object",
+            "properties": {
+                "available": {
+                    "type": "boolean"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "phone": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.CourierPings": {
+            "type": "object",
+            "properties": {
+                "pings": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.LocationPing"
+                    }
+                }
+            }
+        },
+        "models.DeliveryConfirmation": {
+            "type": "object",
+            "properties": {
+                "location": {
+                    "description": "Location is where the courier was on handover, when the app knows it",
+                    "$ref": "#/definitions/models.LatLng"
+                },
+                "pin": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.DeliveryEstimate": {
+            "type": "object",
+            "properties": {
+                "courier_wait_minutes": {
+                    "type": "integer"
+                },
+                "distance_metres": {
+                    "description": "DistanceMetres is the ride along the road, when both ends are located",
+                    "type": "integer"
+                },
+                "estimated_at": {
+                    "type": "string"
+                },
+                "handover_minutes": {
+                    "type": "integer"
+                },
+                "prep_minutes": {
+                    "type": "integer"
+                },
+                "queue_minutes": {
+                    "description": "QueueMinutes is spent waiting for the orders ahead in the kitchen",
+                    "type": "integer"
+                },
+                "travel_minutes": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.DeliveryProof": {
+            "type": "object",
+            "properties": {
+                "courier_id": {
+                    "type": "string"
+                },
+                "delivered_at": {
+                    "type": "string"
+                },
+                "location": {
+                    "$ref": "#/definitions/models.LatLng"
+                },
+                "method": {
+                    "type": "string"
+                },
+                "photo_key": {
+                    "description": "PhotoKey and SignatureKey name the uploads in the blob store",
+                    "type": "string"
+                },
+                "pin_confirmed": {
+                    "type": "boolean"
+                },
+                "signature_key": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.DeliveryTrail": {
+            "type": "object",
+            "properties": {
+                "courier_id": {
+                    "type": "string"
+                },
+                "distance_metres": {
+                    "description": "DistanceMetres is the straight-line length of the trail",
+                    "type": "integer"
+                },
+                "order_id": {
+                    "type": "string"
+                },
+                "pings": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.LocationPing"
+                    }
+                }
+            }
+        },
+        "models.DeliveryZone": {
+            "type": "object",
+            "properties": {
+                "areas": {
+                    "description": "Areas are postal codes or district names; an address is in the zone\nwhen its postal code or locality is one of them",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "boundary": {
+                    "description": "Boundary outlines the zone; a located address is in the zone when it\nlies inside the polygon",
+                    "$ref": "#/definitions/models.Polygon"
+                },
+                "delivery_fee_cents": {
+                    "type": "integer"
+                },
+                "delivery_minutes": {
+                    "description": "DeliveryMinutes is the ride into the zone, used when the distance to an\naddress cannot be measured",
+                    "type": "integer"
+                },
+                "minimum_order_cents": {
+                    "description": "MinimumOrderCents is the smallest subtotal delivered to the zone",
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.LatLng": {
+            "type": "object",
+            "properties": {
+                "lat": {
+                    "type": "number",
+                    "example": 52.5323
+                },
+                "lng": {
+                    "type": "number",
+                    "example": 13.3846
+                }
+            }
+        },
+        "models.LocationPing": {
+            "type": "object",
+            "properties": {
+                "accuracy_metres": {
+                    "description": "AccuracyMetres is the radius the fix is accurate to, when the app reports it",
+                    "type": "number",
+                    "example": 12
+                },
+                "location": {
+                    "$ref": "#/definitions/models.LatLng"
+                },
+                "recorded_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.MenuItem": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string"
+                },
+                "prep_minutes": {
+                    "description": "PrepMinutes is how long the dish takes to prepare; zero takes the default",
+                    "type": "integer"
+                },
+                "price_cents": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.Order": {
+            "type": "object",
+            "properties": {
+                "address": {
+                    "type": "string"
+                },
+                "address_history": {
+                    "description": "AddressHistory records every change of the delivery address, oldest first",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.AddressChange"
+                    }
+                },
+                "batch_id": {
+                    "description": "BatchID is the courier run the order was dispatched in",
+                    "type": "string"
+                },
+                "cancellation": {
+                    "description": "Cancellation is set once the order is cancelled",
+                    "$ref": "#/definitions/models.Cancellation"
+                },
+                "courier_id": {
+                    "type": "string"
+                },
+                "courier_position": {
+                    "description": "CourierPosition is the courier's latest fix while the order is out for delivery",
+                    "$ref": "#/definitions/models.LocationPing"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "delivery": {
+                    "description": "Delivery records the proof given once the order is delivered",
+                    "$ref": "#/definitions/models.DeliveryProof"
+                },
+                "delivery_address": {
+                    "description": "DeliveryAddress is the structured form of Address; when it is given,\nAddress is its single line form",
+                    "$ref": "#/definitions/models.Address"
+                },
+                "delivery_time": {
+                    "type": "string"
+                },
+                "delivery_zone": {
+                    "description": "DeliveryZone names the restaurant zone the address is in",
+                    "type": "string"
+                },
+                "due_at": {
+                    "description": "DueAt is when the order is expected to be delivered",
+                    "type": "string"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "eta": {
+                    "description": "Estimate explains DueAt for orders placed against a restaurant; it is\nupdated as the order moves on",
+                    "$ref": "#/definitions/models.DeliveryEstimate"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "name": {
+                    "type": "string"
+                },
+                "payment": {
+                    "description": "Payment is set once payment has been attempted for the order",
+                    "$ref": "#/definitions/models.PaymentIntent"
+                },
+                "price": {
+                    "description": "Price is only known for orders placed against a restaurant menu",
+                    "$ref": "#/definitions/models.PriceBreakdown"
+                },
+                "promised_at": {
+                    "description": "PromisedAt is the delivery time the customer was given when ordering, or\nwhen they last changed the address; DueAt moves on from it as the order\nis fulfilled",
+                    "type": "string"
+                },
+                "proof_required": {
+                    "description": "ProofRequired is the proof the courier must give to complete the order",
+                    "type": "string",
+                    "example": "pin"
+                },
+                "restaurant_id": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "status_history": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.StatusChange"
+                    }
+                }
+            }
+        },
+        "models.OrderCancellation": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "description": "Email is only needed from customers",
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string",
+                    "example": "ordered by mistake"
+                }
+            }
+        },
+        "models.OrderPatch": {
+            "type": "object",
+            "properties": {
+                "address": {
+                    "type": "string"
+                },
+                "confirm_total_cents": {
+                    "description": "ConfirmTotalCents accepts the new total when the address changes the price",
+                    "type": "integer"
+                },
+                "delivery_address": {
+                    "description": "DeliveryAddress replaces the address with a structured one; Address is\nignored when it is given",
+                    "$ref": "#/definitions/models.Address"
+                },
+                "email": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.PaymentIntent": {
+            "type": "object",
+            "properties": {
+                "amount_cents": {
+                    "type": "integer"
+                },
+                "authorization_id": {
+                    "type": "string"
+                },
+                "captured_cents": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "currency": {
+                    "type": "string"
+                },
+                "failure_reason": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "refunded_cents": {
+                    "type": "integer"
+                },
+                "refunds": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Refund"
+                    }
+                },
+                "status": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.PaymentRequest": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "string"
+                },
+                "payment_method": {
+                    "description": "PaymentMethod is the gateway token of the customer's card",
+                    "type": "string",
+                    "example": "tok_visa"
+                }
+            }
+        },
+        "models.PingReceipt": {
+            "type": "object",
+            "properties": {
+                "orders": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "models.PlacedOrder": {
+            "type": "object",
+            "properties": {
+                "address": {
+                    "type": "string"
+                },
+                "address_history": {
+                    "description": "AddressHistory records every change of the delivery address, oldest first",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.AddressChange"
+                    }
+                },
+                "batch_id": {
+                    "description": "BatchID is the courier run the order was dispatched in",
+                    "type": "string"
+                },
+                "cancellation": {
+                    "description": "Cancellation is set once the order is cancelled",
+                    "$ref": "#/definitions/models.Cancellation"
+                },
+                "courier_id": {
+                    "type": "string"
+                },
+                "courier_position": {
+                    "description": "CourierPosition is the courier's latest fix while the order is out for delivery",
+                    "$ref": "#/definitions/models.LocationPing"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "delivery": {
+                    "description": "Delivery records the proof given once the order is delivered",
+                    "$ref": "#/definitions/models.DeliveryProof"
+                },
+                "delivery_address": {
+                    "description": "DeliveryAddress is the structured form of Address; when it is given,\nAddress is its single line form",
+                    "$ref": "#/definitions/models.Address"
+                },
+                "delivery_pin": {
+                    "type": "string",
+                    "example": "4821"
+                },
+                "delivery_time": {
+                    "type": "string"
+                },
+                "delivery_zone": {
+                    "description": "DeliveryZone names the restaurant zone the address is in",
+                    "type": "string"
+                },
+                "due_at": {
+                    "description": "DueAt is when the order is expected to be delivered",
+                    "type": "string"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "eta": {
+                    "description": "Estimate explains DueAt for orders placed against a restaurant; it is\nupdated as the order moves on",
+                    "$ref": "#/definitions/models.DeliveryEstimate"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "name": {
+                    "type": "string"
+                },
+                "payment": {
+                    "description": "Payment is set once payment has been attempted for the order",
+                    "$ref": "#/definitions/models.PaymentIntent"
+                },
+                "price": {
+                    "description": "Price is only known for orders placed against a restaurant menu",
+                    "$ref": "#/definitions/models.PriceBreakdown"
+                },
+                "promised_at": {
+                    "description": "PromisedAt is the delivery time the customer was given when ordering, or\nwhen they last changed the address; DueAt moves on from it as the order\nis fulfilled",
+                    "type": "string"
+                },
+                "proof_required": {
+                    "description": "ProofRequired is the proof the courier must give to complete the order",
+                    "type": "string",
+                    "example": "pin"
+                },
+                "restaurant_id": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "status_history": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.StatusChange"
+                    }
+                }
+            }
+        },
+        "models.Polygon": {
+            "type": "object",
+            "properties": {
+                "coordinates": {
+                    "type": "array",
+                    "items": {
+                        "type": "array",
+                        "items": {
+                            "type": "array",
+                            "items": {
+                                "type": "number"
+                            }
+                        }
+                    }
+                },
+                "type": {
+                    "type": "string",
+                    "example": "Polygon"
+                }
+            }
+        },
+        "models.PriceBreakdown": {
+            "type": "object",
+            "properties": {
+                "delivery_fee_cents": {
+                    "type": "integer"
+                },
+                "subtotal_cents": {
+                    "type": "integer"
+                },
+                "total_cents": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.Refund": {
+            "type": "object",
+            "properties": {
+                "amount_cents": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Restaurant": {
+            "type": "object",
+            "properties": {
+                "address": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "location": {
+                    "description": "Location is where couriers pick orders up, used to measure the ride",
+                    "$ref": "#/definitions/models.LatLng"
+                },
+                "menu": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.MenuItem"
+                    }
+                },
+                "name": {
+                    "type": "string"
+                },
+                "zones": {
+                    "description": "Zones are the areas the restaurant delivers to; without zones it\ndelivers anywhere at the default fee and time",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.DeliveryZone"
+                    }
+                }
+            }
+        },
+        "models.StatusChange": {
+            "type": "object",
+            "properties": {
+                "at": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        }
+    },
+    "securityDefinitions": {
+        "BearerAuth": {
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
         }
     }
 }`
@@ -0,0 +1,124 @@
+// Package eta estimates when an order will be delivered from how long its
+// dishes take, how busy the kitchen is, how far the courier rides and whether
+// a courier is free to take it.
+package eta
+
+import (
+	"math"
+	"time"
+	"weservefood/geo"
+	"weservefood/models"
+)
+
+// Settings tune the estimates
+type Settings struct {
+	// PrepTime is used for menu items without a preparation time
+	PrepTime time.Duration
+	// KitchenSlots is how many orders a kitchen prepares at once
+	KitchenSlots int
+	// CourierSpeedKmh is the average speed of a courier along the road
+	CourierSpeedKmh int
+	// DetourPercent turns the straight-line distance into the road distance
+	DetourPercent int
+	// Handover covers picking the order up and handing it to the customer
+	Handover time.Duration
+	// CourierWait is how long it takes to free a courier when none is available
+	CourierWait time.Duration
+}
+
+// Default returns the settings used when nothing overrides them
+func Default() Settings {
+	return Settings{
+		PrepTime:        10 * time.Minute,
+		KitchenSlots:    2,
+		CourierSpeedKmh: 15,
+		DetourPercent:   130,
+		Handover:        3 * time.Minute,
+		CourierWait:     15 * time.Minute,
+	}
+}
+
+// Order is what is known about an order when it is estimated
+type Order struct {
+	Status models.OrderStatus
+	// StatusSince is when the order entered its status
+	StatusSince time.Time
+	// PrepTimes of the order's items; zero takes Settings.PrepTime
+	PrepTimes []time.Duration
+	// QueueAhead holds the preparation time left of each order ahead of this
+	// one in the kitchen
+	QueueAhead []time.Duration
+	// From and To are the restaurant and the delivery address, when located
+	From, To *models.LatLng
+	// Travel is the ride used when the distance cannot be measured
+	Travel time.Duration
+	// CourierReady is set when a courier is assigned or free to take the order
+	CourierReady bool
+}
+
+// Estimate breaks down how long is left until the order arrives, as seen at
+// now. Items are prepared side by side, so the slowest one sets the
+// preparation time, and the orders ahead share the kitchen's slots. Finding a
+// courier overlaps with the kitchen, so only the wait beyond it is added.
+// Stages already behind the order count as done, and the stage in progress
+// counts from when the order entered it.
+func (s Settings) Estimate(order Order, now time.Time) models.DeliveryEstimate {
+	var queue, prep, wait time.Duration
+	travel, distance := s.travel(order)
+	elapsed := max(now.Sub(order.StatusSince), 0)
+
+	switch order.Status {
+	case models.StatusPlaced, models.StatusConfirmed:
+		for _, ahead := range order.QueueAhead {
+			queue += ahead
+		}
+		queue /= time.Duration(max(s.KitchenSlots, 1))
+		prep = s.PrepTimeOf(order.PrepTimes)
+	case models.StatusPreparing:
+		prep = max(s.PrepTimeOf(order.PrepTimes)-elapsed, 0)
+	case models.StatusOutForDelivery:
+		travel = max(travel-elapsed, 0)
+	}
+	if !order.CourierReady && order.Status != models.StatusOutForDelivery {
+		wait = max(s.CourierWait-queue-prep, 0)
+	}
+
+	return models.DeliveryEstimate{
+		QueueMinutes:       minutes(queue),
+		PrepMinutes:        minutes(prep),
+		CourierWaitMinutes: minutes(wait),
+		TravelMinutes:      minutes(travel),
+		HandoverMinutes:    minutes(s.Handover),
+		DistanceMetres:     distance,
+		EstimatedAt:        now,
+	}
+}
+
+// PrepTimeOf returns how long the items of an order take to prepare: as long
+// as the slowest of them
+func (s Settings) PrepTimeOf(prepTimes []time.Duration) time.Duration {
+	var slowest time.Duration
+	for _, prepTime := range prepTimes {
+		if prepTime <= 0 {
+			prepTime = s.PrepTime
+		}
+		slowest = max(slowest, prepTime)
+	}
+	return slowest
+}
+
+// travel returns the ride to the customer and, when both ends are located,
+// its road distance in metres
+func (s Settings) travel(order Order) (time.Duration, int) {
+	if order.From == nil || order.To == nil || s.CourierSpeedKmh <= 0 {
+		return order.Travel, 0
+	}
+	metres := geo.Distance(*order.From, *order.To) * float64(s.DetourPercent) / 100
+	hours := metres / 1000 / float64(s.CourierSpeedKmh)
+	return time.Duration(hours * float64(time.Hour)), int(math.Round(metres))
+}
+
+// minutes rounds a duration up to whole minutes
+func minutes(d time.Duration) int {
+	return int((d + time.Minute - 1) / time.Minute)
+}
@@ -0,0 +1,84 @@
+package eta
+
+import (
+	"testing"
+	"time"
+	"weservefood/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var now = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func TestEstimateWaitingOrder(t *testing.T) {
+	estimate := Default().Estimate(Order{
+		Status:      models.StatusConfirmed,
+		StatusSince: now.Add(-time.Minute),
+		// the second item takes the default 10 minutes
+		PrepTimes:    []time.Duration{12 * time.Minute, 0},
+		QueueAhead:   []time.Duration{10 * time.Minute, 6 * time.Minute},
+		Travel:       20 * time.Minute,
+		CourierReady: false,
+	}, now)
+
+	assert.Equal(t, models.DeliveryEstimate{
+		QueueMinutes:    8,
+		PrepMinutes:     12,
+		TravelMinutes:   20,
+		HandoverMinutes: 3,
+		EstimatedAt:     now,
+	}, estimate, "a courier is found while the kitchen works")
+	assert.Equal(t, now.Add(43*time.Minute), estimate.DueAt())
+}
+
+func TestEstimateCourierWait(t *testing.T) {
+	order := Order{Status: models.StatusPlaced, PrepTimes: []time.Duration{5 * time.Minute}, Travel: 10 * time.Minute}
+
+	assert.Equal(t, 10, Default().Estimate(order, now).CourierWaitMinutes)
+	order.CourierReady = true
+	assert.Zero(t, Default().Estimate(order, now).CourierWaitMinutes)
+}
+
+func TestEstimateDistance(t *testing.T) {
+	mitte := models.LatLng{Lat: 52.5323, Lng: 13.3846}
+	neukoelln := models.LatLng{Lat: 52.4770, Lng: 13.4250}
+
+	estimate := Default().Estimate(Order{Status: models.StatusPlaced, From: &mitte, To: &neukoelln, Travel: time.Hour, CourierReady: true}, now)
+	// about 6.7 km as the crow flies, 8.7 km along the road at 15 km/h
+	assert.InDelta(t, 8700, estimate.DistanceMetres, 150)
+	assert.Equal(t, 35, estimate.TravelMinutes)
+
+	estimate = Default().Estimate(Order{Status: models.StatusPlaced, To: &neukoelln, Travel: 25 * time.Minute, CourierReady: true}, now)
+	assert.Zero(t, estimate.DistanceMetres)
+	assert.Equal(t, 25, estimate.TravelMinutes, "without the restaurant's location the zone's ride is used")
+}
+
+func TestEstimateAsOrderMovesOn(t *testing.T) {
+	order := Order{
+		PrepTimes:  []time.Duration{12 * time.Minute},
+		QueueAhead: []time.Duration{20 * time.Minute},
+		Travel:     20 * time.Minute,
+	}
+
+	order.Status, order.StatusSince = models.StatusPreparing, now.Add(-4*time.Minute)
+	estimate := Default().Estimate(order, now)
+	assert.Zero(t, estimate.QueueMinutes)
+	assert.Equal(t, 8, estimate.PrepMinutes)
+	assert.Equal(t, 7, estimate.CourierWaitMinutes)
+
+	order.Status, order.StatusSince = models.StatusOutForDelivery, now.Add(-5*time.Minute)
+	estimate = Default().Estimate(order, now)
+	assert.Zero(t, estimate.PrepMinutes)
+	assert.Zero(t, estimate.CourierWaitMinutes)
+	assert.Equal(t, 15, estimate.TravelMinutes)
+
+	order.StatusSince = now.Add(-time.Hour)
+	assert.Zero(t, Default().Estimate(order, now).TravelMinutes, "a late courier is due any moment")
+}
+
+func TestPrepTimeOf(t *testing.T) {
+	settings := Default()
+	assert.Zero(t, settings.PrepTimeOf(nil))
+	assert.Equal(t, 10*time.Minute, settings.PrepTimeOf([]time.Duration{0, 4 * time.Minute}))
+	assert.Equal(t, 25*time.Minute, settings.PrepTimeOf([]time.Duration{25 * time.Minute, 0}))
+}
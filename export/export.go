@@ -0,0 +1,300 @@
+// Package export writes orders as CSV, NDJSON or Parquet for reporting. Orders
+// are written as they are read, so an export never holds the whole store.
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"iter"
+	"strconv"
+	"strings"
+	"time"
+	"weservefood/models"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// Format is the encoding of an export
+type Format string
+
+const (
+	FormatCSV     Format = "csv"
+	FormatNDJSON  Format = "ndjson"
+	FormatParquet Format = "parquet"
+)
+
+// rowGroupSize is how many orders are buffered per Parquet row group
+const rowGroupSize = 1000
+
+// ParseFormat accepts a format name
+func ParseFormat(value string) (Format, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "csv":
+		return FormatCSV, nil
+	case "ndjson", "jsonl", "json":
+		return FormatNDJSON, nil
+	case "parquet":
+		return FormatParquet, nil
+	default:
+		return "", fmt.Errorf("unsupported export format %q", value)
+	}
+}
+
+// ContentType is the media type of an export in the format
+func (f Format) ContentType() string {
+	switch f {
+	case FormatCSV:
+		return "text/csv"
+	case FormatNDJSON:
+		return "application/x-ndjson"
+	default:
+		return "application/vnd.apache.parquet"
+	}
+}
+
+// Row is an order flattened for reporting. Items are joined with ";" and the
+// status timestamps are empty when the order never entered that status. The
+// payment columns are empty or zero for orders that were never paid, and the
+// cancellation columns for orders that were not cancelled.
+type Row struct {
+	ID                    string     `json:"id"`
+	Name                  string     `json:"name"`
+	Email                 string     `json:"email"`
+	Address               string     `json:"address"`
+	Items                 string     `json:"items"`
+	RestaurantID          string     `json:"restaurant_id"`
+	CourierID             string     `json:"courier_id"`
+	Status                string     `json:"status"`
+	SubtotalCents         int64      `json:"subtotal_cents"`
+	DeliveryFeeCents      int64      `json:"delivery_fee_cents"`
+	TotalCents            int64      `json:"total_cents"`
+	CreatedAt             time.Time  `json:"created_at"`
+	PlacedAt              *time.Time `json:"placed_at"`
+	ConfirmedAt           *time.Time `json:"confirmed_at"`
+	PreparingAt           *time.Time `json:"preparing_at"`
+	OutForDeliveryAt      *time.Time `json:"out_for_delivery_at"`
+	DeliveredAt           *time.Time `json:"delivered_at"`
+	CancelledAt           *time.Time `json:"cancelled_at"`
+	PaymentStatus         string     `json:"payment_status"`
+	CapturedCents         int64      `json:"captured_cents"`
+	RefundedCents         int64      `json:"refunded_cents"`
+	CancellationInitiator string     `json:"cancellation_initiator"`
+	CancellationReason    string     `json:"cancellation_reason"`
+}
+
+// parquetRow is the Parquet schema of a Row. Timestamps are Unix milliseconds;
+// a missing status timestamp is left at zero, which is written as null.
+type parquetRow struct {
+	ID                    string `parquet:"id"`
+	Name                  string `parquet:"name"`
+	Email                 string `parquet:"email"`
+	Address               string `parquet:"address"`
+	Items                 string `parquet:"items"`
+	RestaurantID          string `parquet:"restaurant_id"`
+	CourierID             string `parquet:"courier_id"`
+	Status                string `parquet:"status"`
+	SubtotalCents         int64  `parquet:"subtotal_cents"`
+	DeliveryFeeCents      int64  `parquet:"delivery_fee_cents"`
+	TotalCents            int64  `parquet:"total_cents"`
+	CreatedAt             int64  `parquet:"created_at,timestamp(millisecond)"`
+	PlacedAt              int64  `parquet:"placed_at,optional,timestamp(millisecond)"`
+	ConfirmedAt           int64  `parquet:"confirmed_at,optional,timestamp(millisecond)"`
+	PreparingAt           int64  `parquet:"preparing_at,optional,timestamp(millisecond)"`
+	OutForDeliveryAt      int64  `parquet:"out_for_delivery_at,optional,timestamp(millisecond)"`
+	DeliveredAt           int64  `parquet:"delivered_at,optional,timestamp(millisecond)"`
+	CancelledAt           int64  `parquet:"cancelled_at,optional,timestamp(millisecond)"`
+	PaymentStatus         string `parquet:"payment_status"`
+	CapturedCents         int64  `parquet:"captured_cents"`
+	RefundedCents         int64  `parquet:"refunded_cents"`
+	CancellationInitiator string `parquet:"cancellation_initiator"`
+	CancellationReason    string `parquet:"cancellation_reason"`
+}
+
+func (r Row) parquet() parquetRow {
+	return parquetRow{
+		ID:                    r.ID,
+		Name:                  r.Name,
+		Email:                 r.Email,
+		Address:               r.Address,
+		Items:                 r.Items,
+		RestaurantID:          r.RestaurantID,
+		CourierID:             r.CourierID,
+		Status:                r.Status,
+		SubtotalCents:         r.SubtotalCents,
+		DeliveryFeeCents:      r.DeliveryFeeCents,
+		TotalCents:            r.TotalCents,
+		CreatedAt:             r.CreatedAt.UnixMilli(),
+		PlacedAt:              unixMilli(r.PlacedAt),
+		ConfirmedAt:           unixMilli(r.ConfirmedAt),
+		PreparingAt:           unixMilli(r.PreparingAt),
+		OutForDeliveryAt:      unixMilli(r.OutForDeliveryAt),
+		DeliveredAt:           unixMilli(r.DeliveredAt),
+		CancelledAt:           unixMilli(r.CancelledAt),
+		PaymentStatus:         r.PaymentStatus,
+		CapturedCents:         r.CapturedCents,
+		RefundedCents:         r.RefundedCents,
+		CancellationInitiator: r.CancellationInitiator,
+		CancellationReason:    r.CancellationReason,
+	}
+}
+
+// unixMilli is zero, written as null, for a status the order never entered
+func unixMilli(at *time.Time) int64 {
+	if at == nil {
+		return 0
+	}
+	return at.UnixMilli()
+}
+
+// columns is the CSV header, in Row field order
+var columns = []string{
+	"id", "name", "email", "address", "items", "restaurant_id", "courier_id", "status",
+	"subtotal_cents", "delivery_fee_cents", "total_cents", "created_at", "placed_at", "confirmed_at",
+	"preparing_at", "out_for_delivery_at", "delivered_at", "cancelled_at", "payment_status",
+	"captured_cents", "refunded_cents", "cancellation_initiator", "cancellation_reason",
+}
+
+// NewRow flattens an order
+func NewRow(order models.Order) Row {
+	row := Row{
+		ID:               order.ID,
+		Name:             order.Name,
+		Email:            order.Email,
+		Address:          order.Address,
+		Items:            strings.Join(order.Items, ";"),
+		RestaurantID:     order.RestaurantID,
+		CourierID:        order.CourierID,
+		Status:           string(order.Status),
+		SubtotalCents:    int64(order.Price.SubtotalCents),
+		DeliveryFeeCents: int64(order.Price.DeliveryFeeCents),
+		TotalCents:       int64(order.Price.TotalCents),
+		CreatedAt:        order.CreatedAt,
+		PlacedAt:         statusTime(order, models.StatusPlaced),
+		ConfirmedAt:      statusTime(order, models.StatusConfirmed),
+		PreparingAt:      statusTime(order, models.StatusPreparing),
+		OutForDeliveryAt: statusTime(order, models.StatusOutForDelivery),
+		DeliveredAt:      statusTime(order, models.StatusDelivered),
+		CancelledAt:      statusTime(order, models.StatusCancelled),
+	}
+	if order.Payment != nil {
+		row.PaymentStatus = string(order.Payment.Status)
+		row.CapturedCents = int64(order.Payment.CapturedCents)
+		row.RefundedCents = int64(order.Payment.RefundedCents)
+	}
+	if order.Cancellation != nil {
+		row.CancellationInitiator = string(order.Cancellation.Initiator)
+		row.CancellationReason = order.Cancellation.Reason
+	}
+	return row
+}
+
+func statusTime(order models.Order, status models.OrderStatus) *time.Time {
+	at, ok := order.StatusAt(status)
+	if !ok {
+		return nil
+	}
+	return &at
+}
+
+// Write encodes the orders to w in the format and returns how many were written
+func Write(w io.Writer, format Format, orders iter.Seq[models.Order]) (int, error) {
+	switch format {
+	case FormatCSV:
+		return writeCSV(w, orders)
+	case FormatNDJSON:
+		return writeNDJSON(w, orders)
+	case FormatParquet:
+		return writeParquet(w, orders)
+	default:
+		return 0, fmt.Errorf("unsupported export format %q", format)
+	}
+}
+
+func writeCSV(w io.Writer, orders iter.Seq[models.Order]) (int, error) {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(columns); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for order := range orders {
+		row := NewRow(order)
+		record := []string{
+			row.ID, row.Name, row.Email, row.Address, row.Items, row.RestaurantID, row.CourierID, row.Status,
+			strconv.FormatInt(row.SubtotalCents, 10),
+			strconv.FormatInt(row.DeliveryFeeCents, 10),
+			strconv.FormatInt(row.TotalCents, 10),
+			formatTime(&row.CreatedAt),
+			formatTime(row.PlacedAt),
+			formatTime(row.ConfirmedAt),
+			formatTime(row.PreparingAt),
+			formatTime(row.OutForDeliveryAt),
+			formatTime(row.DeliveredAt),
+			formatTime(row.CancelledAt),
+			row.PaymentStatus,
+			strconv.FormatInt(row.CapturedCents, 10),
+			strconv.FormatInt(row.RefundedCents, 10),
+			row.CancellationInitiator,
+			row.CancellationReason,
+		}
+		if err := writer.Write(record); err != nil {
+			return count, err
+		}
+		count++
+	}
+
+	writer.Flush()
+	return count, writer.Error()
+}
+
+func formatTime(at *time.Time) string {
+	if at == nil || at.IsZero() {
+		return ""
+	}
+	return at.UTC().Format(time.RFC3339Nano)
+}
+
+func writeNDJSON(w io.Writer, orders iter.Seq[models.Order]) (int, error) {
+	encoder := json.NewEncoder(w)
+
+	count := 0
+	for order := range orders {
+		if err := encoder.Encode(NewRow(order)); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+func writeParquet(w io.Writer, orders iter.Seq[models.Order]) (int, error) {
+	writer := parquet.NewGenericWriter[parquetRow](w)
+
+	count := 0
+	batch := make([]parquetRow, 0, rowGroupSize)
+	flush := func() error {
+		if _, err := writer.Write(batch); err != nil {
+			return err
+		}
+		batch = batch[:0]
+		return writer.Flush()
+	}
+
+	for order := range orders {
+		batch = append(batch, NewRow(order).parquet())
+		count++
+		if len(batch) == rowGroupSize {
+			if err := flush(); err != nil {
+				return count, err
+			}
+		}
+	}
+	if len(batch) > 0 {
+		if err := flush(); err != nil {
+			return count, err
+		}
+	}
+	return count, writer.Close()
+}
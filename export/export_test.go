@@ -0,0 +1,141 @@
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+	"weservefood/models"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testOrders() []models.Order {
+	placedAt := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	placed := models.Order{
+		ID:           "o-1",
+		Email:        "finance@example.com",
+		Address:      "1 Main St",
+		Items:        []string{"Soup", "Bread"},
+		RestaurantID: "r-1",
+		Price:        models.PriceBreakdown{SubtotalCents: 650, DeliveryFeeCents: 299, TotalCents: 949},
+		CreatedAt:    placedAt,
+	}
+	placed.SetStatus(models.StatusPlaced, placedAt)
+
+	cancelled := placed
+	cancelled.ID = "o-2"
+	cancelled.SetStatus(models.StatusCancelled, placedAt.Add(time.Hour))
+
+	delivered := placed
+	delivered.ID = "o-3"
+	delivered.CourierID = "c-1"
+	delivered.SetStatus(models.StatusConfirmed, placedAt.Add(time.Minute))
+	delivered.SetStatus(models.StatusPreparing, placedAt.Add(2*time.Minute))
+	delivered.SetStatus(models.StatusOutForDelivery, placedAt.Add(20*time.Minute))
+	delivered.SetStatus(models.StatusDelivered, placedAt.Add(35*time.Minute))
+	delivered.Payment = &models.PaymentIntent{Status: models.PaymentCaptured, AmountCents: 949, CapturedCents: 949}
+
+	refunded := placed
+	refunded.ID = "o-4"
+	refunded.SetStatus(models.StatusConfirmed, placedAt.Add(time.Minute))
+	refunded.SetStatus(models.StatusPreparing, placedAt.Add(2*time.Minute))
+	refunded.SetStatus(models.StatusCancelled, placedAt.Add(5*time.Minute))
+	refunded.Payment = &models.PaymentIntent{Status: models.PaymentCaptured, AmountCents: 949, CapturedCents: 949, RefundedCents: 474}
+	refunded.Cancellation = &models.Cancellation{Initiator: models.InitiatorRestaurant, Reason: "out of stock", At: placedAt.Add(5 * time.Minute)}
+
+	return []models.Order{placed, cancelled, delivered, refunded}
+}
+
+func TestParseFormat(t *testing.T) {
+	format, err := ParseFormat("Parquet")
+	assert.NoError(t, err)
+	assert.Equal(t, FormatParquet, format)
+
+	format, err = ParseFormat("jsonl")
+	assert.NoError(t, err)
+	assert.Equal(t, FormatNDJSON, format)
+
+	_, err = ParseFormat("xlsx")
+	assert.Error(t, err)
+}
+
+func TestWriteCSV(t *testing.T) {
+	var buf bytes.Buffer
+	count, err := Write(&buf, FormatCSV, slices.Values(testOrders()))
+	require.NoError(t, err)
+	assert.Equal(t, 4, count)
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 5)
+	assert.Equal(t, columns, records[0])
+	assert.Equal(t, []string{"o-1", "", "finance@example.com", "1 Main St", "Soup;Bread", "r-1", "", "placed", "650", "299", "949",
+		"2026-03-01T12:00:00Z", "2026-03-01T12:00:00Z", "", "", "", "", "", "", "0", "0", "", ""}, records[1])
+	assert.Equal(t, "cancelled", records[2][7])
+	assert.Equal(t, "2026-03-01T13:00:00Z", records[2][17])
+	assert.Equal(t, []string{"2026-03-01T12:01:00Z", "2026-03-01T12:02:00Z", "2026-03-01T12:20:00Z", "2026-03-01T12:35:00Z", "",
+		"captured", "949", "0", "", ""}, records[3][13:])
+	assert.Equal(t, []string{"2026-03-01T12:05:00Z", "captured", "949", "474", "restaurant", "out of stock"}, records[4][17:])
+}
+
+func TestWriteNDJSON(t *testing.T) {
+	var buf bytes.Buffer
+	count, err := Write(&buf, FormatNDJSON, slices.Values(testOrders()))
+	require.NoError(t, err)
+	assert.Equal(t, 4, count)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 4)
+
+	var row Row
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &row))
+	assert.Equal(t, int64(949), row.TotalCents)
+	assert.Nil(t, row.CancelledAt)
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &row))
+	require.NotNil(t, row.CancelledAt)
+	row = Row{}
+	require.NoError(t, json.Unmarshal([]byte(lines[3]), &row))
+	assert.Equal(t, int64(474), row.RefundedCents)
+	assert.Equal(t, "restaurant", row.CancellationInitiator)
+	assert.Nil(t, row.DeliveredAt)
+}
+
+func TestWriteParquet(t *testing.T) {
+	var buf bytes.Buffer
+	count, err := Write(&buf, FormatParquet, slices.Values(testOrders()))
+	require.NoError(t, err)
+	assert.Equal(t, 4, count)
+
+	rows, err := parquet.Read[parquetRow](bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	require.Len(t, rows, 4)
+	assert.Equal(t, "Soup;Bread", rows[0].Items)
+	assert.Equal(t, int64(650), rows[0].SubtotalCents)
+	assert.Equal(t, time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC).UnixMilli(), rows[0].CreatedAt)
+	assert.Zero(t, rows[0].CancelledAt)
+	assert.Equal(t, time.Date(2026, 3, 1, 13, 0, 0, 0, time.UTC).UnixMilli(), rows[1].CancelledAt)
+	assert.Equal(t, time.Date(2026, 3, 1, 12, 35, 0, 0, time.UTC).UnixMilli(), rows[2].DeliveredAt)
+	assert.Equal(t, int64(949), rows[2].CapturedCents)
+	assert.Equal(t, int64(474), rows[3].RefundedCents)
+	assert.Equal(t, "out of stock", rows[3].CancellationReason)
+}
+
+func TestColumnPerStatus(t *testing.T) {
+	for _, status := range models.OrderStatuses {
+		assert.Contains(t, columns, string(status)+"_at")
+	}
+}
+
+func TestWriteEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	count, err := Write(&buf, FormatCSV, slices.Values([]models.Order(nil)))
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+	assert.Equal(t, strings.Join(columns, ",")+"\n", buf.String())
+}
@@ -0,0 +1,117 @@
+package geo
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"weservefood/models"
+)
+
+// bundledDataset is the postal code dataset built into the binary
+//
+//go:embed gazetteer.csv
+var bundledDataset []byte
+
+// Place is a postal code and the coordinates of its centre
+type Place struct {
+	Country    string
+	PostalCode string
+	City       string
+	Location   models.LatLng
+}
+
+// Gazetteer is an offline geocoder that places an address at the centre of
+// its postal code
+type Gazetteer struct {
+	places map[string]Place
+}
+
+// NewGazetteer returns a gazetteer that knows the given places
+func NewGazetteer(places ...Place) *Gazetteer {
+	g := &Gazetteer{places: make(map[string]Place, len(places))}
+	for _, place := range places {
+		g.places[placeKey(place.Country, place.PostalCode)] = place
+	}
+	return g
+}
+
+// LoadGazetteer reads a CSV dataset with a header row and the columns
+// country, postal_code, city, lat and lng
+func LoadGazetteer(r io.Reader) (*Gazetteer, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 5
+	reader.Comment = '#'
+	if _, err := reader.Read(); err != nil {
+		return nil, fmt.Errorf("gazetteer header: %w", err)
+	}
+
+	var places []Place
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		lat, latErr := strconv.ParseFloat(record[3], 64)
+		lng, lngErr := strconv.ParseFloat(record[4], 64)
+		location := models.LatLng{Lat: lat, Lng: lng}
+		if latErr != nil || lngErr != nil || !location.Valid() {
+			return nil, fmt.Errorf("gazetteer line %d: invalid coordinates %s,%s", line, record[3], record[4])
+		}
+		places = append(places, Place{Country: record[0], PostalCode: record[1], City: record[2], Location: location})
+	}
+	return NewGazetteer(places...), nil
+}
+
+// Bundled returns the gazetteer of the dataset built into the binary. The
+// dataset is checked by the tests, so it panics only on a broken build.
+var Bundled = sync.OnceValue(func() *Gazetteer {
+	gazetteer, err := LoadGazetteer(bytes.NewReader(bundledDataset))
+	if err != nil {
+		panic(fmt.Sprintf("bundled gazetteer: %v", err))
+	}
+	return gazetteer
+})
+
+// Len returns how many postal codes the gazetteer knows
+func (g *Gazetteer) Len() int {
+	return len(g.places)
+}
+
+// Lookup finds the place of a postal code. Full codes fall back to their
+// first part, so "SW1A 1AA" is found as "SW1A".
+func (g *Gazetteer) Lookup(country, postalCode string) (Place, bool) {
+	if place, ok := g.places[placeKey(country, postalCode)]; ok {
+		return place, true
+	}
+	if fields := strings.Fields(postalCode); len(fields) > 1 {
+		place, ok := g.places[placeKey(country, fields[0])]
+		return place, ok
+	}
+	return Place{}, false
+}
+
+// Geocode places the address at the centre of its postal code, and returns
+// ErrNotFound for postal codes the gazetteer does not know
+func (g *Gazetteer) Geocode(ctx context.Context, address models.Address) (models.LatLng, error) {
+	place, ok := g.Lookup(address.Country, address.PostalCode)
+	if !ok {
+		return models.LatLng{}, fmt.Errorf("%w: unknown postal code %q in %q", ErrNotFound, address.PostalCode, address.Country)
+	}
+	return place.Location, nil
+}
+
+// placeKey ignores case and spacing, so "sw1a" and "SW1A" are the same code
+func placeKey(country, postalCode string) string {
+	return strings.ToUpper(strings.TrimSpace(country)) + "/" + strings.ToUpper(strings.Join(strings.Fields(postalCode), ""))
+}
@@ -0,0 +1,35 @@
+// Package geo turns delivery addresses into coordinates and measures the
+// distances between them. Geocoders only need to know postal codes: a Gazetteer
+// answers from a bundled dataset, without calling an external service.
+package geo
+
+import (
+	"context"
+	"errors"
+	"math"
+	"weservefood/models"
+)
+
+// ErrNotFound is returned when a geocoder cannot place an address
+var ErrNotFound = errors.New("address not found")
+
+// Geocoder finds where an address is
+type Geocoder interface {
+	Geocode(ctx context.Context, address models.Address) (models.LatLng, error)
+}
+
+// earthRadiusMetres is the mean radius of the earth
+const earthRadiusMetres = 6371000
+
+// Distance returns the great-circle distance between two points in metres
+func Distance(from, to models.LatLng) float64 {
+	lat1, lat2 := radians(from.Lat), radians(to.Lat)
+	dLat, dLng := lat2-lat1, radians(to.Lng-from.Lng)
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusMetres * math.Asin(math.Sqrt(min(h, 1)))
+}
+
+func radians(degrees float64) float64 {
+	return degrees * math.Pi / 180
+}
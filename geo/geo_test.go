@@ -0,0 +1,97 @@
+package geo
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"weservefood/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDistance(t *testing.T) {
+	mitte := models.LatLng{Lat: 52.5323, Lng: 13.3846}
+	neukoelln := models.LatLng{Lat: 52.4770, Lng: 13.4250}
+
+	assert.Zero(t, Distance(mitte, mitte))
+	assert.InDelta(t, 6700, Distance(mitte, neukoelln), 100)
+	assert.Equal(t, Distance(mitte, neukoelln), Distance(neukoelln, mitte))
+	// half way round the equator
+	assert.InDelta(t, 20015000, Distance(models.LatLng{}, models.LatLng{Lng: 180}), 1000)
+}
+
+func TestLoadGazetteer(t *testing.T) {
+	gazetteer, err := LoadGazetteer(strings.NewReader("# comment\ncountry,postal_code,city,lat,lng\nDE,10115,Berlin,52.5323,13.3846\nGB,SW1A,London,51.5010,-0.1416\n"))
+	require.NoError(t, err)
+	assert.Equal(t, 2, gazetteer.Len())
+
+	location, err := gazetteer.Geocode(context.Background(), models.Address{Country: "de", PostalCode: " 10115 "})
+	require.NoError(t, err)
+	assert.Equal(t, models.LatLng{Lat: 52.5323, Lng: 13.3846}, location)
+
+	// full codes fall back to their first part
+	place, ok := gazetteer.Lookup("GB", "sw1a 1aa")
+	require.True(t, ok)
+	assert.Equal(t, "London", place.City)
+
+	_, err = gazetteer.Geocode(context.Background(), models.Address{Country: "US", PostalCode: "10115"})
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	_, err = LoadGazetteer(strings.NewReader("country,postal_code,city,lat,lng\nDE,10115,Berlin,152.5,13.3\n"))
+	assert.ErrorContains(t, err, "line 2")
+	_, err = LoadGazetteer(strings.NewReader("country,postal_code,city,lat,lng\nDE,10115,Berlin\n"))
+	assert.Error(t, err)
+}
+
+func TestBundledGazetteer(t *testing.T) {
+	_, err := LoadGazetteer(bytes.NewReader(bundledDataset))
+	require.NoError(t, err)
+
+	gazetteer := Bundled()
+	assert.Positive(t, gazetteer.Len())
+	_, ok := gazetteer.Lookup("DE", "10115")
+	assert.True(t, ok)
+	assert.Same(t, gazetteer, Bundled())
+}
+
+// square is a GeoJSON polygon around Berlin Mitte with a hole in its middle
+var square = models.Polygon{
+	Type: "Polygon",
+	Coordinates: [][][]float64{
+		{{13.36, 52.51}, {13.42, 52.51}, {13.42, 52.55}, {13.36, 52.55}, {13.36, 52.51}},
+		{{13.385, 52.525}, {13.395, 52.525}, {13.395, 52.535}, {13.385, 52.535}, {13.385, 52.525}},
+	},
+}
+
+func TestContains(t *testing.T) {
+	assert.True(t, Contains(square, models.LatLng{Lat: 52.52, Lng: 13.37}))
+	assert.False(t, Contains(square, models.LatLng{Lat: 52.53, Lng: 13.39}), "inside the hole")
+	assert.False(t, Contains(square, models.LatLng{Lat: 52.4770, Lng: 13.4250}))
+	assert.False(t, Contains(square, models.LatLng{Lat: 52.52, Lng: 13.43}))
+	assert.False(t, Contains(models.Polygon{Type: "Polygon"}, models.LatLng{Lat: 52.52, Lng: 13.37}))
+
+	// a concave L shape
+	l := models.Polygon{Type: "Polygon", Coordinates: [][][]float64{{{0, 0}, {2, 0}, {2, 1}, {1, 1}, {1, 2}, {0, 2}, {0, 0}}}}
+	assert.True(t, Contains(l, models.LatLng{Lat: 1.5, Lng: 0.5}))
+	assert.False(t, Contains(l, models.LatLng{Lat: 1.5, Lng: 1.5}))
+}
+
+func TestValidatePolygon(t *testing.T) {
+	assert.NoError(t, ValidatePolygon(square))
+
+	invalid := map[string]models.Polygon{
+		"type must be Polygon": {Type: "MultiPolygon", Coordinates: square.Coordinates},
+		"no rings":             {Type: "Polygon"},
+		"at least 4 positions": {Type: "Polygon", Coordinates: [][][]float64{{{0, 0}, {1, 0}, {0, 0}}}},
+		"is not closed":        {Type: "Polygon", Coordinates: [][][]float64{{{0, 0}, {1, 0}, {1, 1}, {0, 1}}}},
+		"invalid position [0]": {Type: "Polygon", Coordinates: [][][]float64{{{0, 0}, {1, 0}, {0}, {0, 0}}}},
+		"position [200 0]":     {Type: "Polygon", Coordinates: [][][]float64{{{0, 0}, {200, 0}, {1, 1}, {0, 0}}}},
+	}
+	for message, polygon := range invalid {
+		err := ValidatePolygon(polygon)
+		assert.ErrorIs(t, err, ErrInvalidPolygon, message)
+		assert.ErrorContains(t, err, message)
+	}
+}
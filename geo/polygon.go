@@ -0,0 +1,65 @@
+package geo
+
+import (
+	"errors"
+	"fmt"
+	"weservefood/models"
+)
+
+// ErrInvalidPolygon is returned for a polygon that is not valid GeoJSON
+var ErrInvalidPolygon = errors.New("invalid polygon")
+
+// ValidatePolygon checks that a polygon is a GeoJSON Polygon whose rings are
+// closed, have at least three corners and stay within the map
+func ValidatePolygon(polygon models.Polygon) error {
+	if polygon.Type != "Polygon" {
+		return fmt.Errorf("%w: type must be Polygon, not %q", ErrInvalidPolygon, polygon.Type)
+	}
+	if len(polygon.Coordinates) == 0 {
+		return fmt.Errorf("%w: no rings", ErrInvalidPolygon)
+	}
+	for i, ring := range polygon.Coordinates {
+		if len(ring) < 4 {
+			return fmt.Errorf("%w: ring %d needs at least 4 positions", ErrInvalidPolygon, i)
+		}
+		for _, position := range ring {
+			if len(position) < 2 || !(models.LatLng{Lat: position[1], Lng: position[0]}).Valid() {
+				return fmt.Errorf("%w: ring %d has the invalid position %v", ErrInvalidPolygon, i, position)
+			}
+		}
+		first, last := ring[0], ring[len(ring)-1]
+		if first[0] != last[0] || first[1] != last[1] {
+			return fmt.Errorf("%w: ring %d is not closed", ErrInvalidPolygon, i)
+		}
+	}
+	return nil
+}
+
+// Contains reports whether a point lies inside a polygon: within its outer
+// ring and outside every hole. Zones are small enough to treat degrees as a
+// flat grid.
+func Contains(polygon models.Polygon, point models.LatLng) bool {
+	if len(polygon.Coordinates) == 0 || !insideRing(polygon.Coordinates[0], point) {
+		return false
+	}
+	for _, hole := range polygon.Coordinates[1:] {
+		if insideRing(hole, point) {
+			return false
+		}
+	}
+	return true
+}
+
+// insideRing casts a ray east from the point and counts the edges it crosses
+func insideRing(ring [][]float64, point models.LatLng) bool {
+	inside := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		lngI, latI := ring[i][0], ring[i][1]
+		lngJ, latJ := ring[j][0], ring[j][1]
+		if (latI > point.Lat) != (latJ > point.Lat) &&
+			point.Lng < (lngJ-lngI)*(point.Lat-latI)/(latJ-latI)+lngI {
+			inside = !inside
+		}
+	}
+	return inside
+}
@@ -0,0 +1,128 @@
+package graphqlapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+const ContentTypeHeader string = "Content-Type"
+const ApplicationJson string = "application/json"
+const EventStream string = "text/event-stream"
+
+// Request is a GraphQL request as sent over HTTP
+type Request struct {
+	Query         string                 `json:"query"`
+	Variables     map[string]interface{} `json:"variables"`
+	OperationName string                 `json:"operationName"`
+}
+
+// @Summary GraphQL endpoint
+// @Description Execute GraphQL queries and mutations over orders, menus and couriers.
+// @Description Subscriptions are streamed as server-sent events.
+// @Tags graphql
+// @Accept json
+// @Produce json
+// @Produce text/event-stream
+// @Param request body Request true "GraphQL request"
+// @Success 200 {object} map[string]interface{} "GraphQL result with data and errors"
+// @Failure 400 {string} string "Invalid Request Payload"
+// @Failure 413 {string} string "request body too large"
+// @Router /graphql [post]
+func Handler(rw http.ResponseWriter, req *http.Request) {
+	var request Request
+
+	if req.Method == http.MethodGet {
+		request.Query = req.URL.Query().Get("query")
+		request.OperationName = req.URL.Query().Get("operationName")
+		if variables := req.URL.Query().Get("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &request.Variables); err != nil {
+				http.Error(rw, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+	} else if err := json.NewDecoder(req.Body).Decode(&request); err != nil {
+		status := http.StatusBadRequest
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		http.Error(rw, err.Error(), status)
+		return
+	}
+
+	params := graphql.Params{
+		Schema:         Schema,
+		RequestString:  request.Query,
+		VariableValues: request.Variables,
+		OperationName:  request.OperationName,
+		Context:        withLoaders(req.Context(), newLoaders()),
+	}
+
+	if isSubscription(request.Query, request.OperationName) {
+		serveSubscription(rw, req, params)
+		return
+	}
+
+	rw.Header().Set(ContentTypeHeader, ApplicationJson)
+	if err := json.NewEncoder(rw).Encode(graphql.Do(params)); err != nil {
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// serveSubscription streams each subscription result as a server-sent "next"
+// event, followed by "complete" once the subscription ends
+func serveSubscription(rw http.ResponseWriter, req *http.Request, params graphql.Params) {
+	flusher, ok := rw.(http.Flusher)
+	if !ok || !strings.Contains(req.Header.Get("Accept"), EventStream) {
+		http.Error(rw, "subscriptions require Accept: "+EventStream, http.StatusNotAcceptable)
+		return
+	}
+
+	// subscriptions stay open far longer than the server's write timeout
+	http.NewResponseController(rw).SetWriteDeadline(time.Time{})
+	rw.Header().Set(ContentTypeHeader, EventStream)
+	rw.Header().Set("Cache-Control", "no-cache")
+	rw.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for result := range graphql.Subscribe(params) {
+		payload, err := json.Marshal(result)
+		if err != nil {
+			return
+		}
+		fmt.Fprintf(rw, "event: next\ndata: %s\n\n", payload)
+		flusher.Flush()
+	}
+
+	fmt.Fprint(rw, "event: complete\ndata:\n\n")
+	flusher.Flush()
+}
+
+// isSubscription reports whether the selected operation of the document is a subscription
+func isSubscription(query, operationName string) bool {
+	document, err := parser.Parse(parser.ParseParams{Source: query})
+	if err != nil {
+		return false
+	}
+
+	for _, definition := range document.Definitions {
+		operation, ok := definition.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		if operationName == "" || (operation.Name != nil && operation.Name.Value == operationName) {
+			return operation.Operation == ast.OperationTypeSubscription
+		}
+	}
+
+	return false
+}
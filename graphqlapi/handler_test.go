@@ -0,0 +1,105 @@
+package graphqlapi
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"weservefood/models"
+	"weservefood/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandlerQuery(t *testing.T) {
+	order, err := repository.CreateOrder(context.Background(), models.Order{Email: "http-gql@example.com", Address: "123 Test St"})
+	require.NoError(t, err)
+
+	body, _ := json.Marshal(Request{Query: `query($id: ID!) { order(id: $id) { email } }`, Variables: map[string]interface{}{"id": order.ID}})
+	req, err := http.NewRequest("POST", "/graphql", bytes.NewBuffer(body))
+	require.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(Handler).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"data":{"order":{"email":"http-gql@example.com"}}}`, rr.Body.String())
+}
+
+func TestHandlerGetQuery(t *testing.T) {
+	req, err := http.NewRequest("GET", `/graphql?query={couriers{id}}`, nil)
+	require.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(Handler).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"couriers"`)
+}
+
+func TestHandlerInvalidBody(t *testing.T) {
+	req, err := http.NewRequest("POST", "/graphql", strings.NewReader("not json"))
+	require.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(Handler).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestHandlerSubscription(t *testing.T) {
+	order, err := repository.CreateOrder(context.Background(), models.Order{Email: "sub@example.com", Address: "123 Test St"})
+	require.NoError(t, err)
+
+	server := httptest.NewServer(http.HandlerFunc(Handler))
+	defer server.Close()
+
+	body, _ := json.Marshal(Request{Query: `subscription($id: ID!) { orderStatus(id: $id) { status } }`, Variables: map[string]interface{}{"id": order.ID}})
+	req, err := http.NewRequest("POST", server.URL, bytes.NewBuffer(body))
+	require.NoError(t, err)
+	req.Header.Set("Accept", EventStream)
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, EventStream, resp.Header.Get(ContentTypeHeader))
+
+	events := bufio.NewScanner(resp.Body)
+	nextData := func() string {
+		for events.Scan() {
+			if line := events.Text(); strings.HasPrefix(line, "data: ") {
+				return strings.TrimPrefix(line, "data: ")
+			}
+		}
+		return ""
+	}
+
+	assert.JSONEq(t, `{"data":{"orderStatus":{"status":"placed"}}}`, nextData())
+
+	_, err = repository.CancelOrder(context.Background(), order.ID, models.OrderCancellation{Email: "sub@example.com", Reason: "changed my mind"})
+	require.NoError(t, err)
+
+	assert.JSONEq(t, `{"data":{"orderStatus":{"status":"cancelled"}}}`, nextData())
+	for events.Scan() {
+		if events.Text() == "event: complete" {
+			return
+		}
+	}
+	t.Fatal("subscription did not complete")
+}
+
+func TestHandlerSubscriptionRequiresEventStream(t *testing.T) {
+	body, _ := json.Marshal(Request{Query: `subscription { orderStatus(id: "x") { status } }`})
+	req, err := http.NewRequest("POST", "/graphql", bytes.NewBuffer(body))
+	require.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(Handler).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNotAcceptable, rr.Code)
+}
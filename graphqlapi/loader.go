@@ -0,0 +1,84 @@
+package graphqlapi
+
+import (
+	"context"
+	"sync"
+	"weservefood/models"
+	"weservefood/repository"
+)
+
+// loader batches the lookups made while resolving one GraphQL request. Each call
+// to load queues a key and returns a thunk; graphql-go resolves thunks only once
+// the current level of the query is complete, so the first thunk fetches every
+// queued key in a single call.
+type loader[T any] struct {
+	mu      sync.Mutex
+	fetch   func(ids []string) map[string]T
+	pending []string
+	results map[string]T
+	loaded  map[string]bool
+}
+
+func newLoader[T any](fetch func(ids []string) map[string]T) *loader[T] {
+	return &loader[T]{
+		fetch:   fetch,
+		results: make(map[string]T),
+		loaded:  make(map[string]bool),
+	}
+}
+
+// load queues an ID and returns a thunk yielding its value, or nil if it does not exist
+func (l *loader[T]) load(id string) func() (interface{}, error) {
+	l.mu.Lock()
+	if !l.loaded[id] {
+		l.loaded[id] = true
+		l.pending = append(l.pending, id)
+	}
+	l.mu.Unlock()
+
+	return func() (interface{}, error) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		if len(l.pending) > 0 {
+			for id, value := range l.fetch(l.pending) {
+				l.results[id] = value
+			}
+			l.pending = nil
+		}
+
+		value, exist := l.results[id]
+		if !exist {
+			return nil, nil
+		}
+		return value, nil
+	}
+}
+
+// loaders holds the per-request loaders used by the resolvers
+type loaders struct {
+	restaurants *loader[models.Restaurant]
+	couriers    *loader[models.Courier]
+}
+
+type loadersKey struct{}
+
+func newLoaders() *loaders {
+	return &loaders{
+		restaurants: newLoader(repository.GetRestaurantsByIDs),
+		couriers:    newLoader(repository.GetCouriersByIDs),
+	}
+}
+
+// withLoaders attaches a fresh set of loaders to the request context
+func withLoaders(ctx context.Context, l *loaders) context.Context {
+	return context.WithValue(ctx, loadersKey{}, l)
+}
+
+// loadersFrom returns the loaders of the request, or fresh ones outside of a request
+func loadersFrom(ctx context.Context) *loaders {
+	if l, ok := ctx.Value(loadersKey{}).(*loaders); ok {
+		return l
+	}
+	return newLoaders()
+}
@@ -0,0 +1,373 @@
+package graphqlapi
+
+import (
+	"errors"
+	"weservefood/middleware"
+	"weservefood/models"
+	"weservefood/repository"
+
+	"github.com/graphql-go/graphql"
+)
+
+// errAdminOnly is returned by the fields over every customer's orders and by
+// the mutations moving orders through fulfilment
+var errAdminOnly = errors.New("an admin token is required")
+
+var menuItemType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "MenuItem",
+	Fields: graphql.Fields{
+		"name": &graphql.Field{Type: graphql.String},
+		"priceCents": &graphql.Field{
+			Type: graphql.Int,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(models.MenuItem).PriceCents, nil
+			},
+		},
+	},
+})
+
+var restaurantType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Restaurant",
+	Fields: graphql.Fields{
+		"id":      &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+		"name":    &graphql.Field{Type: graphql.String},
+		"address": &graphql.Field{Type: graphql.String},
+		"menu":    &graphql.Field{Type: graphql.NewList(menuItemType)},
+	},
+})
+
+var courierType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Courier",
+	Fields: graphql.Fields{
+		"id":        &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+		"name":      &graphql.Field{Type: graphql.String},
+		"phone":     &graphql.Field{Type: graphql.String},
+		"available": &graphql.Field{Type: graphql.Boolean},
+	},
+})
+
+var positionType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "CourierPosition",
+	Fields: graphql.Fields{
+		"lat": &graphql.Field{
+			Type: graphql.Float,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(*models.LocationPing).Location.Lat, nil
+			},
+		},
+		"lng": &graphql.Field{
+			Type: graphql.Float,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(*models.LocationPing).Location.Lng, nil
+			},
+		},
+		"recordedAt": &graphql.Field{
+			Type: graphql.DateTime,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(*models.LocationPing).RecordedAt, nil
+			},
+		},
+	},
+})
+
+var orderType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Order",
+	Fields: graphql.Fields{
+		"id":      &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+		"name":    &graphql.Field{Type: graphql.String},
+		"email":   &graphql.Field{Type: graphql.String},
+		"address": &graphql.Field{Type: graphql.String},
+		"items":   &graphql.Field{Type: graphql.NewList(graphql.String)},
+		"deliveryTime": &graphql.Field{
+			Type: graphql.String,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(models.Order).DeliveryTime, nil
+			},
+		},
+		"status": &graphql.Field{
+			Type: graphql.String,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return string(p.Source.(models.Order).Status), nil
+			},
+		},
+		"restaurant": &graphql.Field{
+			Type: restaurantType,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				order := p.Source.(models.Order)
+				if order.RestaurantID == "" {
+					return nil, nil
+				}
+				return loadersFrom(p.Context).restaurants.load(order.RestaurantID), nil
+			},
+		},
+		"courier": &graphql.Field{
+			Type: courierType,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				order := p.Source.(models.Order)
+				if order.CourierID == "" {
+					return nil, nil
+				}
+				return loadersFrom(p.Context).couriers.load(order.CourierID), nil
+			},
+		},
+		"courierPosition": &graphql.Field{
+			Type: positionType,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				if position := p.Source.(models.Order).CourierPosition; position != nil {
+					return position, nil
+				}
+				return nil, nil
+			},
+		},
+	},
+})
+
+// placedOrderType is the order returned to the customer placing it: every
+// field of an Order, and the delivery PIN no other query returns
+var placedOrderType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "PlacedOrder",
+	Fields: graphql.FieldsThunk(func() graphql.Fields {
+		fields := graphql.Fields{
+			"deliveryPin": &graphql.Field{
+				Type: graphql.String,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(models.PlacedOrder).DeliveryPIN, nil
+				},
+			},
+		}
+		for name, definition := range orderType.Fields() {
+			resolve := definition.Resolve
+			if resolve == nil {
+				resolve = graphql.DefaultResolveFn
+			}
+			fields[name] = &graphql.Field{
+				Type:        definition.Type,
+				Description: definition.Description,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					p.Source = p.Source.(models.PlacedOrder).Order
+					return resolve(p)
+				},
+			}
+		}
+		return fields
+	}),
+})
+
+var queryType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Query",
+	Fields: graphql.Fields{
+		"order": &graphql.Field{
+			Type: orderType,
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return repository.GetOrderByID(p.Context, p.Args["id"].(string))
+			},
+		},
+		"orders": &graphql.Field{
+			Type: graphql.NewList(orderType),
+			Args: graphql.FieldConfigArgument{
+				// every order is only listed to administrators
+				"email": &graphql.ArgumentConfig{Type: graphql.String},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				var (
+					orders []models.Order
+					err    error
+				)
+				if email, _ := p.Args["email"].(string); email != "" {
+					orders, err = repository.GetOrderByEmail(p.Context, email)
+				} else if middleware.IsAdmin(p.Context) {
+					orders, err = repository.GetAllOrders(p.Context)
+				} else {
+					return nil, errAdminOnly
+				}
+				// the repository reports an empty result as an error; the list is simply empty
+				if err != nil {
+					return []models.Order{}, nil
+				}
+				return orders, nil
+			},
+		},
+		"restaurants": &graphql.Field{
+			Type: graphql.NewList(restaurantType),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return repository.GetRestaurants(), nil
+			},
+		},
+		"menu": &graphql.Field{
+			Type: graphql.NewList(menuItemType),
+			Args: graphql.FieldConfigArgument{
+				"restaurantId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				restaurant, err := repository.GetRestaurant(p.Args["restaurantId"].(string))
+				if err != nil {
+					return nil, err
+				}
+				return restaurant.Menu, nil
+			},
+		},
+		"couriers": &graphql.Field{
+			Type: graphql.NewList(courierType),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return repository.GetCouriers(), nil
+			},
+		},
+	},
+})
+
+var mutationType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Mutation",
+	Fields: graphql.Fields{
+		"placeOrder": &graphql.Field{
+			Type: placedOrderType,
+			Args: graphql.FieldConfigArgument{
+				"name":         &graphql.ArgumentConfig{Type: graphql.String},
+				"email":        &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				"address":      &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				"items":        &graphql.ArgumentConfig{Type: graphql.NewList(graphql.String)},
+				"restaurantId": &graphql.ArgumentConfig{Type: graphql.ID},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				newOrder := models.Order{
+					Email:   p.Args["email"].(string),
+					Address: p.Args["address"].(string),
+				}
+				newOrder.Name, _ = p.Args["name"].(string)
+				newOrder.RestaurantID, _ = p.Args["restaurantId"].(string)
+				if items, ok := p.Args["items"].([]interface{}); ok {
+					for _, item := range items {
+						if name, ok := item.(string); ok {
+							newOrder.Items = append(newOrder.Items, name)
+						}
+					}
+				}
+				order, err := repository.CreateOrder(p.Context, newOrder)
+				if err != nil {
+					return nil, err
+				}
+				return models.PlacedOrder{Order: order, DeliveryPIN: order.DeliveryPIN}, nil
+			},
+		},
+		"cancelOrder": &graphql.Field{
+			Type: graphql.String,
+			Args: graphql.FieldConfigArgument{
+				"id":     &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+				"email":  &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				"reason": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+			},
+			// customers only; restaurants and support cancel through their HTTP routes
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return repository.CancelOrder(p.Context, p.Args["id"].(string), models.OrderCancellation{
+					Initiator: models.InitiatorCustomer,
+					Email:     p.Args["email"].(string),
+					Reason:    p.Args["reason"].(string),
+				})
+			},
+		},
+		"updateAddress": &graphql.Field{
+			Type: orderType,
+			Args: graphql.FieldConfigArgument{
+				"id":      &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+				"email":   &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				"address": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				// the new total, when the address changes it
+				"confirmTotalCents": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 0},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return repository.UpdateAddress(p.Context, p.Args["id"].(string), models.OrderPatch{
+					Email:             p.Args["email"].(string),
+					Address:           p.Args["address"].(string),
+					ConfirmTotalCents: p.Args["confirmTotalCents"].(int),
+				})
+			},
+		},
+		"assignCourier": &graphql.Field{
+			Type: orderType,
+			Args: graphql.FieldConfigArgument{
+				"orderId":   &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+				"courierId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				if !middleware.IsAdmin(p.Context) {
+					return nil, errAdminOnly
+				}
+				return repository.AssignCourier(p.Context, p.Args["orderId"].(string), p.Args["courierId"].(string))
+			},
+		},
+		"advanceOrder": &graphql.Field{
+			Type: orderType,
+			Args: graphql.FieldConfigArgument{
+				"orderId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+				"status":  &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				if !middleware.IsAdmin(p.Context) {
+					return nil, errAdminOnly
+				}
+				return repository.AdvanceOrder(p.Context, p.Args["orderId"].(string), models.OrderStatus(p.Args["status"].(string)))
+			},
+		},
+	},
+})
+
+var subscriptionType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Subscription",
+	Fields: graphql.Fields{
+		"orderStatus": &graphql.Field{
+			Type: orderType,
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+			},
+			Subscribe: func(p graphql.ResolveParams) (interface{}, error) {
+				updates, stop, err := repository.WatchOrder(p.Args["id"].(string))
+				if err != nil {
+					return nil, err
+				}
+
+				events := make(chan interface{})
+				go func() {
+					defer stop()
+					defer close(events)
+					for {
+						select {
+						case <-p.Context.Done():
+							return
+						case order, open := <-updates:
+							if !open {
+								return
+							}
+							select {
+							case events <- order:
+							case <-p.Context.Done():
+								return
+							}
+						}
+					}
+				}()
+
+				return events, nil
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source, nil
+			},
+		},
+	},
+})
+
+// Schema is the GraphQL schema served at /graphql
+var Schema = mustSchema(graphql.SchemaConfig{
+	Query:        queryType,
+	Mutation:     mutationType,
+	Subscription: subscriptionType,
+})
+
+func mustSchema(config graphql.SchemaConfig) graphql.Schema {
+	schema, err := graphql.NewSchema(config)
+	if err != nil {
+		panic(err)
+	}
+	return schema
+}
@@ -0,0 +1,183 @@
+package graphqlapi
+
+import (
+	"context"
+	"testing"
+	"weservefood/middleware"
+	"weservefood/models"
+	"weservefood/payments"
+	"weservefood/repository"
+
+	"github.com/graphql-go/graphql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func execute(t *testing.T, ctx context.Context, query string, variables map[string]interface{}) map[string]interface{} {
+	result := graphql.Do(graphql.Params{
+		Schema:         Schema,
+		RequestString:  query,
+		VariableValues: variables,
+		Context:        ctx,
+	})
+	require.Empty(t, result.Errors)
+	return result.Data.(map[string]interface{})
+}
+
+func TestQueryOrderWithRestaurantAndCourier(t *testing.T) {
+	repository.AddRestaurant(models.Restaurant{ID: "r-gql", Name: "GraphQL Grill", Menu: []models.MenuItem{{Name: "Burger", PriceCents: 900}}})
+	repository.AddCourier(models.Courier{ID: "c-gql", Name: "Quinn", Available: true})
+	order, err := repository.CreateOrder(context.Background(), models.Order{Email: "gql@example.com", Address: "123 Test St", Items: []string{"Burger"}, RestaurantID: "r-gql"})
+	require.NoError(t, err)
+	_, err = repository.AssignCourier(context.Background(), order.ID, "c-gql")
+	require.NoError(t, err)
+	// free the courier's slot for reruns
+	t.Cleanup(func() {
+		repository.CancelOrder(context.Background(), order.ID, models.OrderCancellation{Email: "gql@example.com", Reason: "changed my mind"})
+	})
+
+	data := execute(t, context.Background(), `query($id: ID!) {
+		order(id: $id) { id status items restaurant { name menu { name priceCents } } courier { name } }
+	}`, map[string]interface{}{"id": order.ID})
+
+	fetched := data["order"].(map[string]interface{})
+	assert.Equal(t, order.ID, fetched["id"])
+	assert.Equal(t, "placed", fetched["status"])
+	assert.Equal(t, []interface{}{"Burger"}, fetched["items"])
+	assert.Equal(t, "GraphQL Grill", fetched["restaurant"].(map[string]interface{})["name"])
+	assert.Equal(t, []interface{}{map[string]interface{}{"name": "Burger", "priceCents": 900}}, fetched["restaurant"].(map[string]interface{})["menu"])
+	assert.Equal(t, "Quinn", fetched["courier"].(map[string]interface{})["name"])
+}
+
+func TestQueryOrdersBatchesRestaurantLookups(t *testing.T) {
+	repository.AddRestaurant(models.Restaurant{ID: "r-batch-1", Name: "First"})
+	repository.AddRestaurant(models.Restaurant{ID: "r-batch-2", Name: "Second"})
+	for _, restaurantID := range []string{"r-batch-1", "r-batch-2", "r-batch-1"} {
+		_, err := repository.CreateOrder(context.Background(), models.Order{Email: "batch@example.com", Address: "123 Test St", RestaurantID: restaurantID})
+		require.NoError(t, err)
+	}
+
+	var batches [][]string
+	l := newLoaders()
+	l.restaurants = newLoader(func(ids []string) map[string]models.Restaurant {
+		batches = append(batches, ids)
+		return repository.GetRestaurantsByIDs(ids)
+	})
+
+	data := execute(t, withLoaders(context.Background(), l), `{ orders(email: "batch@example.com") { restaurant { name } } }`, nil)
+
+	assert.GreaterOrEqual(t, len(data["orders"].([]interface{})), 3)
+	require.Len(t, batches, 1)
+	assert.ElementsMatch(t, []string{"r-batch-1", "r-batch-2"}, batches[0])
+}
+
+func TestQueryMenuAndCouriers(t *testing.T) {
+	repository.AddRestaurant(models.Restaurant{ID: "r-menu", Name: "Menu Place", Menu: []models.MenuItem{{Name: "Salad", PriceCents: 700}}})
+	repository.AddCourier(models.Courier{ID: "c-menu", Name: "Mo"})
+
+	data := execute(t, context.Background(), `{ menu(restaurantId: "r-menu") { name priceCents } couriers { id } restaurants { id } }`, nil)
+
+	assert.Equal(t, []interface{}{map[string]interface{}{"name": "Salad", "priceCents": 700}}, data["menu"])
+	assert.Contains(t, data["couriers"], map[string]interface{}{"id": "c-menu"})
+	assert.Contains(t, data["restaurants"], map[string]interface{}{"id": "r-menu"})
+}
+
+func TestMutations(t *testing.T) {
+	ctx := context.Background()
+
+	data := execute(t, ctx, `mutation { placeOrder(email: "mut@example.com", address: "123 Test St", items: ["Soup"]) { id address items } }`, nil)
+	placed := data["placeOrder"].(map[string]interface{})
+	assert.Equal(t, []interface{}{"Soup"}, placed["items"])
+	id := placed["id"].(string)
+
+	data = execute(t, ctx, `mutation($id: ID!) { updateAddress(id: $id, email: "mut@example.com", address: "456 New St") { address } }`, map[string]interface{}{"id": id})
+	assert.Equal(t, "456 New St", data["updateAddress"].(map[string]interface{})["address"])
+
+	data = execute(t, ctx, `mutation($id: ID!) { cancelOrder(id: $id, email: "mut@example.com", reason: "changed my mind") }`, map[string]interface{}{"id": id})
+	assert.Contains(t, data["cancelOrder"], "Order Cancelled Successfully")
+}
+
+func TestPlacedOrderDeliveredWithPIN(t *testing.T) {
+	ctx := context.Background()
+	repository.AddRestaurant(models.Restaurant{ID: "r-gql-pin", Name: "GraphQL Pins", Menu: []models.MenuItem{{Name: "Burger", PriceCents: 1000}}})
+	repository.AddCourier(models.Courier{ID: "c-gql-pin", Name: "Pia", Available: true})
+
+	data := execute(t, ctx, `mutation { placeOrder(email: "gql-pin@example.com", address: "1 Pin St", restaurantId: "r-gql-pin", items: ["Burger"]) { id status deliveryPin } }`, nil)
+	placed := data["placeOrder"].(map[string]interface{})
+	assert.Equal(t, "placed", placed["status"])
+	id, pin := placed["id"].(string), placed["deliveryPin"].(string)
+	require.Regexp(t, `^\d{4}$`, pin)
+
+	_, err := repository.ConfirmOrder(ctx, "gql-pin@example.com", id, payments.MethodVisa)
+	require.NoError(t, err)
+	_, err = repository.AssignCourier(ctx, id, "c-gql-pin")
+	require.NoError(t, err)
+	for _, status := range []models.OrderStatus{models.StatusPreparing, models.StatusOutForDelivery} {
+		_, err = repository.AdvanceOrder(ctx, id, status)
+		require.NoError(t, err)
+	}
+	delivered, err := repository.DeliverOrder(ctx, id, models.DeliveryConfirmation{CourierID: "c-gql-pin", PIN: pin}, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusDelivered, delivered.Status)
+}
+
+func TestMutationError(t *testing.T) {
+	result := graphql.Do(graphql.Params{
+		Schema:        Schema,
+		RequestString: `mutation { cancelOrder(id: "nonexistentID", email: "mut@example.com", reason: "changed my mind") }`,
+		Context:       context.Background(),
+	})
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, repository.ErrOrderNotFound.Error(), result.Errors[0].Message)
+}
+
+func TestAdvanceOrderMutation(t *testing.T) {
+	ctx := middleware.WithAdmin(context.Background())
+	repository.AddRestaurant(models.Restaurant{ID: "r-advance", Name: "Advance Deli", Menu: []models.MenuItem{{Name: "Bagel", PriceCents: 400}}})
+	order, err := repository.CreateOrder(ctx, models.Order{Email: "advance@example.com", Address: "1 Main St", RestaurantID: "r-advance", Items: []string{"Bagel"}})
+	require.NoError(t, err)
+	_, err = repository.ConfirmOrder(ctx, "advance@example.com", order.ID, "tok_visa")
+	require.NoError(t, err)
+
+	data := execute(t, ctx, `mutation($id: ID!) { advanceOrder(orderId: $id, status: "preparing") { status } }`, map[string]interface{}{"id": order.ID})
+	assert.Equal(t, "preparing", data["advanceOrder"].(map[string]interface{})["status"])
+
+	result := graphql.Do(graphql.Params{
+		Schema:         Schema,
+		RequestString:  `mutation($id: ID!) { advanceOrder(orderId: $id, status: "confirmed") { status } }`,
+		VariableValues: map[string]interface{}{"id": order.ID},
+		Context:        ctx,
+	})
+	require.Len(t, result.Errors, 1)
+	assert.Contains(t, result.Errors[0].Message, repository.ErrStatusChange.Error())
+}
+
+func TestAdminOnlyFields(t *testing.T) {
+	order, err := repository.CreateOrder(context.Background(), models.Order{Email: "admin-only@example.com", Address: "1 Main St"})
+	require.NoError(t, err)
+	repository.AddCourier(models.Courier{ID: "c-admin-only", Name: "Ari", Available: true})
+
+	for name, query := range map[string]string{
+		"orders":        `{ orders { id email } }`,
+		"assignCourier": `mutation($id: ID!) { assignCourier(orderId: $id, courierId: "c-admin-only") { id } }`,
+		"advanceOrder":  `mutation($id: ID!) { advanceOrder(orderId: $id, status: "preparing") { id } }`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			result := graphql.Do(graphql.Params{
+				Schema:         Schema,
+				RequestString:  query,
+				VariableValues: map[string]interface{}{"id": order.ID},
+				Context:        context.Background(),
+			})
+			require.Len(t, result.Errors, 1)
+			assert.Equal(t, errAdminOnly.Error(), result.Errors[0].Message)
+		})
+	}
+
+	stored, err := repository.GetOrderByID(context.Background(), order.ID)
+	require.NoError(t, err)
+	assert.Empty(t, stored.CourierID)
+
+	data := execute(t, middleware.WithAdmin(context.Background()), `{ orders { id } }`, nil)
+	assert.Contains(t, data["orders"], map[string]interface{}{"id": order.ID})
+}
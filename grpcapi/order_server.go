@@ -0,0 +1,168 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"weservefood/models"
+	"weservefood/orderpb"
+	"weservefood/repository"
+
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// OrderServer implements orderpb.OrderServiceServer on top of the repository
+type OrderServer struct {
+	orderpb.UnimplementedOrderServiceServer
+}
+
+// NewServer returns a gRPC server with the OrderService registered. Calls are
+// traced, continuing the trace context sent by the client.
+func NewServer(opts ...grpc.ServerOption) *grpc.Server {
+	opts = append([]grpc.ServerOption{grpc.StatsHandler(otelgrpc.NewServerHandler())}, opts...)
+	server := grpc.NewServer(opts...)
+	orderpb.RegisterOrderServiceServer(server, &OrderServer{})
+	return server
+}
+
+// toStatus maps repository errors to gRPC status errors
+func toStatus(err error) error {
+	switch {
+	case errors.Is(err, repository.ErrOrderNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, repository.ErrEmailMismatch):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, repository.ErrRestaurantNotFound), errors.Is(err, repository.ErrInvalidOrder),
+		errors.Is(err, repository.ErrOutsideDeliveryZone), errors.Is(err, repository.ErrBelowMinimumOrder):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, repository.ErrOrderCancelled), errors.Is(err, repository.ErrCourierFull),
+		errors.Is(err, repository.ErrOrderConfirmed), errors.Is(err, repository.ErrPaymentInProgress),
+		errors.Is(err, repository.ErrStatusChange), errors.Is(err, repository.ErrCancellationRejected),
+		errors.Is(err, repository.ErrAddressChangeRejected), errors.Is(err, repository.ErrPriceChangeUnconfirmed):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
+
+// toProto converts an order to its protobuf representation
+func toProto(order models.Order) *orderpb.Order {
+	return &orderpb.Order{
+		Id:           order.ID,
+		Name:         order.Name,
+		Email:        order.Email,
+		Address:      order.Address,
+		Items:        order.Items,
+		DeliveryTime: order.DeliveryTime,
+		Status:       string(order.Status),
+		RestaurantId: order.RestaurantID,
+		CourierId:    order.CourierID,
+	}
+}
+
+// PlaceOrder creates a new order
+func (s *OrderServer) PlaceOrder(ctx context.Context, req *orderpb.PlaceOrderRequest) (*orderpb.Order, error) {
+	order, err := repository.CreateOrder(ctx, models.Order{
+		Name:         req.GetName(),
+		Email:        req.GetEmail(),
+		Address:      req.GetAddress(),
+		Items:        req.GetItems(),
+		RestaurantID: req.GetRestaurantId(),
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	// only the customer placing the order is told its PIN
+	placed := toProto(order)
+	placed.DeliveryPin = order.DeliveryPIN
+	return placed, nil
+}
+
+// GetOrder retrieves a single order by ID
+func (s *OrderServer) GetOrder(ctx context.Context, req *orderpb.GetOrderRequest) (*orderpb.Order, error) {
+	order, err := repository.GetOrderByID(ctx, req.GetId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return toProto(order), nil
+}
+
+// ListOrders returns all active orders, or those of a single customer
+func (s *OrderServer) ListOrders(ctx context.Context, req *orderpb.ListOrdersRequest) (*orderpb.ListOrdersResponse, error) {
+	var (
+		orders []models.Order
+		err    error
+	)
+
+	if req.GetEmail() != "" {
+		orders, err = repository.GetOrderByEmail(ctx, req.GetEmail())
+	} else {
+		orders, err = repository.GetAllOrders(ctx)
+	}
+	// the repository reports an empty result as an error; the list is simply empty
+	if err != nil {
+		return &orderpb.ListOrdersResponse{}, nil
+	}
+
+	response := &orderpb.ListOrdersResponse{Orders: make([]*orderpb.Order, 0, len(orders))}
+	for _, order := range orders {
+		response.Orders = append(response.Orders, toProto(order))
+	}
+
+	return response, nil
+}
+
+// CancelOrder cancels an order owned by the given email
+func (s *OrderServer) CancelOrder(ctx context.Context, req *orderpb.CancelOrderRequest) (*orderpb.CancelOrderResponse, error) {
+	message, err := repository.CancelOrder(ctx, req.GetId(), models.OrderCancellation{Email: req.GetEmail(), Reason: req.GetReason()})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &orderpb.CancelOrderResponse{Message: message}, nil
+}
+
+// UpdateAddress changes the delivery address of an order owned by the given email
+func (s *OrderServer) UpdateAddress(ctx context.Context, req *orderpb.UpdateAddressRequest) (*orderpb.Order, error) {
+	if req.GetAddress() == "" {
+		return nil, status.Error(codes.InvalidArgument, "address is required")
+	}
+
+	order, err := repository.UpdateAddress(ctx, req.GetId(), models.OrderPatch{
+		Email:             req.GetEmail(),
+		Address:           req.GetAddress(),
+		ConfirmTotalCents: int(req.GetConfirmTotalCents()),
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return toProto(order), nil
+}
+
+// WatchOrder streams the state of an order until it is delivered or cancelled, or the client goes away
+func (s *OrderServer) WatchOrder(req *orderpb.WatchOrderRequest, stream grpc.ServerStreamingServer[orderpb.Order]) error {
+	updates, stop, err := repository.WatchOrder(req.GetId())
+	if err != nil {
+		return toStatus(err)
+	}
+	defer stop()
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		case order, open := <-updates:
+			if !open {
+				return nil
+			}
+			if err := stream.Send(toProto(order)); err != nil {
+				return err
+			}
+		}
+	}
+}
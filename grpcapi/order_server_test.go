@@ -0,0 +1,180 @@
+package grpcapi
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"weservefood/health"
+	"weservefood/models"
+	"weservefood/orderpb"
+	"weservefood/payments"
+	"weservefood/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func newTestClient(t *testing.T, opts ...grpc.ServerOption) orderpb.OrderServiceClient {
+	listener := bufconn.Listen(1024 * 1024)
+	server := NewServer(opts...)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return orderpb.NewOrderServiceClient(conn)
+}
+
+func TestPlaceAndGetOrder(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	placed, err := client.PlaceOrder(ctx, &orderpb.PlaceOrderRequest{
+		Email:   "grpc@example.com",
+		Address: "123 Test St",
+		Items:   []string{"pizza"},
+	})
+	require.NoError(t, err)
+	assert.NotEmpty(t, placed.GetId())
+	assert.Equal(t, "placed", placed.GetStatus())
+
+	fetched, err := client.GetOrder(ctx, &orderpb.GetOrderRequest{Id: placed.GetId()})
+	require.NoError(t, err)
+	assert.Equal(t, placed.GetAddress(), fetched.GetAddress())
+	assert.Equal(t, []string{"pizza"}, fetched.GetItems())
+}
+
+func TestPlacedOrderDeliveredWithPIN(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+	repository.AddRestaurant(models.Restaurant{ID: "r-grpc-pin", Name: "gRPC Grill", Menu: []models.MenuItem{{Name: "Burger", PriceCents: 1000}}})
+	repository.AddCourier(models.Courier{ID: "c-grpc-pin", Name: "Gina", Available: true})
+
+	placed, err := client.PlaceOrder(ctx, &orderpb.PlaceOrderRequest{Email: "grpc-pin@example.com", Address: "1 Pin St", RestaurantId: "r-grpc-pin", Items: []string{"Burger"}})
+	require.NoError(t, err)
+	require.Regexp(t, `^\d{4}$`, placed.GetDeliveryPin())
+	fetched, err := client.GetOrder(ctx, &orderpb.GetOrderRequest{Id: placed.GetId()})
+	require.NoError(t, err)
+	assert.Empty(t, fetched.GetDeliveryPin(), "only the customer placing the order is told the PIN")
+
+	_, err = repository.ConfirmOrder(ctx, "grpc-pin@example.com", placed.GetId(), payments.MethodVisa)
+	require.NoError(t, err)
+	_, err = repository.AssignCourier(ctx, placed.GetId(), "c-grpc-pin")
+	require.NoError(t, err)
+	for _, status := range []models.OrderStatus{models.StatusPreparing, models.StatusOutForDelivery} {
+		_, err = repository.AdvanceOrder(ctx, placed.GetId(), status)
+		require.NoError(t, err)
+	}
+	delivered, err := repository.DeliverOrder(ctx, placed.GetId(), models.DeliveryConfirmation{CourierID: "c-grpc-pin", PIN: placed.GetDeliveryPin()}, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusDelivered, delivered.Status)
+}
+
+func TestPlaceOrderInvalidArgument(t *testing.T) {
+	client := newTestClient(t)
+
+	_, err := client.PlaceOrder(context.Background(), &orderpb.PlaceOrderRequest{Name: "No Email"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestGetOrderNotFound(t *testing.T) {
+	client := newTestClient(t)
+
+	_, err := client.GetOrder(context.Background(), &orderpb.GetOrderRequest{Id: "nonexistentID"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestListOrders(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	placed, err := client.PlaceOrder(ctx, &orderpb.PlaceOrderRequest{Email: "list-grpc@example.com", Address: "123 Test St"})
+	require.NoError(t, err)
+
+	response, err := client.ListOrders(ctx, &orderpb.ListOrdersRequest{Email: "list-grpc@example.com"})
+	require.NoError(t, err)
+	var ids []string
+	for _, order := range response.GetOrders() {
+		ids = append(ids, order.GetId())
+	}
+	assert.Contains(t, ids, placed.GetId())
+
+	response, err = client.ListOrders(ctx, &orderpb.ListOrdersRequest{Email: "nobody@example.com"})
+	require.NoError(t, err)
+	assert.Empty(t, response.GetOrders())
+}
+
+func TestUpdateAddressPermissionDenied(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	placed, err := client.PlaceOrder(ctx, &orderpb.PlaceOrderRequest{Email: "grpc@example.com", Address: "123 Test St"})
+	require.NoError(t, err)
+
+	_, err = client.UpdateAddress(ctx, &orderpb.UpdateAddressRequest{Id: placed.GetId(), Email: "wrong@example.com", Address: "456 New St"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+func TestWatchOrder(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	placed, err := client.PlaceOrder(ctx, &orderpb.PlaceOrderRequest{Email: "watch@example.com", Address: "123 Test St"})
+	require.NoError(t, err)
+
+	stream, err := client.WatchOrder(ctx, &orderpb.WatchOrderRequest{Id: placed.GetId()})
+	require.NoError(t, err)
+
+	current, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, "123 Test St", current.GetAddress())
+
+	_, err = client.UpdateAddress(ctx, &orderpb.UpdateAddressRequest{Id: placed.GetId(), Email: "watch@example.com", Address: "456 New St"})
+	require.NoError(t, err)
+
+	updated, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, "456 New St", updated.GetAddress())
+
+	cancelled, err := client.CancelOrder(ctx, &orderpb.CancelOrderRequest{Id: placed.GetId(), Email: "watch@example.com", Reason: "changed my mind"})
+	require.NoError(t, err)
+	assert.Contains(t, cancelled.GetMessage(), "Order Cancelled Successfully")
+
+	last, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, "cancelled", last.GetStatus())
+
+	_, err = stream.Recv()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestServingOnly(t *testing.T) {
+	client := newTestClient(t, ServingOnly()...)
+	ctx := context.Background()
+
+	health.SetPhase(health.PhaseStarting)
+	_, err := client.GetOrder(ctx, &orderpb.GetOrderRequest{Id: "nonexistentID"})
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	stream, err := client.WatchOrder(ctx, &orderpb.WatchOrderRequest{Id: "nonexistentID"})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+
+	health.SetPhase(health.PhaseServing)
+	t.Cleanup(func() { health.SetPhase(health.PhaseStarting) })
+	_, err = client.GetOrder(ctx, &orderpb.GetOrderRequest{Id: "nonexistentID"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
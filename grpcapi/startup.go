@@ -0,0 +1,36 @@
+package grpcapi
+
+import (
+	"context"
+	"weservefood/health"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ServingOnly refuses calls with Unavailable while the server is starting, so
+// no call sees the order store before the orders are replayed
+func ServingOnly() []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			if err := checkStarted(); err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}),
+		grpc.ChainStreamInterceptor(func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			if err := checkStarted(); err != nil {
+				return err
+			}
+			return handler(srv, stream)
+		}),
+	}
+}
+
+func checkStarted() error {
+	if health.CurrentPhase() == health.PhaseStarting {
+		return status.Error(codes.Unavailable, "server is starting")
+	}
+	return nil
+}
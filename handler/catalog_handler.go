@@ -0,0 +1,161 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"weservefood/models"
+	"weservefood/repository"
+
+	"github.com/gorilla/mux"
+)
+
+// @Summary List restaurants
+// @Description Retrieve all restaurants together with their menus
+// @Tags v1
+// @Produce json
+// @Success 200 {array} models.Restaurant
+// @Router /v1/restaurants [get]
+func ListRestaurantsV1(rw http.ResponseWriter, req *http.Request) {
+	rw.Header().Set(ContentTypeHeader, ApplicationJson)
+	if err := json.NewEncoder(rw).Encode(repository.GetRestaurants()); err != nil {
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// @Summary Get a menu
+// @Description Retrieve the menu of a restaurant
+// @Tags v1
+// @Produce json
+// @Param id path string true "Restaurant ID"
+// @Success 200 {array} models.MenuItem
+// @Failure 404 {string} string "restaurant not found"
+// @Router /v1/restaurants/{id}/menu [get]
+func GetMenuV1(rw http.ResponseWriter, req *http.Request) {
+	restaurant, err := repository.GetRestaurant(mux.Vars(req)["id"])
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	rw.Header().Set(ContentTypeHeader, ApplicationJson)
+	if err := json.NewEncoder(rw).Encode(restaurant.Menu); err != nil {
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// @Summary List couriers
+// @Description Retrieve all couriers and their availability
+// @Tags v1
+// @Produce json
+// @Success 200 {array} models.Courier
+// @Router /v1/couriers [get]
+func ListCouriersV1(rw http.ResponseWriter, req *http.Request) {
+	rw.Header().Set(ContentTypeHeader, ApplicationJson)
+	if err := json.NewEncoder(rw).Encode(repository.GetCouriers()); err != nil {
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// zoneStatus is the status for an error managing delivery zones
+func zoneStatus(err error) int {
+	switch {
+	case errors.Is(err, repository.ErrRestaurantNotFound), errors.Is(err, repository.ErrZoneNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, repository.ErrInvalidZone):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// @Summary List delivery zones
+// @Description Retrieve the delivery zones of a restaurant in the order they are matched against addresses
+// @Tags admin
+// @Produce json
+// @Param id path string true "Restaurant ID"
+// @Success 200 {array} models.DeliveryZone
+// @Failure 401 {string} string "a valid bearer token is required"
+// @Failure 404 {string} string "restaurant not found"
+// @Security BearerAuth
+// @Router /v1/admin/restaurants/{id}/zones [get]
+func ListZonesV1(rw http.ResponseWriter, req *http.Request) {
+	zones, err := repository.GetZones(mux.Vars(req)["id"])
+	if err != nil {
+		http.Error(rw, err.Error(), zoneStatus(err))
+		return
+	}
+	if zones == nil {
+		zones = []models.DeliveryZone{}
+	}
+
+	rw.Header().Set(ContentTypeHeader, ApplicationJson)
+	if err := json.NewEncoder(rw).Encode(zones); err != nil {
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// @Summary Create or replace a delivery zone
+// @Description Add a delivery zone to a restaurant, or replace the zone of the same name. The zone covers the postal codes or districts in areas and the addresses located inside the GeoJSON polygon in boundary, and orders delivered there pay its fee and must reach its minimum order. Orders already placed keep their price. The change is saved to the catalog file, and not applied when that fails.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path string true "Restaurant ID"
+// @Param name path string true "Zone name"
+// @Param zone body models.DeliveryZone true "Delivery zone"
+// @Success 200 {object} models.DeliveryZone "zone replaced"
+// @Success 201 {object} models.DeliveryZone "zone added"
+// @Failure 400 {string} string "invalid delivery zone: zone centre: invalid polygon: ring 0 is not closed"
+// @Failure 401 {string} string "a valid bearer token is required"
+// @Failure 404 {string} string "restaurant not found"
+// @Failure 500 {string} string "saving the catalog: permission denied"
+// @Security BearerAuth
+// @Router /v1/admin/restaurants/{id}/zones/{name} [put]
+func PutZoneV1(rw http.ResponseWriter, req *http.Request) {
+	var zone models.DeliveryZone
+
+	if err := json.NewDecoder(req.Body).Decode(&zone); err != nil {
+		http.Error(rw, err.Error(), decodeStatus(err))
+		return
+	}
+	// the path names the zone
+	zone.Name = mux.Vars(req)["name"]
+
+	created, err := repository.PutZone(mux.Vars(req)["id"], zone)
+	if err != nil {
+		http.Error(rw, err.Error(), zoneStatus(err))
+		return
+	}
+
+	rw.Header().Set(ContentTypeHeader, ApplicationJson)
+	if created {
+		rw.WriteHeader(http.StatusCreated)
+	}
+	if err := json.NewEncoder(rw).Encode(zone); err != nil {
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// @Summary Delete a delivery zone
+// @Description Stop delivering to a zone of a restaurant. Orders already placed there are not affected. The change is saved to the catalog file, and not applied when that fails.
+// @Tags admin
+// @Param id path string true "Restaurant ID"
+// @Param name path string true "Zone name"
+// @Success 204 "zone deleted"
+// @Failure 401 {string} string "a valid bearer token is required"
+// @Failure 404 {string} string "delivery zone not found"
+// @Failure 500 {string} string "saving the catalog: permission denied"
+// @Security BearerAuth
+// @Router /v1/admin/restaurants/{id}/zones/{name} [delete]
+func DeleteZoneV1(rw http.ResponseWriter, req *http.Request) {
+	if err := repository.DeleteZone(mux.Vars(req)["id"], mux.Vars(req)["name"]); err != nil {
+		http.Error(rw, err.Error(), zoneStatus(err))
+		return
+	}
+	rw.WriteHeader(http.StatusNoContent)
+}
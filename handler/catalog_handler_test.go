@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"weservefood/models"
+	"weservefood/repository"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func newCatalogRouter() *mux.Router {
+	router := mux.NewRouter()
+	router.HandleFunc("/v1/restaurants", ListRestaurantsV1).Methods("GET")
+	router.HandleFunc("/v1/restaurants/{id}/menu", GetMenuV1).Methods("GET")
+	router.HandleFunc("/v1/couriers", ListCouriersV1).Methods("GET")
+	router.HandleFunc("/v1/admin/restaurants/{id}/zones", ListZonesV1).Methods("GET")
+	router.HandleFunc("/v1/admin/restaurants/{id}/zones/{name}", PutZoneV1).Methods("PUT")
+	router.HandleFunc("/v1/admin/restaurants/{id}/zones/{name}", DeleteZoneV1).Methods("DELETE")
+	return router
+}
+
+func TestListRestaurantsV1(t *testing.T) {
+	repository.AddRestaurant(models.Restaurant{ID: "r-handler", Name: "Handler Diner"})
+
+	req, err := http.NewRequest("GET", "/v1/restaurants", nil)
+	assert.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	newCatalogRouter().ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var restaurants []models.Restaurant
+	err = json.NewDecoder(rr.Body).Decode(&restaurants)
+	assert.NoError(t, err)
+	assert.Contains(t, restaurants, models.Restaurant{ID: "r-handler", Name: "Handler Diner"})
+}
+
+func TestGetMenuV1(t *testing.T) {
+	menu := []models.MenuItem{{Name: "Dosa", PriceCents: 800}}
+	repository.AddRestaurant(models.Restaurant{ID: "r-handler-menu", Name: "Menu Diner", Menu: menu})
+
+	req, err := http.NewRequest("GET", "/v1/restaurants/r-handler-menu/menu", nil)
+	assert.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	newCatalogRouter().ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var items []models.MenuItem
+	err = json.NewDecoder(rr.Body).Decode(&items)
+	assert.NoError(t, err)
+	assert.Equal(t, menu, items)
+}
+
+func TestGetMenuV1NotFound(t *testing.T) {
+	req, err := http.NewRequest("GET", "/v1/restaurants/r-missing/menu", nil)
+	assert.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	newCatalogRouter().ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+func TestListCouriersV1(t *testing.T) {
+	repository.AddCourier(models.Courier{ID: "c-handler", Name: "Hal", Available: true})
+
+	req, err := http.NewRequest("GET", "/v1/couriers", nil)
+	assert.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	newCatalogRouter().ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var couriers []models.Courier
+	err = json.NewDecoder(rr.Body).Decode(&couriers)
+	assert.NoError(t, err)
+	assert.Contains(t, couriers, models.Courier{ID: "c-handler", Name: "Hal", Available: true})
+}
+
+func TestZonesV1(t *testing.T) {
+	repository.AddRestaurant(models.Restaurant{ID: "r-handler-zones", Name: "Zoned Diner"})
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		newCatalogRouter().ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := serve("GET", "/v1/admin/restaurants/r-handler-zones/zones", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, "[]", rr.Body.String())
+
+	zone := `{"boundary": {"type": "Polygon", "coordinates": [[[13.36, 52.51], [13.42, 52.51], [13.42, 52.55], [13.36, 52.55], [13.36, 52.51]]]},
+		"delivery_fee_cents": 199, "delivery_minutes": 20, "minimum_order_cents": 1500}`
+	rr = serve("PUT", "/v1/admin/restaurants/r-handler-zones/zones/mitte", zone)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	rr = serve("PUT", "/v1/admin/restaurants/r-handler-zones/zones/mitte", zone)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	rr = serve("GET", "/v1/admin/restaurants/r-handler-zones/zones", "")
+	var zones []models.DeliveryZone
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&zones))
+	if assert.Len(t, zones, 1) {
+		assert.Equal(t, "mitte", zones[0].Name)
+		assert.Equal(t, 1500, zones[0].MinimumOrderCents)
+	}
+
+	rr = serve("PUT", "/v1/admin/restaurants/r-handler-zones/zones/open", `{"boundary": {"type": "Polygon", "coordinates": [[[0, 0], [1, 0], [1, 1], [0, 1]]]}, "delivery_minutes": 20}`)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "ring 0 is not closed")
+	rr = serve("PUT", "/v1/admin/restaurants/r-missing/zones/mitte", zone)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+
+	assert.Equal(t, http.StatusNoContent, serve("DELETE", "/v1/admin/restaurants/r-handler-zones/zones/mitte", "").Code)
+	assert.Equal(t, http.StatusNotFound, serve("DELETE", "/v1/admin/restaurants/r-handler-zones/zones/mitte", "").Code)
+}
@@ -0,0 +1,123 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"weservefood/middleware"
+	"weservefood/models"
+	"weservefood/repository"
+
+	"github.com/gorilla/mux"
+)
+
+// multipartMemory is how much of a multipart upload is held in memory before
+// the rest is spooled to temporary files
+const multipartMemory = 8 << 20
+
+// @Summary Deliver an order
+// @Description Complete an order out for delivery with the proof it requires (proof_required on the order): the PIN shown to the customer, a photo of the handover or the customer's signature. Send JSON when only the PIN is given, or multipart/form-data with the fields pin, lat and lng and the JPEG or PNG files photo and signature. The courier is the one the bearer token belongs to, and only the courier carrying the order may deliver it; five wrong PINs lock the order until support steps in.
+// @Tags v1
+// @Accept json,mpfd
+// @Produce json
+// @Param id path string true "Order ID"
+// @Param confirmation body models.DeliveryConfirmation false "PIN and location, when not sent as multipart/form-data"
+// @Success 200 {object} models.Order
+// @Failure 400 {string} string "proof of delivery required: the order requires a photo of the handover"
+// @Failure 401 {string} string "a valid bearer token is required"
+// @Failure 403 {string} string "delivery PIN does not match: 4 attempts left"
+// @Failure 404 {string} string "order not found"
+// @Failure 409 {string} string "delivery PIN locked after too many wrong attempts"
+// @Failure 413 {string} string "request body too large"
+// @Security BearerAuth
+// @Router /v1/orders/{id}/delivery [post]
+func DeliverOrderV1(rw http.ResponseWriter, req *http.Request) {
+	var confirmation models.DeliveryConfirmation
+	var photo, signature io.Reader
+
+	if mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type")); mediaType == "multipart/form-data" {
+		if err := req.ParseMultipartForm(multipartMemory); err != nil {
+			http.Error(rw, err.Error(), decodeStatus(err))
+			return
+		}
+		defer req.MultipartForm.RemoveAll()
+
+		confirmation.PIN = req.FormValue("pin")
+		if lat, lng := req.FormValue("lat"), req.FormValue("lng"); lat != "" || lng != "" {
+			location, err := parseLocation(lat, lng)
+			if err != nil {
+				http.Error(rw, err.Error(), http.StatusBadRequest)
+				return
+			}
+			confirmation.Location = &location
+		}
+		for name, upload := range map[string]*io.Reader{"photo": &photo, "signature": &signature} {
+			file, _, err := req.FormFile(name)
+			if errors.Is(err, http.ErrMissingFile) {
+				continue
+			}
+			if err != nil {
+				http.Error(rw, err.Error(), http.StatusBadRequest)
+				return
+			}
+			defer file.Close()
+			*upload = file
+		}
+	} else if err := json.NewDecoder(req.Body).Decode(&confirmation); err != nil {
+		http.Error(rw, err.Error(), decodeStatus(err))
+		return
+	}
+
+	confirmation.CourierID, _ = middleware.CourierOf(req.Context())
+	order, err := repository.DeliverOrder(req.Context(), mux.Vars(req)["id"], confirmation, photo, signature)
+	if err != nil {
+		http.Error(rw, err.Error(), errorStatus(err))
+		return
+	}
+
+	rw.Header().Set(ContentTypeHeader, ApplicationJson)
+	if err := json.NewEncoder(rw).Encode(order); err != nil {
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// @Summary Get a proof of delivery
+// @Description Download the photo or signature a courier gave as proof of delivery, to settle disputes
+// @Tags admin
+// @Produce image/jpeg,image/png
+// @Param id path string true "Order ID"
+// @Param kind path string true "photo or signature"
+// @Success 200 {file} binary
+// @Failure 401 {string} string "a valid bearer token is required"
+// @Failure 404 {string} string "proof of delivery not found: the order has no photo"
+// @Security BearerAuth
+// @Router /v1/admin/orders/{id}/proof/{kind} [get]
+func GetProofV1(rw http.ResponseWriter, req *http.Request) {
+	proof, contentType, err := repository.OpenProof(req.Context(), mux.Vars(req)["id"], models.ProofMethod(mux.Vars(req)["kind"]))
+	if err != nil {
+		http.Error(rw, err.Error(), errorStatus(err))
+		return
+	}
+	defer proof.Close()
+
+	rw.Header().Set(ContentTypeHeader, contentType)
+	io.Copy(rw, proof)
+}
+
+// parseLocation reads a location given as form fields
+func parseLocation(lat, lng string) (models.LatLng, error) {
+	latitude, err := strconv.ParseFloat(lat, 64)
+	if err != nil {
+		return models.LatLng{}, fmt.Errorf("invalid lat: %w", err)
+	}
+	longitude, err := strconv.ParseFloat(lng, 64)
+	if err != nil {
+		return models.LatLng{}, fmt.Errorf("invalid lng: %w", err)
+	}
+	return models.LatLng{Lat: latitude, Lng: longitude}, nil
+}
@@ -0,0 +1,160 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"weservefood/blob"
+	"weservefood/config"
+	"weservefood/middleware"
+	"weservefood/models"
+	"weservefood/payments"
+	"weservefood/repository"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newDeliveryRouter() *mux.Router {
+	router := mux.NewRouter()
+	router.Use(middleware.AuthMiddleware(config.Auth{CourierTokens: []string{
+		"c-handler-pin=pin-secret", "c-handler-photo=photo-secret", "c-someone-else=other-secret",
+	}}))
+	router.Handle("/v1/orders/{id}/delivery", middleware.RequireCourier(http.HandlerFunc(DeliverOrderV1))).Methods("POST")
+	router.HandleFunc("/v1/admin/orders/{id}/proof/{kind}", GetProofV1).Methods("GET")
+	return router
+}
+
+// riddenOrder places an order requiring the proof and has the courier ride it
+func riddenOrder(t *testing.T, email, courierID string, proof models.ProofMethod) models.Order {
+	t.Helper()
+	ctx := context.Background()
+	repository.AddRestaurant(models.Restaurant{ID: "r-delivery", Name: "Doorstep Deli", Menu: []models.MenuItem{{Name: "Bagel", PriceCents: 450}}})
+	repository.AddCourier(models.Courier{ID: courierID, Name: "Del", Available: true})
+	order, err := repository.CreateOrder(ctx, models.Order{Email: email, Address: "1 Door St", RestaurantID: "r-delivery", Items: []string{"Bagel"}, ProofRequired: proof})
+	require.NoError(t, err)
+	_, err = repository.ConfirmOrder(ctx, email, order.ID, payments.MethodVisa)
+	require.NoError(t, err)
+	_, err = repository.AssignCourier(ctx, order.ID, courierID)
+	require.NoError(t, err)
+	_, err = repository.AdvanceOrder(ctx, order.ID, models.StatusPreparing)
+	require.NoError(t, err)
+	order, err = repository.AdvanceOrder(ctx, order.ID, models.StatusOutForDelivery)
+	require.NoError(t, err)
+	return order
+}
+
+func TestDeliverOrderV1WithPIN(t *testing.T) {
+	order := riddenOrder(t, "handler-pin@example.com", "c-handler-pin", models.ProofPIN)
+
+	tests := []struct {
+		name   string
+		token  string
+		body   string
+		status int
+	}{
+		{"anonymous", "", `{"pin":"0000"}`, http.StatusUnauthorized},
+		{"another courier", "other-secret", `{"pin":"` + order.DeliveryPIN + `"}`, http.StatusForbidden},
+		{"courier named in the body", "other-secret", `{"courier_id":"c-handler-pin","pin":"` + order.DeliveryPIN + `"}`, http.StatusForbidden},
+		{"no PIN", "pin-secret", `{}`, http.StatusBadRequest},
+		{"malformed body", "pin-secret", `{"pin":`, http.StatusBadRequest},
+		{"right PIN", "pin-secret", `{"pin":"` + order.DeliveryPIN + `"}`, http.StatusOK},
+		{"already delivered", "pin-secret", `{"pin":"` + order.DeliveryPIN + `"}`, http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest("POST", "/v1/orders/"+order.ID+"/delivery", strings.NewReader(tt.body))
+			assert.NoError(t, err)
+			req.Header.Set(ContentTypeHeader, ApplicationJson)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+
+			rr := httptest.NewRecorder()
+			newDeliveryRouter().ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.status, rr.Code, rr.Body.String())
+		})
+	}
+}
+
+func TestDeliverOrderV1LockoutNeedsAssignedCourier(t *testing.T) {
+	order := riddenOrder(t, "handler-lockout@example.com", "c-handler-pin", models.ProofPIN)
+	deliver := func(token, pin string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest("POST", "/v1/orders/"+order.ID+"/delivery", strings.NewReader(`{"pin":"`+pin+`"}`))
+		require.NoError(t, err)
+		req.Header.Set(ContentTypeHeader, ApplicationJson)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rr := httptest.NewRecorder()
+		newDeliveryRouter().ServeHTTP(rr, req)
+		return rr
+	}
+	wrongPIN := "0000"
+	if order.DeliveryPIN == wrongPIN {
+		wrongPIN = "0001"
+	}
+
+	for range 10 {
+		assert.Equal(t, http.StatusUnauthorized, deliver("", wrongPIN).Code)
+		assert.Equal(t, http.StatusForbidden, deliver("other-secret", wrongPIN).Code)
+	}
+
+	rr := deliver("pin-secret", wrongPIN)
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	assert.Contains(t, rr.Body.String(), "4 attempts left")
+	assert.Equal(t, http.StatusOK, deliver("pin-secret", order.DeliveryPIN).Code)
+}
+
+func TestDeliverOrderV1WithPhoto(t *testing.T) {
+	previous := repository.Blobs
+	repository.Blobs = blob.NewDir(t.TempDir())
+	t.Cleanup(func() { repository.Blobs = previous })
+	order := riddenOrder(t, "handler-photo@example.com", "c-handler-photo", models.ProofPhoto)
+	photo := "\xff\xd8\xff\xe0 doorstep photo"
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	form.WriteField("lat", "52.53")
+	form.WriteField("lng", "13.38")
+	part, err := form.CreateFormFile("photo", "door.jpg")
+	require.NoError(t, err)
+	part.Write([]byte(photo))
+	require.NoError(t, form.Close())
+
+	req, err := http.NewRequest("POST", "/v1/orders/"+order.ID+"/delivery", &body)
+	assert.NoError(t, err)
+	req.Header.Set(ContentTypeHeader, form.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer photo-secret")
+	rr := httptest.NewRecorder()
+	newDeliveryRouter().ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var delivered models.Order
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&delivered))
+	assert.Equal(t, models.StatusDelivered, delivered.Status)
+	assert.Equal(t, &models.LatLng{Lat: 52.53, Lng: 13.38}, delivered.Delivery.Location)
+
+	req, err = http.NewRequest("GET", "/v1/admin/orders/"+order.ID+"/proof/photo", nil)
+	assert.NoError(t, err)
+	rr = httptest.NewRecorder()
+	newDeliveryRouter().ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "image/jpeg", rr.Header().Get(ContentTypeHeader))
+	assert.Equal(t, photo, rr.Body.String())
+
+	req, err = http.NewRequest("GET", "/v1/admin/orders/"+order.ID+"/proof/signature", nil)
+	assert.NoError(t, err)
+	rr = httptest.NewRecorder()
+	newDeliveryRouter().ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"weservefood/repository"
+
+	"github.com/gorilla/mux"
+)
+
+// @Summary Preview batched deliveries
+// @Description Group the orders being prepared into courier runs and order the stops of each run, without dispatching anything. Orders only share a courier when their drop-offs are close together, they are due close together and every stop stays on time. A batch without a courier_id has no courier free to take it.
+// @Tags admin
+// @Produce json
+// @Success 200 {array} models.Batch
+// @Failure 401 {string} string "a valid bearer token is required"
+// @Security BearerAuth
+// @Router /v1/admin/dispatch/plan [get]
+func PlanBatchesV1(rw http.ResponseWriter, req *http.Request) {
+	batches, err := repository.PlanBatches(req.Context())
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	rw.Header().Set(ContentTypeHeader, ApplicationJson)
+	if err := json.NewEncoder(rw).Encode(batches); err != nil {
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// @Summary Dispatch batched deliveries
+// @Description Plan batches as the preview does and assign each batch a courier is free for to that courier. The delivery estimates of its orders follow the planned route. Batches no courier is free for are left for the next dispatch.
+// @Tags admin
+// @Produce json
+// @Success 200 {array} models.Batch "dispatched batches"
+// @Failure 401 {string} string "a valid bearer token is required"
+// @Security BearerAuth
+// @Router /v1/admin/dispatch [post]
+func DispatchBatchesV1(rw http.ResponseWriter, req *http.Request) {
+	batches, err := repository.DispatchBatches(req.Context())
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	rw.Header().Set(ContentTypeHeader, ApplicationJson)
+	if err := json.NewEncoder(rw).Encode(batches); err != nil {
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// @Summary Get a batch
+// @Description Retrieve a dispatched batch with its stops in the order the courier makes them
+// @Tags admin
+// @Produce json
+// @Param id path string true "Batch ID"
+// @Success 200 {object} models.Batch
+// @Failure 401 {string} string "a valid bearer token is required"
+// @Failure 404 {string} string "batch not found"
+// @Security BearerAuth
+// @Router /v1/admin/dispatch/batches/{id} [get]
+func GetBatchV1(rw http.ResponseWriter, req *http.Request) {
+	batch, err := repository.GetBatch(req.Context(), mux.Vars(req)["id"])
+	if errors.Is(err, repository.ErrBatchNotFound) {
+		http.Error(rw, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	rw.Header().Set(ContentTypeHeader, ApplicationJson)
+	if err := json.NewEncoder(rw).Encode(batch); err != nil {
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"weservefood/models"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func newDispatchRouter() *mux.Router {
+	router := mux.NewRouter()
+	router.HandleFunc("/v1/admin/dispatch/plan", PlanBatchesV1).Methods("GET")
+	router.HandleFunc("/v1/admin/dispatch", DispatchBatchesV1).Methods("POST")
+	router.HandleFunc("/v1/admin/dispatch/batches/{id}", GetBatchV1).Methods("GET")
+	return router
+}
+
+func TestPlanBatchesV1(t *testing.T) {
+	req, err := http.NewRequest("GET", "/v1/admin/dispatch/plan", nil)
+	assert.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	newDispatchRouter().ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, ApplicationJson, rr.Header().Get(ContentTypeHeader))
+
+	var batches []models.Batch
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&batches))
+	assert.NotNil(t, batches, "an empty plan is an empty list")
+}
+
+func TestGetBatchV1NotFound(t *testing.T) {
+	req, err := http.NewRequest("GET", "/v1/admin/dispatch/batches/b-missing", nil)
+	assert.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	newDispatchRouter().ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Contains(t, rr.Body.String(), "batch not found")
+}
@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"weservefood/export"
+	"weservefood/models"
+	"weservefood/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportOrdersV1(t *testing.T) {
+	repository.AddRestaurant(models.Restaurant{ID: "r-export", Name: "Export Eatery", Menu: []models.MenuItem{{Name: "Soup", PriceCents: 500}}})
+	from := time.Now().UTC().Format(time.RFC3339Nano)
+
+	placed, err := repository.CreateOrder(context.Background(), models.Order{Email: "export@example.com", Address: "1 Main St", RestaurantID: "r-export", Items: []string{"Soup"}})
+	require.NoError(t, err)
+	cancelled, err := repository.CreateOrder(context.Background(), models.Order{Email: "export@example.com", Address: "2 Main St", RestaurantID: "r-export", Items: []string{"Soup"}})
+	require.NoError(t, err)
+	_, err = repository.CancelOrder(context.Background(), cancelled.ID, models.OrderCancellation{Email: "export@example.com", Reason: "changed my mind"})
+	require.NoError(t, err)
+
+	req, err := http.NewRequest("GET", "/v1/admin/orders/export?restaurant_id=r-export&from="+from, nil)
+	require.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(ExportOrdersV1).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "text/csv", rr.Header().Get("Content-Type"))
+	assert.Contains(t, rr.Header().Get("Content-Disposition"), ".csv")
+
+	records, err := csv.NewReader(rr.Body).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.Equal(t, placed.ID, records[1][0])
+	assert.Equal(t, "799", records[1][10])
+	assert.Equal(t, cancelled.ID, records[2][0])
+	assert.Equal(t, "cancelled", records[2][7])
+	assert.NotEmpty(t, records[2][17])
+	assert.Equal(t, []string{"customer", "changed my mind"}, records[2][21:])
+}
+
+func TestExportOrdersV1NDJSONByStatus(t *testing.T) {
+	repository.AddRestaurant(models.Restaurant{ID: "r-export-status", Name: "Status Eatery"})
+	placed, err := repository.CreateOrder(context.Background(), models.Order{Email: "export@example.com", Address: "1 Main St", RestaurantID: "r-export-status"})
+	require.NoError(t, err)
+	cancelled, err := repository.CreateOrder(context.Background(), models.Order{Email: "export@example.com", Address: "2 Main St", RestaurantID: "r-export-status"})
+	require.NoError(t, err)
+	_, err = repository.CancelOrder(context.Background(), cancelled.ID, models.OrderCancellation{Email: "export@example.com", Reason: "changed my mind"})
+	require.NoError(t, err)
+
+	req, err := http.NewRequest("GET", "/v1/admin/orders/export?format=ndjson&status=placed&restaurant_id=r-export-status", nil)
+	require.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(ExportOrdersV1).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, ApplicationNDJson, rr.Header().Get("Content-Type"))
+
+	var ids []string
+	lines := bufio.NewScanner(rr.Body)
+	for lines.Scan() {
+		var row export.Row
+		require.NoError(t, json.Unmarshal(lines.Bytes(), &row))
+		ids = append(ids, row.ID)
+	}
+	assert.Contains(t, ids, placed.ID)
+	assert.NotContains(t, ids, cancelled.ID)
+}
+
+func TestExportOrdersV1InvalidParameters(t *testing.T) {
+	for _, query := range []string{"format=xlsx", "status=lost", "from=yesterday", "from=2026-03-02&to=2026-03-01"} {
+		req, err := http.NewRequest("GET", "/v1/admin/orders/export?"+query, nil)
+		require.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(ExportOrdersV1).ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code, query)
+	}
+}
+
+func TestParseExportTime(t *testing.T) {
+	from, err := parseExportTime("2026-03-01", false)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), from)
+
+	to, err := parseExportTime("2026-03-31", true)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC), to)
+
+	at, err := parseExportTime("2026-03-01T10:00:00+02:00", true)
+	require.NoError(t, err)
+	assert.True(t, at.Equal(time.Date(2026, 3, 1, 8, 0, 0, 0, time.UTC)))
+}
@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"weservefood/health"
+)
+
+// liveness is the body of the liveness probe
+type liveness struct {
+	Status string       `json:"status" example:"ok"`
+	Phase  health.Phase `json:"phase" example:"serving"`
+}
+
+// @Summary Liveness probe
+// @Description Report that the process is running; it never depends on other components
+// @Tags health
+// @Produce json
+// @Success 200 {object} handler.liveness
+// @Router /healthz [get]
+func Healthz(rw http.ResponseWriter, req *http.Request) {
+	writeProbe(rw, http.StatusOK, liveness{Status: health.StatusOK, Phase: health.CurrentPhase()})
+}
+
+// @Summary Readiness probe
+// @Description Report whether the server should receive traffic, with the health of the storage backend and background workers. It is unavailable while orders are replayed at startup and while draining on shutdown.
+// @Tags health
+// @Produce json
+// @Success 200 {object} health.Report
+// @Failure 503 {object} health.Report
+// @Router /readyz [get]
+func Readyz(rw http.ResponseWriter, req *http.Request) {
+	report := health.Ready(req.Context())
+	status := http.StatusOK
+	if report.Status != health.StatusOK {
+		status = http.StatusServiceUnavailable
+	}
+	writeProbe(rw, status, report)
+}
+
+func writeProbe(rw http.ResponseWriter, status int, body interface{}) {
+	rw.Header().Set(ContentTypeHeader, ApplicationJson)
+	rw.Header().Set("Cache-Control", "no-store")
+	rw.WriteHeader(status)
+	json.NewEncoder(rw).Encode(body)
+}
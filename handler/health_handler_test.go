@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"weservefood/health"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthz(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "/healthz", nil)
+	rr := httptest.NewRecorder()
+	Healthz(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, ApplicationJson, rr.Header().Get(ContentTypeHeader))
+	assert.JSONEq(t, `{"status":"ok","phase":"starting"}`, rr.Body.String())
+}
+
+func TestReadyz(t *testing.T) {
+	t.Cleanup(func() { health.SetPhase(health.PhaseStarting) })
+
+	for phase, want := range map[health.Phase]int{
+		health.PhaseStarting: http.StatusServiceUnavailable,
+		health.PhaseServing:  http.StatusOK,
+		health.PhaseDraining: http.StatusServiceUnavailable,
+	} {
+		health.SetPhase(phase)
+		req, _ := http.NewRequest(http.MethodGet, "/readyz", nil)
+		rr := httptest.NewRecorder()
+		Readyz(rr, req)
+
+		assert.Equal(t, want, rr.Code, phase)
+		var report health.Report
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &report))
+		assert.Equal(t, phase, report.Phase)
+	}
+}
@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+	"weservefood/bulkimport"
+)
+
+const ApplicationNDJson string = "application/x-ndjson"
+
+// importTrailer is the last line of an import response
+type importTrailer struct {
+	Summary bulkimport.Summary `json:"summary"`
+	Error   string             `json:"error,omitempty"`
+}
+
+// @Summary Import orders
+// @Description Stream orders from a JSONL or CSV document through the same validation as placing an order.
+// @Description The response is NDJSON: one result per record followed by a line holding the summary.
+// @Description Restart an interrupted import with resume_after set to the last reported line.
+// @Tags admin
+// @Accept plain
+// @Produce application/x-ndjson
+// @Param format query string false "jsonl or csv (defaults to the Content-Type)"
+// @Param dry_run query bool false "Validate without creating orders"
+// @Param resume_after query int false "Skip records on or before this line"
+// @Success 200 {object} bulkimport.LineResult "One line per record, then the summary"
+// @Failure 400 {string} string "Invalid import parameters"
+// @Failure 401 {string} string "a valid bearer token is required"
+// @Failure 413 {string} string "request body too large"
+// @Security BearerAuth
+// @Router /v1/admin/orders/import [post]
+func ImportOrdersV1(rw http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+
+	formatName := query.Get("format")
+	if formatName == "" {
+		formatName = req.Header.Get(ContentTypeHeader)
+	}
+	format, err := bulkimport.ParseFormat(formatName)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var opts bulkimport.Options
+	if value := query.Get("dry_run"); value != "" {
+		if opts.DryRun, err = strconv.ParseBool(value); err != nil {
+			http.Error(rw, "dry_run must be a boolean", http.StatusBadRequest)
+			return
+		}
+	}
+	if value := query.Get("resume_after"); value != "" {
+		if opts.ResumeAfter, err = strconv.Atoi(value); err != nil || opts.ResumeAfter < 0 {
+			http.Error(rw, "resume_after must be a line number", http.StatusBadRequest)
+			return
+		}
+	}
+
+	// results are written while the document is still being read, and a large
+	// document may outlast the server's read and write timeouts
+	controller := http.NewResponseController(rw)
+	controller.EnableFullDuplex()
+	controller.SetReadDeadline(time.Time{})
+	controller.SetWriteDeadline(time.Time{})
+
+	rw.Header().Set(ContentTypeHeader, ApplicationNDJson)
+	encoder := json.NewEncoder(rw)
+	flusher, _ := rw.(http.Flusher)
+
+	summary, err := bulkimport.Import(req.Context(), req.Body, format, opts, func(result bulkimport.LineResult) error {
+		if err := encoder.Encode(result); err != nil {
+			return err
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	})
+
+	trailer := importTrailer{Summary: summary}
+	if err != nil {
+		trailer.Error = err.Error()
+	}
+	encoder.Encode(trailer)
+}
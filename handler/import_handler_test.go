@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"weservefood/bulkimport"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImportOrdersV1(t *testing.T) {
+	document := "email,address\nimport-handler@example.com,1 Main St\n,2 Main St\n"
+	req, err := http.NewRequest("POST", "/v1/admin/orders/import", strings.NewReader(document))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "text/csv")
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(ImportOrdersV1).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, ApplicationNDJson, rr.Header().Get("Content-Type"))
+
+	lines := bufio.NewScanner(rr.Body)
+	var results []bulkimport.LineResult
+	var trailer importTrailer
+	for lines.Scan() {
+		if strings.Contains(lines.Text(), `"summary"`) {
+			require.NoError(t, json.Unmarshal(lines.Bytes(), &trailer))
+			continue
+		}
+		var result bulkimport.LineResult
+		require.NoError(t, json.Unmarshal(lines.Bytes(), &result))
+		results = append(results, result)
+	}
+
+	require.Len(t, results, 2)
+	assert.True(t, results[0].OK)
+	assert.False(t, results[1].OK)
+	assert.Equal(t, bulkimport.Summary{Succeeded: 1, Failed: 1, LastLine: 3}, trailer.Summary)
+	assert.Empty(t, trailer.Error)
+}
+
+func TestImportOrdersV1DryRunAndResume(t *testing.T) {
+	document := `{"email": "import-dry@example.com", "address": "1 Main St"}
+{"email": "import-dry@example.com", "address": "2 Main St"}`
+	req, err := http.NewRequest("POST", "/v1/admin/orders/import?format=jsonl&dry_run=true&resume_after=1", strings.NewReader(document))
+	require.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(ImportOrdersV1).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `{"line":2,"ok":true}`)
+	assert.Contains(t, rr.Body.String(), `"skipped":1`)
+	assert.Contains(t, rr.Body.String(), `"dry_run":true`)
+}
+
+func TestImportOrdersV1InvalidParams(t *testing.T) {
+	for _, target := range []string{
+		"/v1/admin/orders/import",
+		"/v1/admin/orders/import?format=xml",
+		"/v1/admin/orders/import?format=csv&dry_run=maybe",
+		"/v1/admin/orders/import?format=csv&resume_after=-1",
+	} {
+		req, err := http.NewRequest("POST", target, strings.NewReader(""))
+		require.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(ImportOrdersV1).ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code, target)
+	}
+}
@@ -27,25 +27,26 @@ func PingServer(rw http.ResponseWriter, req *http.Request) {
 // @Accept json
 // @Produce json
 // @Param order body models.Order true "Order Details"
-// @Success 200 {object} models.Order "Order Details"
+// @Success 200 {object} models.PlacedOrder "Order Details, with the delivery PIN"
 // @Failure 400 {string} string "Invalid Request Payload"
+// @Failure 413 {string} string "request body too large"
 // @Failure 500 {string} string "Internal Server Error"
 // @Router /place-order [post]
 func PlaceOrder(rw http.ResponseWriter, req *http.Request) {
 	var newOrder models.Order
 
 	if err := json.NewDecoder(req.Body).Decode(&newOrder); err != nil {
-		http.Error(rw, err.Error(), http.StatusBadRequest)
+		http.Error(rw, err.Error(), decodeStatus(err))
 		return
 	}
 
-	order, err := repository.CreateOrder(newOrder)
+	order, err := repository.CreateOrder(req.Context(), newOrder)
 	if err != nil {
-		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		http.Error(rw, err.Error(), errorStatus(err))
 		return
 	}
 
-	if err := json.NewEncoder(rw).Encode(order); err != nil {
+	if err := json.NewEncoder(rw).Encode(models.PlacedOrder{Order: order, DeliveryPIN: order.DeliveryPIN}); err != nil {
 		http.Error(rw, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -61,7 +62,7 @@ func PlaceOrder(rw http.ResponseWriter, req *http.Request) {
 func GetOrder(rw http.ResponseWriter, req *http.Request) {
 	email := req.URL.Query().Get("email")
 
-	order, err := repository.GetOrderByEmail(email)
+	order, err := repository.GetOrderByEmail(req.Context(), email)
 	if err != nil {
 		http.Error(rw, err.Error(), http.StatusNotFound)
 		return
@@ -84,7 +85,7 @@ func GetOrder(rw http.ResponseWriter, req *http.Request) {
 // @Router /get-all-orders [get]
 func GetAllOrders(rw http.ResponseWriter, req *http.Request) {
 
-	orders, err := repository.GetAllOrders()
+	orders, err := repository.GetAllOrders(req.Context())
 	if err != nil {
 		http.Error(rw, err.Error(), http.StatusNotFound)
 		return
@@ -103,16 +104,19 @@ func GetAllOrders(rw http.ResponseWriter, req *http.Request) {
 // @Produce json
 // @Param email path string true "User Email"
 // @Param id path string true "Order ID"
+// @Param reason query string true "Cancellation reason"
 // @Success 200 {string} string "Order Cancelled Successfully"
 // @Router /cancel-order/{email}/{id} [delete]
 func CancelOrder(rw http.ResponseWriter, req *http.Request) {
 	vars := mux.Vars(req)
 	email := vars["email"]
 	orderID := vars["id"]
+	reason := req.URL.Query().Get("reason")
 
-	message, err := repository.CancelOrder(email, orderID)
+	message, err := repository.CancelOrder(req.Context(), orderID, models.OrderCancellation{Email: email, Reason: reason})
 	if err != nil {
-		http.Error(rw, err.Error(), http.StatusNotFound)
+		http.Error(rw, err.Error(), errorStatus(err))
+		return
 	}
 
 	rw.Header().Set(ContentTypeHeader, ApplicationJson)
@@ -132,6 +136,7 @@ func CancelOrder(rw http.ResponseWriter, req *http.Request) {
 // @Param new_address query string true "New Address"
 // @Success 200 {object} models.Order
 // @Failure 400 {string} string "unable to update new address"
+// @Failure 413 {string} string "request body too large"
 // @Router /update-address/{email}/{id} [put]
 func UpdateAddress(rw http.ResponseWriter, req *http.Request) {
 	vars := mux.Vars(req)
@@ -143,11 +148,11 @@ func UpdateAddress(rw http.ResponseWriter, req *http.Request) {
 	}
 
 	if err := json.NewDecoder(req.Body).Decode(&requestData); err != nil {
-		http.Error(rw, "unable to update new address", http.StatusBadRequest)
+		http.Error(rw, "unable to update new address", decodeStatus(err))
 		return
 	}
 
-	updatedOrder, err := repository.UpdateAddress(email, orderID, requestData.NewAddress)
+	updatedOrder, err := repository.UpdateAddress(req.Context(), orderID, models.OrderPatch{Email: email, Address: requestData.NewAddress})
 	if err != nil {
 		http.Error(rw, err.Error(), http.StatusBadRequest)
 		return
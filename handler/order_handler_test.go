@@ -2,6 +2,7 @@ package handler
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -54,7 +55,7 @@ func TestGetOrder(t *testing.T) {
 		Email:   "test@example.com",
 		Address: "123 Test St",
 	}
-	_, _ = repository.CreateOrder(order)
+	_, _ = repository.CreateOrder(context.Background(), order)
 
 	req, err := http.NewRequest("GET", "/get-order?email=test@example.com", nil)
 	assert.NoError(t, err)
@@ -77,7 +78,7 @@ func TestGetAllOrders(t *testing.T) {
 		Email:   "test@example.com",
 		Address: "123 Test St",
 	}
-	_, _ = repository.CreateOrder(order)
+	_, _ = repository.CreateOrder(context.Background(), order)
 
 	req, err := http.NewRequest("GET", "/get-all-orders", nil)
 	assert.NoError(t, err)
@@ -99,9 +100,9 @@ func TestCancelOrder(t *testing.T) {
 		Email:   "test@example.com",
 		Address: "123 Test St",
 	}
-	createdOrder, _ := repository.CreateOrder(order)
+	createdOrder, _ := repository.CreateOrder(context.Background(), order)
 
-	req, err := http.NewRequest("DELETE", "/cancel-order/test@example.com/"+createdOrder.ID, nil)
+	req, err := http.NewRequest("DELETE", "/cancel-order/test@example.com/"+createdOrder.ID+"?reason=changed+my+mind", nil)
 	assert.NoError(t, err)
 
 	rr := httptest.NewRecorder()
@@ -117,12 +118,37 @@ func TestCancelOrder(t *testing.T) {
 	assert.Equal(t, createdOrder.ID+" Order Cancelled Successfully", responseMessage)
 }
 
+func TestCancelOrderErrors(t *testing.T) {
+	createdOrder, _ := repository.CreateOrder(context.Background(), models.Order{Email: "test@example.com", Address: "123 Test St"})
+	router := mux.NewRouter()
+	router.HandleFunc("/cancel-order/{email}/{id}", CancelOrder).Methods("DELETE")
+
+	tests := map[string]struct {
+		path   string
+		status int
+	}{
+		"no reason": {"/cancel-order/test@example.com/" + createdOrder.ID, http.StatusBadRequest},
+		"not found": {"/cancel-order/test@example.com/nonexistentID?reason=changed+my+mind", http.StatusNotFound},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			req, err := http.NewRequest("DELETE", test.path, nil)
+			assert.NoError(t, err)
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, test.status, rr.Code)
+			assert.NotContains(t, rr.Body.String(), "Order Cancelled Successfully")
+		})
+	}
+}
+
 func TestUpdateAddress(t *testing.T) {
 	order := models.Order{
 		Email:   "test@example.com",
 		Address: "123 Test St",
 	}
-	createdOrder, _ := repository.CreateOrder(order)
+	createdOrder, _ := repository.CreateOrder(context.Background(), order)
 
 	updateData := map[string]string{"new_address": "456 New St"}
 	updateJSON, _ := json.Marshal(updateData)
@@ -0,0 +1,258 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"weservefood/blob"
+	"weservefood/models"
+	"weservefood/payments"
+	"weservefood/repository"
+
+	"github.com/gorilla/mux"
+)
+
+// errorStatus maps repository errors to the HTTP status used by the /v1 routes
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, repository.ErrOrderNotFound), errors.Is(err, repository.ErrCourierNotFound),
+		errors.Is(err, repository.ErrProofNotFound), errors.Is(err, blob.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, repository.ErrEmailMismatch), errors.Is(err, repository.ErrNotAssigned),
+		errors.Is(err, repository.ErrWrongPIN):
+		return http.StatusForbidden
+	case errors.Is(err, repository.ErrRestaurantNotFound), errors.Is(err, repository.ErrInvalidOrder),
+		errors.Is(err, payments.ErrInvalidPaymentMethod), errors.Is(err, repository.ErrOutsideDeliveryZone),
+		errors.Is(err, repository.ErrBelowMinimumOrder), errors.Is(err, repository.ErrInvalidPing),
+		errors.Is(err, repository.ErrInvalidProof), errors.Is(err, repository.ErrProofRequired):
+		return http.StatusBadRequest
+	case errors.Is(err, payments.ErrDeclined):
+		return http.StatusPaymentRequired
+	case errors.Is(err, repository.ErrOrderCancelled), errors.Is(err, repository.ErrCourierFull),
+		errors.Is(err, repository.ErrOrderConfirmed), errors.Is(err, repository.ErrPaymentInProgress),
+		errors.Is(err, repository.ErrStatusChange), errors.Is(err, payments.ErrInvalidTransition),
+		errors.Is(err, repository.ErrCancellationRejected), errors.Is(err, repository.ErrAddressChangeRejected),
+		errors.Is(err, repository.ErrPriceChangeUnconfirmed), errors.Is(err, repository.ErrPINLocked):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// decodeStatus is the status for a request body that could not be decoded:
+// 413 when it exceeded the route's size limit, otherwise 400
+func decodeStatus(err error) int {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
+
+// @Summary Create an order
+// @Description Create a new food order. The address may be given as a structured delivery_address instead, which is geocoded from its postal code; a location it carries must be near the postal code and refines it.
+// @Tags v1
+// @Accept json
+// @Produce json
+// @Param order body models.Order true "Order Details"
+// @Success 201 {object} models.PlacedOrder "Order Details, with the delivery PIN"
+// @Failure 400 {string} string "Invalid Request Payload"
+// @Failure 413 {string} string "request body too large"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /v1/orders [post]
+func CreateOrderV1(rw http.ResponseWriter, req *http.Request) {
+	var newOrder models.Order
+
+	if err := json.NewDecoder(req.Body).Decode(&newOrder); err != nil {
+		http.Error(rw, err.Error(), decodeStatus(err))
+		return
+	}
+
+	order, err := repository.CreateOrder(req.Context(), newOrder)
+	if err != nil {
+		http.Error(rw, err.Error(), errorStatus(err))
+		return
+	}
+
+	rw.Header().Set(ContentTypeHeader, ApplicationJson)
+	rw.Header().Set("Location", "/v1/orders/"+order.ID)
+	rw.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(rw).Encode(models.PlacedOrder{Order: order, DeliveryPIN: order.DeliveryPIN}); err != nil {
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// @Summary List orders
+// @Description Retrieve all active orders, optionally filtered by the customer email
+// @Tags v1
+// @Produce json
+// @Param email query string false "User Email"
+// @Success 200 {array} models.Order
+// @Router /v1/orders [get]
+func ListOrdersV1(rw http.ResponseWriter, req *http.Request) {
+	var (
+		orders []models.Order
+		err    error
+	)
+
+	if email := req.URL.Query().Get("email"); email != "" {
+		orders, err = repository.GetOrderByEmail(req.Context(), email)
+	} else {
+		orders, err = repository.GetAllOrders(req.Context())
+	}
+	// the repository reports an empty result as an error; a collection is simply empty
+	if err != nil {
+		orders = []models.Order{}
+	}
+
+	rw.Header().Set(ContentTypeHeader, ApplicationJson)
+	if err := json.NewEncoder(rw).Encode(orders); err != nil {
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// @Summary Get an order
+// @Description Retrieve a single order by its ID
+// @Tags v1
+// @Produce json
+// @Param id path string true "Order ID"
+// @Success 200 {object} models.Order
+// @Failure 404 {string} string "order not found"
+// @Router /v1/orders/{id} [get]
+func GetOrderV1(rw http.ResponseWriter, req *http.Request) {
+	order, err := repository.GetOrderByID(req.Context(), mux.Vars(req)["id"])
+	if err != nil {
+		http.Error(rw, err.Error(), errorStatus(err))
+		return
+	}
+
+	rw.Header().Set(ContentTypeHeader, ApplicationJson)
+	if err := json.NewEncoder(rw).Encode(order); err != nil {
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// @Summary Update an order
+// @Description Update the delivery address of an order until it is out for delivery. The address must be in a delivery zone of the restaurant; the delivery fee and due time follow the zone, and a change of total is only applied when confirm_total_cents repeats the new total. A structured delivery_address is geocoded from its postal code, refined by a location near it, and replaces address.
+// @Tags v1
+// @Accept json
+// @Produce json
+// @Param id path string true "Order ID"
+// @Param patch body models.OrderPatch true "Order changes"
+// @Success 200 {object} models.Order
+// @Failure 400 {string} string "address is outside the delivery zone"
+// @Failure 413 {string} string "request body too large"
+// @Failure 403 {string} string "email does not match"
+// @Failure 404 {string} string "order not found"
+// @Failure 409 {string} string "address change alters the price: the total becomes 1498 cents instead of 1299; confirm the new total to go ahead"
+// @Router /v1/orders/{id} [patch]
+func PatchOrderV1(rw http.ResponseWriter, req *http.Request) {
+	var patch models.OrderPatch
+
+	if err := json.NewDecoder(req.Body).Decode(&patch); err != nil {
+		http.Error(rw, err.Error(), decodeStatus(err))
+		return
+	}
+	if patch.Email == "" || (patch.Address == "" && patch.DeliveryAddress == nil) {
+		http.Error(rw, "email and address or delivery_address are required", http.StatusBadRequest)
+		return
+	}
+
+	updatedOrder, err := repository.UpdateAddress(req.Context(), mux.Vars(req)["id"], patch)
+	if err != nil {
+		http.Error(rw, err.Error(), errorStatus(err))
+		return
+	}
+
+	rw.Header().Set(ContentTypeHeader, ApplicationJson)
+	if err := json.NewEncoder(rw).Encode(updatedOrder); err != nil {
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// @Summary Cancel an order
+// @Description Cancel an order as the customer who placed it, by order ID and the email it was placed with, giving a reason. The cancellation policy decides which statuses customers may cancel, and until how long before delivery.
+// @Tags v1
+// @Accept json
+// @Produce json
+// @Param id path string true "Order ID"
+// @Param cancellation body models.OrderCancellation true "Cancellation details"
+// @Success 200 {string} string "Order Cancelled Successfully"
+// @Failure 400 {string} string "Invalid Request Payload"
+// @Failure 413 {string} string "request body too large"
+// @Failure 404 {string} string "order not found"
+// @Failure 409 {string} string "cancellation rejected: customer cannot cancel an order that is out_for_delivery"
+// @Router /v1/orders/{id}/cancel [post]
+func CancelOrderV1(rw http.ResponseWriter, req *http.Request) {
+	cancelOrderAs(rw, req, mux.Vars(req)["id"], models.InitiatorCustomer, "")
+}
+
+// @Summary Cancel an order as support
+// @Description Cancel any order by order ID, giving a reason; the email is not needed. The cancellation policy decides which statuses support may cancel, and until how long before delivery.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path string true "Order ID"
+// @Param cancellation body models.OrderCancellation true "Cancellation details"
+// @Success 200 {string} string "Order Cancelled Successfully"
+// @Failure 400 {string} string "Invalid Request Payload"
+// @Failure 401 {string} string "a valid bearer token is required"
+// @Failure 404 {string} string "order not found"
+// @Failure 409 {string} string "cancellation rejected: support cannot cancel an order that is delivered"
+// @Failure 413 {string} string "request body too large"
+// @Security BearerAuth
+// @Router /v1/admin/orders/{id}/cancel [post]
+func CancelOrderAsSupportV1(rw http.ResponseWriter, req *http.Request) {
+	cancelOrderAs(rw, req, mux.Vars(req)["id"], models.InitiatorSupport, "")
+}
+
+// @Summary Cancel an order as the restaurant
+// @Description Cancel an order placed with the restaurant, giving a reason; the email is not needed. Only the restaurant's own token is accepted. The cancellation policy decides which statuses restaurants may cancel, and until how long before delivery.
+// @Tags v1
+// @Accept json
+// @Produce json
+// @Param id path string true "Restaurant ID"
+// @Param orderId path string true "Order ID"
+// @Param cancellation body models.OrderCancellation true "Cancellation details"
+// @Success 200 {string} string "Order Cancelled Successfully"
+// @Failure 400 {string} string "Invalid Request Payload"
+// @Failure 401 {string} string "a valid bearer token is required"
+// @Failure 403 {string} string "the token belongs to another restaurant"
+// @Failure 404 {string} string "order not found"
+// @Failure 409 {string} string "cancellation rejected: restaurant cannot cancel an order that is out_for_delivery"
+// @Failure 413 {string} string "request body too large"
+// @Security BearerAuth
+// @Router /v1/restaurants/{id}/orders/{orderId}/cancel [post]
+func CancelOrderAsRestaurantV1(rw http.ResponseWriter, req *http.Request) {
+	vars := mux.Vars(req)
+	cancelOrderAs(rw, req, vars["orderId"], models.InitiatorRestaurant, vars["id"])
+}
+
+// cancelOrderAs cancels the order on behalf of the initiator the route stands
+// for; the body only gives the reason, and the customer's email
+func cancelOrderAs(rw http.ResponseWriter, req *http.Request, orderID string, initiator models.CancelInitiator, restaurantID string) {
+	var cancellation models.OrderCancellation
+
+	if err := json.NewDecoder(req.Body).Decode(&cancellation); err != nil {
+		http.Error(rw, err.Error(), decodeStatus(err))
+		return
+	}
+	cancellation.Initiator = initiator
+	cancellation.RestaurantID = restaurantID
+
+	message, err := repository.CancelOrder(req.Context(), orderID, cancellation)
+	if err != nil {
+		http.Error(rw, err.Error(), errorStatus(err))
+		return
+	}
+
+	rw.Header().Set(ContentTypeHeader, ApplicationJson)
+	if err := json.NewEncoder(rw).Encode(message); err != nil {
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
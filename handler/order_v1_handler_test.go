@@ -0,0 +1,305 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"weservefood/geo"
+	"weservefood/models"
+	"weservefood/repository"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func newV1Router() *mux.Router {
+	router := mux.NewRouter()
+	router.HandleFunc("/v1/orders", CreateOrderV1).Methods("POST")
+	router.HandleFunc("/v1/orders", ListOrdersV1).Methods("GET")
+	router.HandleFunc("/v1/orders/{id}", GetOrderV1).Methods("GET")
+	router.HandleFunc("/v1/orders/{id}", PatchOrderV1).Methods("PATCH")
+	router.HandleFunc("/v1/orders/{id}/cancel", CancelOrderV1).Methods("POST")
+	router.HandleFunc("/v1/admin/orders/{id}/cancel", CancelOrderAsSupportV1).Methods("POST")
+	router.HandleFunc("/v1/restaurants/{id}/orders/{orderId}/cancel", CancelOrderAsRestaurantV1).Methods("POST")
+	return router
+}
+
+func TestCreateOrderV1(t *testing.T) {
+	order := models.Order{
+		Email:   "v1@example.com",
+		Address: "123 Test St",
+	}
+	orderJSON, _ := json.Marshal(order)
+
+	req, err := http.NewRequest("POST", "/v1/orders", bytes.NewBuffer(orderJSON))
+	assert.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	newV1Router().ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusCreated, rr.Code)
+
+	var createdOrder models.PlacedOrder
+	err = json.NewDecoder(rr.Body).Decode(&createdOrder)
+	assert.NoError(t, err)
+	assert.Equal(t, order.Email, createdOrder.Email)
+	assert.Equal(t, "/v1/orders/"+createdOrder.ID, rr.Header().Get("Location"))
+	assert.Regexp(t, `^\d{4}$`, createdOrder.DeliveryPIN, "the customer placing the order is told the PIN")
+
+	req, err = http.NewRequest("GET", "/v1/orders/"+createdOrder.ID, nil)
+	assert.NoError(t, err)
+	rr = httptest.NewRecorder()
+	newV1Router().ServeHTTP(rr, req)
+	assert.NotContains(t, rr.Body.String(), "delivery_pin")
+	assert.NotContains(t, rr.Body.String(), createdOrder.DeliveryPIN)
+}
+
+func TestCreateOrderV1BodyTooLarge(t *testing.T) {
+	orderJSON, _ := json.Marshal(models.Order{Email: "large@example.com", Address: strings.Repeat("1 Main St ", 100)})
+
+	req, err := http.NewRequest("POST", "/v1/orders", bytes.NewBuffer(orderJSON))
+	assert.NoError(t, err)
+	rr := httptest.NewRecorder()
+	req.Body = http.MaxBytesReader(rr, req.Body, 64)
+
+	newV1Router().ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+}
+
+func TestListOrdersV1(t *testing.T) {
+	createdOrder, _ := repository.CreateOrder(context.Background(), models.Order{Email: "list@example.com", Address: "123 Test St"})
+
+	req, err := http.NewRequest("GET", "/v1/orders?email=list@example.com", nil)
+	assert.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	newV1Router().ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var orders []models.Order
+	err = json.NewDecoder(rr.Body).Decode(&orders)
+	assert.NoError(t, err)
+	// only the customer placing the order is told the PIN
+	createdOrder.DeliveryPIN = ""
+	assert.Contains(t, orders, createdOrder)
+}
+
+func TestListOrdersV1Empty(t *testing.T) {
+	req, err := http.NewRequest("GET", "/v1/orders?email=nobody@example.com", nil)
+	assert.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	newV1Router().ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, "[]", rr.Body.String())
+}
+
+func TestGetOrderV1(t *testing.T) {
+	createdOrder, _ := repository.CreateOrder(context.Background(), models.Order{Email: "v1@example.com", Address: "123 Test St"})
+
+	req, err := http.NewRequest("GET", "/v1/orders/"+createdOrder.ID, nil)
+	assert.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	newV1Router().ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var order models.Order
+	err = json.NewDecoder(rr.Body).Decode(&order)
+	assert.NoError(t, err)
+	// only the customer placing the order is told the PIN
+	createdOrder.DeliveryPIN = ""
+	assert.Equal(t, createdOrder, order)
+}
+
+func TestGetOrderV1NotFound(t *testing.T) {
+	req, err := http.NewRequest("GET", "/v1/orders/nonexistentID", nil)
+	assert.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	newV1Router().ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+func TestPatchOrderV1(t *testing.T) {
+	createdOrder, _ := repository.CreateOrder(context.Background(), models.Order{Email: "v1@example.com", Address: "123 Test St"})
+
+	patchJSON, _ := json.Marshal(models.OrderPatch{Email: "v1@example.com", Address: "456 New St"})
+	req, err := http.NewRequest("PATCH", "/v1/orders/"+createdOrder.ID, bytes.NewBuffer(patchJSON))
+	assert.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	newV1Router().ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var updatedOrder models.Order
+	err = json.NewDecoder(rr.Body).Decode(&updatedOrder)
+	assert.NoError(t, err)
+	assert.Equal(t, "456 New St", updatedOrder.Address)
+}
+
+func TestPatchOrderV1EmailMismatch(t *testing.T) {
+	createdOrder, _ := repository.CreateOrder(context.Background(), models.Order{Email: "v1@example.com", Address: "123 Test St"})
+
+	patchJSON, _ := json.Marshal(models.OrderPatch{Email: "wrong@example.com", Address: "456 New St"})
+	req, err := http.NewRequest("PATCH", "/v1/orders/"+createdOrder.ID, bytes.NewBuffer(patchJSON))
+	assert.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	newV1Router().ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+}
+
+func TestCancelOrderV1(t *testing.T) {
+	createdOrder, _ := repository.CreateOrder(context.Background(), models.Order{Email: "v1@example.com", Address: "123 Test St"})
+
+	cancelJSON, _ := json.Marshal(models.OrderCancellation{Email: "v1@example.com", Reason: "changed my mind"})
+	req, err := http.NewRequest("POST", "/v1/orders/"+createdOrder.ID+"/cancel", bytes.NewBuffer(cancelJSON))
+	assert.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	newV1Router().ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	cancelled, err := repository.GetOrderByID(context.Background(), createdOrder.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.StatusCancelled, cancelled.Status)
+
+	req, err = http.NewRequest("POST", "/v1/orders/"+createdOrder.ID+"/cancel", bytes.NewBuffer(cancelJSON))
+	assert.NoError(t, err)
+
+	rr = httptest.NewRecorder()
+	newV1Router().ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusConflict, rr.Code)
+}
+
+func TestCancelOrderV1NotFound(t *testing.T) {
+	cancelJSON, _ := json.Marshal(models.OrderCancellation{Email: "v1@example.com", Reason: "changed my mind"})
+	req, err := http.NewRequest("POST", "/v1/orders/nonexistentID/cancel", bytes.NewBuffer(cancelJSON))
+	assert.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	newV1Router().ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+func TestCancelOrderV1Rejected(t *testing.T) {
+	repository.AddRestaurant(models.Restaurant{ID: "r-cancel-v1", Name: "Cancel Cafe", Menu: []models.MenuItem{{Name: "Tea", PriceCents: 300}}})
+	createdOrder, _ := repository.CreateOrder(context.Background(), models.Order{Email: "v1@example.com", Address: "123 Test St", RestaurantID: "r-cancel-v1", Items: []string{"Tea"}})
+	_, err := repository.ConfirmOrder(context.Background(), "v1@example.com", createdOrder.ID, "tok_visa")
+	assert.NoError(t, err)
+	for _, status := range []models.OrderStatus{models.StatusPreparing, models.StatusOutForDelivery} {
+		_, err = repository.AdvanceOrder(context.Background(), createdOrder.ID, status)
+		assert.NoError(t, err)
+	}
+
+	cancel := func(path string, cancellation models.OrderCancellation) *httptest.ResponseRecorder {
+		cancelJSON, _ := json.Marshal(cancellation)
+		req, err := http.NewRequest("POST", path, bytes.NewBuffer(cancelJSON))
+		assert.NoError(t, err)
+		rr := httptest.NewRecorder()
+		newV1Router().ServeHTTP(rr, req)
+		return rr
+	}
+
+	customerPath := "/v1/orders/" + createdOrder.ID + "/cancel"
+	rr := cancel(customerPath, models.OrderCancellation{Email: "v1@example.com"})
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "a cancellation reason is required")
+
+	rr = cancel(customerPath, models.OrderCancellation{Email: "v1@example.com", Reason: "too slow"})
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	assert.Equal(t, "cancellation rejected: customer cannot cancel an order that is out_for_delivery\n", rr.Body.String())
+
+	// the customer route ignores an initiator in the body
+	req, err := http.NewRequest("POST", customerPath, strings.NewReader(`{"initiator":"support","reason":"customer called support"}`))
+	assert.NoError(t, err)
+	rr = httptest.NewRecorder()
+	newV1Router().ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+
+	rr = cancel("/v1/restaurants/r-other/orders/"+createdOrder.ID+"/cancel", models.OrderCancellation{Reason: "out of tea"})
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	rr = cancel("/v1/restaurants/r-cancel-v1/orders/"+createdOrder.ID+"/cancel", models.OrderCancellation{Reason: "out of tea"})
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	assert.Contains(t, rr.Body.String(), "restaurant cannot cancel")
+
+	rr = cancel("/v1/admin/orders/"+createdOrder.ID+"/cancel", models.OrderCancellation{Reason: "customer called support"})
+	assert.Equal(t, http.StatusOK, rr.Code)
+}
+
+func TestPatchOrderV1PriceChange(t *testing.T) {
+	repository.AddRestaurant(models.Restaurant{ID: "r-patch-zone", Name: "Patch Pasta", Menu: []models.MenuItem{{Name: "Penne", PriceCents: 900}}, Zones: []models.DeliveryZone{
+		{Name: "near", Areas: []string{"10115"}, DeliveryFeeCents: 100, DeliveryMinutes: 20},
+		{Name: "far", Areas: []string{"12049"}, DeliveryFeeCents: 500, DeliveryMinutes: 45},
+	}})
+	createdOrder, err := repository.CreateOrder(context.Background(), models.Order{Email: "v1@example.com", Address: "1 Main St, 10115", RestaurantID: "r-patch-zone", Items: []string{"Penne"}})
+	assert.NoError(t, err)
+
+	patch := func(orderPatch models.OrderPatch) *httptest.ResponseRecorder {
+		patchJSON, _ := json.Marshal(orderPatch)
+		req, err := http.NewRequest("PATCH", "/v1/orders/"+createdOrder.ID, bytes.NewBuffer(patchJSON))
+		assert.NoError(t, err)
+		rr := httptest.NewRecorder()
+		newV1Router().ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := patch(models.OrderPatch{Email: "v1@example.com", Address: "2 Main St, 99999"})
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	rr = patch(models.OrderPatch{Email: "v1@example.com", Address: "2 Main St, 12049"})
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	assert.Contains(t, rr.Body.String(), "the total becomes 1400 cents")
+
+	rr = patch(models.OrderPatch{Email: "v1@example.com", Address: "2 Main St, 12049", ConfirmTotalCents: 1400})
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var updatedOrder models.Order
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&updatedOrder))
+	assert.Equal(t, 1400, updatedOrder.Price.TotalCents)
+	assert.Len(t, updatedOrder.AddressHistory, 1)
+}
+
+func TestDeliveryAddressV1(t *testing.T) {
+	previous := repository.Geocoder
+	repository.Geocoder = geo.NewGazetteer(geo.Place{Country: "DE", PostalCode: "10119", City: "Berlin", Location: models.LatLng{Lat: 52.5300, Lng: 13.4050}})
+	t.Cleanup(func() { repository.Geocoder = previous })
+
+	body := `{"email":"structured-v1@example.com","delivery_address":{"street":"Torstraße 1","city":"Berlin","postal_code":"10119","country":"DE","location":{"lat":52.53,"lng":13.40},"delivery_instructions":"ring twice"}}`
+	req, _ := http.NewRequest("POST", "/v1/orders", strings.NewReader(body))
+	rr := httptest.NewRecorder()
+	newV1Router().ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+
+	var createdOrder models.Order
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&createdOrder))
+	assert.Equal(t, "Torstraße 1, 10119 Berlin, DE", createdOrder.Address)
+	assert.Equal(t, "ring twice", createdOrder.DeliveryAddress.Instructions)
+
+	// nothing locates this postal code
+	patch := `{"email":"structured-v1@example.com","delivery_address":{"street":"Nowhere 1","city":"Berlin","postal_code":"99999","country":"DE"}}`
+	req, _ = http.NewRequest("PATCH", "/v1/orders/"+createdOrder.ID, strings.NewReader(patch))
+	rr = httptest.NewRecorder()
+	newV1Router().ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "address not found")
+
+	req, _ = http.NewRequest("PATCH", "/v1/orders/"+createdOrder.ID, strings.NewReader(`{"email":"structured-v1@example.com"}`))
+	rr = httptest.NewRecorder()
+	newV1Router().ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"weservefood/models"
+	"weservefood/repository"
+
+	"github.com/gorilla/mux"
+)
+
+// @Summary Pay for an order
+// @Description Authorize the order total on a payment method and confirm the order. A declined payment leaves the order placed, with the reason on its payment, so it can be retried.
+// @Tags v1
+// @Accept json
+// @Produce json
+// @Param id path string true "Order ID"
+// @Param payment body models.PaymentRequest true "Payment details"
+// @Success 200 {object} models.Order
+// @Failure 400 {string} string "Invalid Request Payload"
+// @Failure 402 {string} string "payment declined"
+// @Failure 403 {string} string "email does not match"
+// @Failure 404 {string} string "order not found"
+// @Failure 409 {string} string "order is already confirmed"
+// @Failure 413 {string} string "request body too large"
+// @Router /v1/orders/{id}/payment [post]
+func PayOrderV1(rw http.ResponseWriter, req *http.Request) {
+	var payment models.PaymentRequest
+
+	if err := json.NewDecoder(req.Body).Decode(&payment); err != nil {
+		http.Error(rw, err.Error(), decodeStatus(err))
+		return
+	}
+	if payment.Email == "" || payment.PaymentMethod == "" {
+		http.Error(rw, "email and payment_method are required", http.StatusBadRequest)
+		return
+	}
+
+	order, err := repository.ConfirmOrder(req.Context(), payment.Email, mux.Vars(req)["id"], payment.PaymentMethod)
+	if err != nil {
+		http.Error(rw, err.Error(), errorStatus(err))
+		return
+	}
+
+	rw.Header().Set(ContentTypeHeader, ApplicationJson)
+	if err := json.NewEncoder(rw).Encode(order); err != nil {
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
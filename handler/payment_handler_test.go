@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"weservefood/models"
+	"weservefood/payments"
+	"weservefood/repository"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func payOrder(t *testing.T, orderID string, payment models.PaymentRequest) *httptest.ResponseRecorder {
+	t.Helper()
+	router := mux.NewRouter()
+	router.HandleFunc("/v1/orders/{id}/payment", PayOrderV1).Methods("POST")
+
+	paymentJSON, _ := json.Marshal(payment)
+	req, err := http.NewRequest("POST", "/v1/orders/"+orderID+"/payment", bytes.NewBuffer(paymentJSON))
+	require.NoError(t, err)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestPayOrderV1(t *testing.T) {
+	repository.AddRestaurant(models.Restaurant{ID: "r-pay-handler", Name: "Pay Handler Diner", Menu: []models.MenuItem{{Name: "Pie", PriceCents: 700}}})
+	createdOrder, err := repository.CreateOrder(context.Background(), models.Order{Email: "pay@example.com", Address: "1 Pay St", RestaurantID: "r-pay-handler", Items: []string{"Pie"}})
+	require.NoError(t, err)
+
+	rr := payOrder(t, createdOrder.ID, models.PaymentRequest{Email: "pay@example.com", PaymentMethod: payments.MethodDeclined})
+	assert.Equal(t, http.StatusPaymentRequired, rr.Code)
+
+	rr = payOrder(t, createdOrder.ID, models.PaymentRequest{Email: "pay@example.com", PaymentMethod: "tok_unknown"})
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	rr = payOrder(t, createdOrder.ID, models.PaymentRequest{Email: "wrong@example.com", PaymentMethod: payments.MethodVisa})
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+
+	rr = payOrder(t, createdOrder.ID, models.PaymentRequest{Email: "pay@example.com", PaymentMethod: payments.MethodVisa})
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var order models.Order
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&order))
+	assert.Equal(t, models.StatusConfirmed, order.Status)
+	assert.Equal(t, models.PaymentAuthorized, order.Payment.Status)
+
+	rr = payOrder(t, createdOrder.ID, models.PaymentRequest{Email: "pay@example.com", PaymentMethod: payments.MethodVisa})
+	assert.Equal(t, http.StatusConflict, rr.Code)
+}
+
+func TestPayOrderV1Invalid(t *testing.T) {
+	rr := payOrder(t, "nonexistentID", models.PaymentRequest{Email: "pay@example.com", PaymentMethod: payments.MethodVisa})
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+
+	rr = payOrder(t, "nonexistentID", models.PaymentRequest{Email: "pay@example.com"})
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"weservefood/models"
+	"weservefood/repository"
+
+	"github.com/gorilla/mux"
+)
+
+// @Summary Report courier locations
+// @Description Record GPS pings from a courier's app against every order the courier has out for delivery. Pings buffered while offline may be sent together and in any order; pings older than the last one recorded for an order are dropped. The latest position is shown on the order as courier_position. Only the courier's own token is accepted.
+// @Tags v1
+// @Accept json
+// @Produce json
+// @Param id path string true "Courier ID"
+// @Param pings body models.CourierPings true "GPS pings"
+// @Success 200 {object} models.PingReceipt "orders the pings were recorded against"
+// @Failure 400 {string} string "invalid location ping: ping 0 is recorded in the future"
+// @Failure 401 {string} string "a valid bearer token is required"
+// @Failure 403 {string} string "the token belongs to another courier"
+// @Failure 404 {string} string "courier not found"
+// @Security BearerAuth
+// @Router /v1/couriers/{id}/pings [post]
+func RecordPingsV1(rw http.ResponseWriter, req *http.Request) {
+	var pings models.CourierPings
+
+	if err := json.NewDecoder(req.Body).Decode(&pings); err != nil {
+		http.Error(rw, err.Error(), decodeStatus(err))
+		return
+	}
+
+	orderIDs, err := repository.RecordPings(req.Context(), mux.Vars(req)["id"], pings.Pings)
+	if err != nil {
+		http.Error(rw, err.Error(), errorStatus(err))
+		return
+	}
+
+	rw.Header().Set(ContentTypeHeader, ApplicationJson)
+	if err := json.NewEncoder(rw).Encode(models.PingReceipt{Orders: orderIDs}); err != nil {
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// @Summary Replay a delivery route
+// @Description Retrieve the pings the courier reported while the order was out for delivery, oldest first, to settle disputes about the delivery. Long rides are thinned to tracking.trail_length pings, keeping the first and the latest.
+// @Tags admin
+// @Produce json
+// @Param id path string true "Order ID"
+// @Success 200 {object} models.DeliveryTrail
+// @Failure 401 {string} string "a valid bearer token is required"
+// @Failure 404 {string} string "order not found"
+// @Security BearerAuth
+// @Router /v1/admin/orders/{id}/trail [get]
+func GetTrailV1(rw http.ResponseWriter, req *http.Request) {
+	trail, err := repository.GetTrail(req.Context(), mux.Vars(req)["id"])
+	if err != nil {
+		http.Error(rw, err.Error(), errorStatus(err))
+		return
+	}
+
+	rw.Header().Set(ContentTypeHeader, ApplicationJson)
+	if err := json.NewEncoder(rw).Encode(trail); err != nil {
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"weservefood/config"
+	"weservefood/middleware"
+	"weservefood/models"
+	"weservefood/repository"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTrackingRouter() *mux.Router {
+	router := mux.NewRouter()
+	router.Use(middleware.AuthMiddleware(config.Auth{CourierTokens: []string{"c-pings=pings-secret", "c-nobody=nobody-secret"}}))
+	router.Handle("/v1/couriers/{id}/pings", middleware.RequireRouteCourier(http.HandlerFunc(RecordPingsV1))).Methods("POST")
+	router.HandleFunc("/v1/admin/orders/{id}/trail", GetTrailV1).Methods("GET")
+	return router
+}
+
+func TestRecordPingsV1(t *testing.T) {
+	repository.AddCourier(models.Courier{ID: "c-pings", Name: "Pia", Available: true})
+
+	ping := `{"pings":[{"location":{"lat":52.5,"lng":13.4},"recorded_at":"2024-05-01T12:00:00Z"}]}`
+	tests := []struct {
+		name   string
+		path   string
+		token  string
+		body   string
+		status int
+	}{
+		{"no delivery under way", "/v1/couriers/c-pings/pings", "pings-secret", ping, http.StatusOK},
+		{"anonymous", "/v1/couriers/c-pings/pings", "", ping, http.StatusUnauthorized},
+		{"another courier", "/v1/couriers/c-pings/pings", "nobody-secret", ping, http.StatusForbidden},
+		{"invalid location", "/v1/couriers/c-pings/pings", "pings-secret", `{"pings":[{"location":{"lat":91,"lng":13.4},"recorded_at":"2024-05-01T12:00:00Z"}]}`, http.StatusBadRequest},
+		{"malformed body", "/v1/couriers/c-pings/pings", "pings-secret", `{"pings":`, http.StatusBadRequest},
+		{"unknown courier", "/v1/couriers/c-nobody/pings", "nobody-secret", ping, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest("POST", tt.path, strings.NewReader(tt.body))
+			assert.NoError(t, err)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+
+			rr := httptest.NewRecorder()
+			newTrackingRouter().ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.status, rr.Code, rr.Body.String())
+			if tt.status == http.StatusOK {
+				var receipt models.PingReceipt
+				assert.NoError(t, json.NewDecoder(rr.Body).Decode(&receipt))
+				assert.Equal(t, []string{}, receipt.Orders)
+			}
+		})
+	}
+}
+
+func TestGetTrailV1NotFound(t *testing.T) {
+	req, err := http.NewRequest("GET", "/v1/admin/orders/nonexistentID/trail", nil)
+	assert.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	newTrackingRouter().ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
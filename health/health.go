@@ -0,0 +1,128 @@
+// Package health tracks the lifecycle phase of the server and the health
+// checks of its components, reported by the /healthz and /readyz probes.
+package health
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Phase is where the server is in its lifecycle
+type Phase string
+
+const (
+	// PhaseStarting lasts until the orders have been replayed
+	PhaseStarting Phase = "starting"
+	// PhaseServing is the only phase in which the server is ready
+	PhaseServing Phase = "serving"
+	// PhaseDraining starts when a shutdown signal arrives
+	PhaseDraining Phase = "draining"
+)
+
+// Status values of a report or check
+const (
+	StatusOK          = "ok"
+	StatusUnavailable = "unavailable"
+)
+
+// CheckTimeout bounds how long a single check may take
+var CheckTimeout = 2 * time.Second
+
+// Check reports whether a component works; a nil error means healthy. Details
+// describe the component, e.g. which storage backend is in use. A check must
+// return once ctx is done.
+type Check func(ctx context.Context) (details map[string]string, err error)
+
+// CheckResult is the outcome of one check
+type CheckResult struct {
+	Status  string            `json:"status" example:"ok"`
+	Details map[string]string `json:"details,omitempty"`
+	Error   string            `json:"error,omitempty"`
+}
+
+// Report is the body of the readiness probe
+type Report struct {
+	Status string                 `json:"status" example:"ok"`
+	Phase  Phase                  `json:"phase" example:"serving"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+var state = struct {
+	sync.Mutex
+	phase  Phase
+	checks map[string]Check
+}{
+	phase:  PhaseStarting,
+	checks: make(map[string]Check),
+}
+
+// SetPhase moves the server to another lifecycle phase
+func SetPhase(phase Phase) {
+	state.Lock()
+	state.phase = phase
+	state.Unlock()
+}
+
+// CurrentPhase returns the lifecycle phase of the server
+func CurrentPhase() Phase {
+	state.Lock()
+	defer state.Unlock()
+	return state.phase
+}
+
+// Register adds or replaces the check of a component
+func Register(name string, check Check) {
+	state.Lock()
+	state.checks[name] = check
+	state.Unlock()
+}
+
+// Ready runs every check concurrently. The server is ready when it is serving
+// and every check passes.
+func Ready(ctx context.Context) Report {
+	state.Lock()
+	phase := state.phase
+	names := make([]string, 0, len(state.checks))
+	checks := make([]Check, 0, len(state.checks))
+	for name := range state.checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		checks = append(checks, state.checks[name])
+	}
+	state.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, CheckTimeout)
+	defer cancel()
+
+	results := make([]CheckResult, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			details, err := check(ctx)
+			results[i] = CheckResult{Status: StatusOK, Details: details}
+			if err != nil {
+				results[i].Status = StatusUnavailable
+				results[i].Error = err.Error()
+			}
+		}()
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusOK, Phase: phase, Checks: make(map[string]CheckResult, len(names))}
+	if phase != PhaseServing {
+		report.Status = StatusUnavailable
+	}
+	for i, name := range names {
+		report.Checks[name] = results[i]
+		if results[i].Status != StatusOK {
+			report.Status = StatusUnavailable
+		}
+	}
+	return report
+}
@@ -0,0 +1,66 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReady(t *testing.T) {
+	SetPhase(PhaseServing)
+	t.Cleanup(func() { SetPhase(PhaseStarting) })
+	Register("storage", func(ctx context.Context) (map[string]string, error) {
+		return map[string]string{"backend": "memory"}, nil
+	})
+
+	report := Ready(context.Background())
+	assert.Equal(t, StatusOK, report.Status)
+	assert.Equal(t, PhaseServing, report.Phase)
+	assert.Equal(t, CheckResult{Status: StatusOK, Details: map[string]string{"backend": "memory"}}, report.Checks["storage"])
+
+	Register("workers", func(ctx context.Context) (map[string]string, error) {
+		return nil, errors.New("1 of 1 workers stopped")
+	})
+	t.Cleanup(func() { unregister("storage", "workers") })
+
+	report = Ready(context.Background())
+	assert.Equal(t, StatusUnavailable, report.Status)
+	assert.Equal(t, StatusOK, report.Checks["storage"].Status)
+	assert.Equal(t, CheckResult{Status: StatusUnavailable, Error: "1 of 1 workers stopped"}, report.Checks["workers"])
+}
+
+func TestNotReadyOutsideServing(t *testing.T) {
+	for _, phase := range []Phase{PhaseStarting, PhaseDraining} {
+		SetPhase(phase)
+		report := Ready(context.Background())
+		assert.Equal(t, StatusUnavailable, report.Status, phase)
+		assert.Equal(t, phase, report.Phase)
+	}
+	SetPhase(PhaseStarting)
+}
+
+func TestCheckTimeout(t *testing.T) {
+	SetPhase(PhaseServing)
+	t.Cleanup(func() { SetPhase(PhaseStarting) })
+	defer func(timeout time.Duration) { CheckTimeout = timeout }(CheckTimeout)
+	CheckTimeout = 10 * time.Millisecond
+	Register("slow", func(ctx context.Context) (map[string]string, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	t.Cleanup(func() { unregister("slow") })
+
+	report := Ready(context.Background())
+	assert.Equal(t, StatusUnavailable, report.Checks["slow"].Status)
+}
+
+func unregister(names ...string) {
+	state.Lock()
+	defer state.Unlock()
+	for _, name := range names {
+		delete(state.checks, name)
+	}
+}
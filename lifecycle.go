@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+	"weservefood/config"
+	"weservefood/health"
+	"weservefood/repository"
+
+	"google.golang.org/grpc"
+)
+
+// newHTTPServer returns a server applying the configured timeouts
+func newHTTPServer(timeouts config.Timeouts, handler http.Handler) *http.Server {
+	return &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: timeouts.ReadHeader,
+		ReadTimeout:       timeouts.Read,
+		WriteTimeout:      timeouts.Write,
+		IdleTimeout:       timeouts.Idle,
+		ErrorLog:          slog.NewLogLogger(slog.Default().Handler(), slog.LevelWarn),
+	}
+}
+
+// listenerGroup binds every address before any server starts, so the process
+// fails fast when one of them is taken
+type listenerGroup struct {
+	listeners []boundListener
+	err       error
+}
+
+type boundListener struct {
+	name  string
+	l     net.Listener
+	serve func(net.Listener) error
+}
+
+// Listen binds addr for serve; after the first failure it does nothing
+func (g *listenerGroup) Listen(name, addr string, serve func(net.Listener) error) {
+	if g.err != nil {
+		return
+	}
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		g.err = fmt.Errorf("%s on %s: %w", name, addr, err)
+		for _, bound := range g.listeners {
+			bound.l.Close()
+		}
+		return
+	}
+	g.listeners = append(g.listeners, boundListener{name: name, l: l, serve: serve})
+}
+
+// Err is the first failure to bind
+func (g *listenerGroup) Err() error {
+	return g.err
+}
+
+// Serve starts every server; the channel receives the error of each that stops
+func (g *listenerGroup) Serve() <-chan error {
+	errs := make(chan error, len(g.listeners))
+	for _, bound := range g.listeners {
+		go func() {
+			slog.Info("starting "+bound.name+" server", slog.String("addr", bound.l.Addr().String()))
+			errs <- fmt.Errorf("%s server: %w", bound.name, bound.serve(bound.l))
+		}()
+	}
+	return errs
+}
+
+// workerGroup runs the background workers and reports how many are still running
+type workerGroup struct {
+	sync.WaitGroup
+	started, running atomic.Int32
+}
+
+// Go runs work in its own goroutine until it returns
+func (g *workerGroup) Go(work func()) {
+	g.Add(1)
+	g.started.Add(1)
+	g.running.Add(1)
+	go func() {
+		defer g.Done()
+		defer g.running.Add(-1)
+		work()
+	}()
+}
+
+// check fails once a worker has stopped; workers only stop on shutdown
+func (g *workerGroup) check(ctx context.Context) (map[string]string, error) {
+	started, running := g.started.Load(), g.running.Load()
+	details := map[string]string{"started": strconv.Itoa(int(started)), "running": strconv.Itoa(int(running))}
+	if running < started {
+		return details, fmt.Errorf("%d of %d workers stopped", started-running, started)
+	}
+	return details, nil
+}
+
+// replayOrders loads the orders saved by the file backend
+func replayOrders(storage config.Storage) error {
+	if storage.Backend != config.BackendFile {
+		return nil
+	}
+	if err := repository.OpenFile(storage.Path); err != nil {
+		return err
+	}
+	slog.Info("orders replayed", slog.String("path", storage.Path), slog.Int("orders", repository.GetOrderStats().StoreSize))
+	return nil
+}
+
+// storageCheck reports the storage backend and, for the file backend, whether
+// the last flush succeeded
+func storageCheck(storage config.Storage) health.Check {
+	return func(ctx context.Context) (map[string]string, error) {
+		details := map[string]string{"backend": storage.Backend}
+		if storage.Backend != config.BackendFile {
+			return details, nil
+		}
+
+		path, lastFlush, err := repository.FlushStatus()
+		details["path"] = storage.Path
+		if !lastFlush.IsZero() {
+			details["last_flush"] = lastFlush.Format(time.RFC3339)
+		}
+		switch {
+		case err != nil:
+			return details, fmt.Errorf("last flush failed: %w", err)
+		case path == "":
+			return details, errors.New("orders not replayed")
+		}
+		return details, nil
+	}
+}
+
+// drain stops accepting connections, waits within the timeout for in-flight
+// requests, streams and background workers to finish, then flushes the orders.
+// The flush happens even when the drain times out.
+func drain(timeout time.Duration, servers []*http.Server, grpcServer *grpc.Server, stopWorkers context.CancelFunc, workers *workerGroup) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var errs []error
+	grpcStopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(grpcStopped)
+	}()
+
+	for _, server := range servers {
+		if err := server.Shutdown(ctx); err != nil {
+			server.Close()
+			errs = append(errs, fmt.Errorf("HTTP requests not drained: %w", err))
+		}
+	}
+	select {
+	case <-grpcStopped:
+	case <-ctx.Done():
+		grpcServer.Stop()
+		errs = append(errs, fmt.Errorf("gRPC calls not drained: %w", ctx.Err()))
+	}
+
+	stopWorkers()
+	workersStopped := make(chan struct{})
+	go func() {
+		workers.Wait()
+		close(workersStopped)
+	}()
+	select {
+	case <-workersStopped:
+	case <-ctx.Done():
+		errs = append(errs, fmt.Errorf("background workers not stopped: %w", ctx.Err()))
+	}
+
+	if err := repository.Flush(); err != nil {
+		errs = append(errs, fmt.Errorf("orders not flushed: %w", err))
+	}
+	return errors.Join(errs...)
+}
+
+// flushEvery saves the orders to the file backend at each interval until ctx is done
+func flushEvery(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := repository.Flush(); err != nil {
+				slog.Error("unable to flush orders", slog.Any("error", err))
+			}
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFromContext(t *testing.T) {
+	assert.Equal(t, slog.Default(), FromContext(context.Background()))
+
+	var out bytes.Buffer
+	logger := New(&out, slog.LevelWarn)
+	ctx := WithLogger(context.Background(), logger)
+	assert.Equal(t, logger, FromContext(ctx))
+
+	FromContext(ctx).Info("dropped")
+	FromContext(ctx).Warn("kept", slog.String("order_id", "o-1"))
+	assert.NotContains(t, out.String(), "dropped")
+	assert.Contains(t, out.String(), `"msg":"kept","order_id":"o-1"`)
+}
@@ -1,15 +1,57 @@
 package main
 
 import (
-	"log"
+	"context"
+	"errors"
+	"flag"
+	"log/slog"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+	"weservefood/blob"
+	"weservefood/config"
+	"weservefood/eta"
+	"weservefood/geo"
+	"weservefood/graphqlapi"
+	"weservefood/grpcapi"
 	"weservefood/handler"
+	"weservefood/health"
+	"weservefood/logging"
+	"weservefood/metrics"
 	"weservefood/middleware"
+	"weservefood/models"
+	"weservefood/repository"
+	"weservefood/routing"
+	"weservefood/tlsconfig"
+	"weservefood/tracing"
 
 	_ "weservefood/docs"
 
 	"github.com/gorilla/mux"
 	swagger "github.com/swaggo/http-swagger"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gorilla/mux/otelmux"
+)
+
+// Legacy routes were deprecated with the introduction of /v1 and are removed at sunset
+var (
+	legacyDeprecatedAt = time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+	legacySunset       = time.Date(2027, time.April, 30, 0, 0, 0, 0, time.UTC)
+)
+
+// deprecated wraps a legacy handler with the deprecation headers pointing at its successor
+func deprecated(successor string, handlerFunc http.HandlerFunc) http.Handler {
+	return middleware.DeprecationMiddleware(legacyDeprecatedAt, legacySunset, successor, handlerFunc)
+}
+
+// Exit codes of the server
+const (
+	exitOK       = 0 // stopped by a signal after draining cleanly
+	exitFailure  = 1 // could not start, or a listener failed while serving
+	exitUsage    = 2 // invalid flags or configuration
+	exitShutdown = 3 // the drain timed out or the orders could not be flushed
 )
 
 // @title WeServeFood Delivery Order Management API
@@ -17,21 +59,245 @@ import (
 // @description API for managing food delivery orders
 // @host localhost:8383
 // @BasePath /
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
 func main() {
+	os.Exit(run())
+}
+
+// run serves until a signal or a listener failure, then drains and returns the exit code
+func run() int {
+	slog.SetDefault(logging.New(os.Stderr, slog.LevelInfo))
+
+	cfg, err := config.Load(os.Args[1:], os.Getenv, os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	}
+	if err != nil {
+		slog.Error("invalid configuration", slog.Any("error", err))
+		return exitUsage
+	}
+	slog.SetDefault(logging.New(os.Stderr, cfg.LogLevel()))
+
+	repository.DeliveryFeeCents = cfg.Business.DeliveryFeeCents
+	repository.DeliveryOffset = cfg.Business.DeliveryOffset
+	repository.SlotCapacity = cfg.Business.SlotCapacity
+	repository.LateRefundPercent = cfg.Business.LateRefundPercent
+	repository.DefaultProof = models.ProofMethod(cfg.Business.ProofOfDelivery)
+	repository.Blobs = blob.NewDir(cfg.Storage.BlobPath)
+	repository.CancellationPolicy = cancellationPolicy(cfg.Cancellation)
+	repository.Estimator = eta.Settings{
+		PrepTime:        cfg.ETA.PrepTime,
+		KitchenSlots:    cfg.ETA.KitchenSlots,
+		CourierSpeedKmh: cfg.ETA.CourierSpeedKmh,
+		DetourPercent:   cfg.ETA.DetourPercent,
+		Handover:        cfg.ETA.Handover,
+		CourierWait:     cfg.ETA.CourierWait,
+	}
+	repository.Dispatch = routing.BatchSettings{
+		MaxStops:     cfg.Dispatch.MaxStops,
+		RadiusMetres: float64(cfg.Dispatch.RadiusMetres),
+		Window:       cfg.Dispatch.Window,
+	}
+	repository.TrailLength = cfg.Tracking.TrailLength
+
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Traces.Exporter, os.Stdout)
+	if err != nil {
+		slog.Error("unable to set up tracing", slog.Any("error", err))
+		return exitFailure
+	}
+	defer shutdownTracing(context.Background())
+
+	// zone changes are saved back to the catalog file, which is created with
+	// the first one when it does not exist yet
+	if err := repository.OpenCatalog(cfg.Storage.CatalogPath); err != nil {
+		slog.Error("unable to load catalog", slog.Any("error", err))
+		return exitFailure
+	}
+	if len(repository.GetRestaurants()) == 0 {
+		slog.Warn("catalog has no restaurants", slog.String("path", cfg.Storage.CatalogPath))
+	}
+	// the bundled gazetteer is used unless another dataset is configured,
+	// which then has to load
+	if cfg.Storage.GazetteerPath != "" {
+		gazetteerFile, err := os.Open(cfg.Storage.GazetteerPath)
+		if err != nil {
+			slog.Error("unable to open gazetteer", slog.Any("error", err))
+			return exitFailure
+		}
+		gazetteer, err := geo.LoadGazetteer(gazetteerFile)
+		gazetteerFile.Close()
+		if err != nil {
+			slog.Error("unable to load gazetteer", slog.Any("error", err))
+			return exitFailure
+		}
+		repository.Geocoder = gazetteer
+	}
+
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	var workers workerGroup
+	health.Register("storage", storageCheck(cfg.Storage))
+	health.Register("workers", workers.check)
+
+	// CORS wraps the router so preflight requests are answered before routing
+	api := newHTTPServer(cfg.Timeouts, middleware.CORSMiddleware(cfg.CORS)(newRouter(cfg.HTTP, cfg.Auth)))
+	// subscriptions would otherwise hold the drain open until it times out
+	api.RegisterOnShutdown(repository.CloseWatchers)
+	httpServers := []*http.Server{api}
+	grpcServer := grpcapi.NewServer(grpcapi.ServingOnly()...)
+
+	var listeners listenerGroup
+	if cfg.TLS.Enabled {
+		reloader, err := tlsconfig.NewReloader(cfg.TLS.CertFile, cfg.TLS.KeyFile)
+		if err != nil {
+			slog.Error("unable to load TLS certificate", slog.Any("error", err))
+			return exitFailure
+		}
+		if api.TLSConfig, err = tlsconfig.New(cfg.TLS, reloader); err != nil {
+			slog.Error("invalid TLS configuration", slog.Any("error", err))
+			return exitFailure
+		}
+		health.Register("tls", reloader.Check)
+		workers.Go(func() { reloader.Watch(workerCtx, cfg.TLS.ReloadInterval) })
+
+		listeners.Listen("HTTPS", cfg.Server.HTTPSAddr, func(l net.Listener) error { return api.ServeTLS(l, "", "") })
+		if cfg.TLS.Redirect {
+			redirect := newHTTPServer(cfg.Timeouts, tlsconfig.RedirectHandler(cfg.Server.HTTPSAddr))
+			httpServers = append(httpServers, redirect)
+			listeners.Listen("HTTP redirect", cfg.Server.HTTPAddr, redirect.Serve)
+		}
+	} else {
+		listeners.Listen("HTTP", cfg.Server.HTTPAddr, api.Serve)
+	}
+	listeners.Listen("gRPC", cfg.Server.GRPCAddr, grpcServer.Serve)
+	if err := listeners.Err(); err != nil {
+		slog.Error("unable to listen", slog.Any("error", err))
+		return exitFailure
+	}
+
+	signals, stopSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stopSignals()
+
+	// only the probes and metrics are answered while the orders are replayed;
+	// the API and gRPC calls get 503 and Unavailable until then
+	serveErrs := listeners.Serve()
+
+	code := exitOK
+	if err := replayOrders(cfg.Storage); err != nil {
+		slog.Error("unable to replay orders", slog.String("path", cfg.Storage.Path), slog.Any("error", err))
+		code = exitFailure
+	} else {
+		if cfg.Storage.Backend == config.BackendFile {
+			workers.Go(func() { flushEvery(workerCtx, cfg.Storage.FlushInterval) })
+		}
+		health.SetPhase(health.PhaseServing)
+		slog.Info("ready")
+
+		select {
+		case <-signals.Done():
+			slog.Info("shutting down", slog.String("timeout", cfg.Timeouts.Shutdown.String()))
+		case err := <-serveErrs:
+			slog.Error("server stopped", slog.Any("error", err))
+			code = exitFailure
+		}
+	}
+	// a second signal kills the process instead of waiting for the drain
+	stopSignals()
+
+	health.SetPhase(health.PhaseDraining)
+	if code == exitOK && cfg.Timeouts.DrainDelay > 0 {
+		// keep serving while load balancers notice the failing readiness probe
+		time.Sleep(cfg.Timeouts.DrainDelay)
+	}
+
+	if err := drain(cfg.Timeouts.Shutdown, httpServers, grpcServer, stopWorkers, &workers); err != nil {
+		slog.Error("shutdown incomplete", slog.Any("error", err))
+		if code == exitOK {
+			code = exitShutdown
+		}
+		return code
+	}
+	slog.Info("shutdown complete")
+	return code
+}
+
+// cancellationPolicy converts the cancellation settings to the repository's rules
+func cancellationPolicy(settings config.Cancellation) map[models.CancelInitiator]repository.CancellationRule {
+	rule := func(statuses []string, cutoff time.Duration) repository.CancellationRule {
+		converted := repository.CancellationRule{Cutoff: cutoff}
+		for _, status := range statuses {
+			converted.Statuses = append(converted.Statuses, models.OrderStatus(status))
+		}
+		return converted
+	}
+	return map[models.CancelInitiator]repository.CancellationRule{
+		models.InitiatorCustomer:   rule(settings.CustomerStatuses, settings.CustomerCutoff),
+		models.InitiatorRestaurant: rule(settings.RestaurantStatuses, settings.RestaurantCutoff),
+		models.InitiatorSupport:    rule(settings.SupportStatuses, settings.SupportCutoff),
+	}
+}
+
+// newRouter registers every HTTP route behind the middleware chain. The admin
+// routes are only served to callers with an admin token, and a restaurant's
+// cancel route to that restaurant's token.
+func newRouter(limits config.HTTP, auth config.Auth) *mux.Router {
 	route := mux.NewRouter()
 
+	route.Use(otelmux.Middleware(tracing.ServiceName))
 	route.Use(middleware.LoggingMiddleware)
+	route.Use(middleware.AuthMiddleware(auth))
+	route.Use(middleware.MetricsMiddleware)
+	route.Use(middleware.StartupMiddleware("/ping", "/healthz", "/readyz", "/metrics"))
+	route.Use(middleware.CompressionMiddleware(limits.CompressMinBytes))
+	route.Use(middleware.DecompressionMiddleware)
+	route.Use(middleware.BodyLimitMiddleware(limits))
 	route.Use(middleware.ValidationMiddleware)
 
-	go route.HandleFunc("/ping", handler.PingServer).Methods("GET")
-	go route.HandleFunc("/place-order", handler.PlaceOrder).Methods("POST")
-	go route.HandleFunc("/get-order", handler.GetOrder).Methods("GET")
-	go route.HandleFunc("/get-all-orders", handler.GetAllOrders).Methods("GET")
-	go route.HandleFunc("/cancel-order/{email}/{id}", handler.CancelOrder).Methods("DELETE")
-	go route.HandleFunc("/update-address/{email}/{id}", handler.UpdateAddress).Methods("PUT")
+	route.HandleFunc("/ping", handler.PingServer).Methods("GET")
+	route.HandleFunc("/healthz", handler.Healthz).Methods("GET")
+	route.HandleFunc("/readyz", handler.Readyz).Methods("GET")
+	route.Handle("/metrics", metrics.Handler()).Methods("GET")
+
+	v1 := route.PathPrefix("/v1").Subrouter()
+	v1.HandleFunc("/orders", handler.CreateOrderV1).Methods("POST")
+	v1.HandleFunc("/orders", handler.ListOrdersV1).Methods("GET")
+	v1.HandleFunc("/orders/{id}", handler.GetOrderV1).Methods("GET")
+	v1.HandleFunc("/orders/{id}", handler.PatchOrderV1).Methods("PATCH")
+	v1.HandleFunc("/orders/{id}/cancel", handler.CancelOrderV1).Methods("POST")
+	v1.HandleFunc("/orders/{id}/payment", handler.PayOrderV1).Methods("POST")
+	v1.Handle("/orders/{id}/delivery", middleware.RequireCourier(http.HandlerFunc(handler.DeliverOrderV1))).Methods("POST")
+	v1.HandleFunc("/restaurants", handler.ListRestaurantsV1).Methods("GET")
+	v1.HandleFunc("/restaurants/{id}/menu", handler.GetMenuV1).Methods("GET")
+	v1.Handle("/restaurants/{id}/orders/{orderId}/cancel", middleware.RequireRestaurant(http.HandlerFunc(handler.CancelOrderAsRestaurantV1))).Methods("POST")
+	v1.HandleFunc("/couriers", handler.ListCouriersV1).Methods("GET")
+	v1.Handle("/couriers/{id}/pings", middleware.RequireRouteCourier(http.HandlerFunc(handler.RecordPingsV1))).Methods("POST")
+
+	admin := v1.PathPrefix("/admin").Subrouter()
+	admin.Use(middleware.RequireAdmin)
+	admin.HandleFunc("/restaurants/{id}/zones", handler.ListZonesV1).Methods("GET")
+	admin.HandleFunc("/restaurants/{id}/zones/{name}", handler.PutZoneV1).Methods("PUT")
+	admin.HandleFunc("/restaurants/{id}/zones/{name}", handler.DeleteZoneV1).Methods("DELETE")
+	admin.HandleFunc("/dispatch/plan", handler.PlanBatchesV1).Methods("GET")
+	admin.HandleFunc("/dispatch", handler.DispatchBatchesV1).Methods("POST")
+	admin.HandleFunc("/dispatch/batches/{id}", handler.GetBatchV1).Methods("GET")
+	admin.HandleFunc("/orders/import", handler.ImportOrdersV1).Methods("POST")
+	admin.HandleFunc("/orders/export", handler.ExportOrdersV1).Methods("GET")
+	admin.HandleFunc("/orders/{id}/cancel", handler.CancelOrderAsSupportV1).Methods("POST")
+	admin.HandleFunc("/orders/{id}/trail", handler.GetTrailV1).Methods("GET")
+	admin.HandleFunc("/orders/{id}/proof/{kind}", handler.GetProofV1).Methods("GET")
+
+	route.HandleFunc("/graphql", graphqlapi.Handler).Methods("GET", "POST")
+
+	// Legacy RPC-style routes, kept as deprecated aliases of the /v1 surface
+	route.Handle("/place-order", deprecated("/v1/orders", handler.PlaceOrder)).Methods("POST")
+	route.Handle("/get-order", deprecated("/v1/orders", handler.GetOrder)).Methods("GET")
+	route.Handle("/get-all-orders", deprecated("/v1/orders", handler.GetAllOrders)).Methods("GET")
+	route.Handle("/cancel-order/{email}/{id}", deprecated("/v1/orders/{id}/cancel", handler.CancelOrder)).Methods("DELETE")
+	route.Handle("/update-address/{email}/{id}", deprecated("/v1/orders/{id}", handler.UpdateAddress)).Methods("PUT")
 
-	go route.PathPrefix("/swagger/").Handler(swagger.Handler()).Methods(http.MethodGet)
+	route.PathPrefix("/swagger/").Handler(swagger.Handler()).Methods(http.MethodGet)
 
-	log.Println("Starting server on port 8383")
-	http.ListenAndServe(":8383", route)
+	return route
 }
@@ -0,0 +1,107 @@
+// Package metrics exposes request and business metrics in the Prometheus
+// text format.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+	"weservefood/models"
+	"weservefood/repository"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "weservefood"
+
+var (
+	requestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by route template, method and status code.",
+	}, []string{"route", "method", "status"})
+
+	requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by route template, method and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+)
+
+// Registry holds every metric served by Handler
+var Registry = prometheus.NewRegistry()
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		requestsTotal,
+		requestDuration,
+		orderCollector{},
+	)
+}
+
+// Handler serves the registered metrics
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// ObserveRequest records a served HTTP request. Route is the matched route
+// template rather than the raw path, so order IDs do not explode the label set.
+func ObserveRequest(route, method string, status int, duration time.Duration) {
+	labels := prometheus.Labels{"route": route, "method": method, "status": strconv.Itoa(status)}
+	requestsTotal.With(labels).Inc()
+	requestDuration.With(labels).Observe(duration.Seconds())
+}
+
+var (
+	ordersPlacedDesc = prometheus.NewDesc(namespace+"_orders_placed_total",
+		"Orders placed since the process started.", nil, nil)
+	ordersDeliveredDesc = prometheus.NewDesc(namespace+"_orders_delivered_total",
+		"Orders delivered since the process started.", nil, nil)
+	ordersCancelledDesc = prometheus.NewDesc(namespace+"_orders_cancelled_total",
+		"Orders cancelled since the process started.", nil, nil)
+	activeOrdersDesc = prometheus.NewDesc(namespace+"_active_orders",
+		"Orders that are neither delivered nor cancelled, by status.", []string{"status"}, nil)
+	storeSizeDesc = prometheus.NewDesc(namespace+"_store_orders",
+		"Orders held in the store, cancelled ones included.", nil, nil)
+	slotUtilisationDesc = prometheus.NewDesc(namespace+"_slot_utilisation_ratio",
+		"Share of courier delivery slots taken by an active order.", nil, nil)
+)
+
+// orderCollector reads the business metrics from the repository on every scrape
+type orderCollector struct{}
+
+func (orderCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- ordersPlacedDesc
+	ch <- ordersDeliveredDesc
+	ch <- ordersCancelledDesc
+	ch <- activeOrdersDesc
+	ch <- storeSizeDesc
+	ch <- slotUtilisationDesc
+}
+
+func (orderCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := repository.GetOrderStats()
+
+	ch <- prometheus.MustNewConstMetric(ordersPlacedDesc, prometheus.CounterValue, float64(stats.Placed))
+	ch <- prometheus.MustNewConstMetric(ordersDeliveredDesc, prometheus.CounterValue, float64(stats.Delivered))
+	ch <- prometheus.MustNewConstMetric(ordersCancelledDesc, prometheus.CounterValue, float64(stats.Cancelled))
+	// every status is reported, so an emptied status drops to zero instead of disappearing
+	for _, status := range models.OrderStatuses {
+		if status.Final() {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(activeOrdersDesc, prometheus.GaugeValue, float64(stats.Active[status]), string(status))
+	}
+	ch <- prometheus.MustNewConstMetric(storeSizeDesc, prometheus.GaugeValue, float64(stats.StoreSize))
+
+	utilisation := 0.0
+	if stats.Slots > 0 {
+		utilisation = float64(stats.BusySlots) / float64(stats.Slots)
+	}
+	ch <- prometheus.MustNewConstMetric(slotUtilisationDesc, prometheus.GaugeValue, utilisation)
+}
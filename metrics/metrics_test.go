@@ -0,0 +1,55 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"weservefood/models"
+	"weservefood/repository"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrderMetrics(t *testing.T) {
+	repository.AddCourier(models.Courier{ID: "c-metrics", Name: "Mia", Available: true})
+	before := repository.GetOrderStats()
+	order, err := repository.CreateOrder(context.Background(), models.Order{Email: "metrics@example.com", Address: "1 Main St"})
+	require.NoError(t, err)
+	_, err = repository.AssignCourier(context.Background(), order.ID, "c-metrics")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		repository.CancelOrder(context.Background(), order.ID, models.OrderCancellation{Email: "metrics@example.com", Reason: "changed my mind"})
+	})
+	cancelled, err := repository.CreateOrder(context.Background(), models.Order{Email: "metrics@example.com", Address: "2 Main St"})
+	require.NoError(t, err)
+	_, err = repository.CancelOrder(context.Background(), cancelled.ID, models.OrderCancellation{Email: "metrics@example.com", Reason: "changed my mind"})
+	require.NoError(t, err)
+
+	// one active_orders series for every status but delivered and cancelled
+	assert.Equal(t, 5+len(models.OrderStatuses)-2, testutil.CollectAndCount(orderCollector{}))
+
+	req, _ := http.NewRequest(http.MethodGet, "/metrics", nil)
+	rr := httptest.NewRecorder()
+	Handler().ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	body := rr.Body.String()
+	assert.Contains(t, body, fmt.Sprintf("weservefood_orders_placed_total %d\n", before.Placed+2))
+	assert.Contains(t, body, fmt.Sprintf("weservefood_orders_cancelled_total %d\n", before.Cancelled+1))
+	assert.Contains(t, body, fmt.Sprintf("weservefood_active_orders{status=\"placed\"} %d\n", before.Active[models.StatusPlaced]+1))
+	assert.Contains(t, body, fmt.Sprintf("weservefood_store_orders %d\n", before.StoreSize+2))
+	assert.Contains(t, body, "weservefood_slot_utilisation_ratio 1")
+	assert.Contains(t, body, "go_goroutines")
+}
+
+func TestMetricsLint(t *testing.T) {
+	ObserveRequest("/lint", http.MethodGet, http.StatusOK, 0)
+
+	problems, err := testutil.GatherAndLint(Registry)
+	require.NoError(t, err)
+	assert.Empty(t, problems)
+}
@@ -0,0 +1,165 @@
+package middleware
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"weservefood/config"
+
+	"github.com/gorilla/mux"
+)
+
+type adminKey struct{}
+
+type restaurantKey struct{}
+
+type courierKey struct{}
+
+// AuthMiddleware recognises callers by their bearer token. An administrator's,
+// a restaurant's or a courier's request carries that on its context, for
+// RequireAdmin, RequireRestaurant, RequireCourier, RequireRouteCourier and
+// their helpers; other requests pass through unchanged.
+func AuthMiddleware(auth config.Auth) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			if token, ok := bearerToken(req); ok {
+				if knownToken(auth.AdminTokens, token) {
+					req = req.WithContext(WithAdmin(req.Context()))
+				}
+				if restaurantID := tokenOwner(auth.RestaurantTokens, token); restaurantID != "" {
+					req = req.WithContext(WithRestaurant(req.Context(), restaurantID))
+				}
+				if courierID := tokenOwner(auth.CourierTokens, token); courierID != "" {
+					req = req.WithContext(WithCourier(req.Context(), courierID))
+				}
+			}
+			next.ServeHTTP(rw, req)
+		})
+	}
+}
+
+// RequireAdmin refuses requests that AuthMiddleware did not recognise as an
+// administrator's
+func RequireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if !IsAdmin(req.Context()) {
+			unauthorized(rw)
+			return
+		}
+		next.ServeHTTP(rw, req)
+	})
+}
+
+// WithAdmin marks the context as an administrator's
+func WithAdmin(ctx context.Context) context.Context {
+	return context.WithValue(ctx, adminKey{}, true)
+}
+
+// IsAdmin reports whether the request the context belongs to was made by an administrator
+func IsAdmin(ctx context.Context) bool {
+	admin, _ := ctx.Value(adminKey{}).(bool)
+	return admin
+}
+
+// RequireRestaurant refuses requests that were not made with the token of the
+// restaurant in the route's id variable
+func RequireRestaurant(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		restaurantID, ok := RestaurantOf(req.Context())
+		if !ok {
+			unauthorized(rw)
+			return
+		}
+		if restaurantID != mux.Vars(req)["id"] {
+			http.Error(rw, "the token belongs to another restaurant", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(rw, req)
+	})
+}
+
+// WithRestaurant marks the context as the given restaurant's
+func WithRestaurant(ctx context.Context, restaurantID string) context.Context {
+	return context.WithValue(ctx, restaurantKey{}, restaurantID)
+}
+
+// RestaurantOf returns the restaurant the request the context belongs to was made by, if any
+func RestaurantOf(ctx context.Context) (string, bool) {
+	restaurantID, ok := ctx.Value(restaurantKey{}).(string)
+	return restaurantID, ok
+}
+
+// RequireCourier refuses requests that AuthMiddleware did not recognise as a
+// courier's; the handler finds the courier with CourierOf
+func RequireCourier(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if _, ok := CourierOf(req.Context()); !ok {
+			unauthorized(rw)
+			return
+		}
+		next.ServeHTTP(rw, req)
+	})
+}
+
+// RequireRouteCourier refuses requests that were not made with the token of
+// the courier in the route's id variable
+func RequireRouteCourier(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		courierID, ok := CourierOf(req.Context())
+		if !ok {
+			unauthorized(rw)
+			return
+		}
+		if courierID != mux.Vars(req)["id"] {
+			http.Error(rw, "the token belongs to another courier", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(rw, req)
+	})
+}
+
+// WithCourier marks the context as the given courier's
+func WithCourier(ctx context.Context, courierID string) context.Context {
+	return context.WithValue(ctx, courierKey{}, courierID)
+}
+
+// CourierOf returns the courier the request the context belongs to was made by, if any
+func CourierOf(ctx context.Context) (string, bool) {
+	courierID, ok := ctx.Value(courierKey{}).(string)
+	return courierID, ok
+}
+
+func bearerToken(req *http.Request) (string, bool) {
+	token, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+	return token, ok && token != ""
+}
+
+// knownToken compares the token with each known one in constant time
+func knownToken(known []string, token string) bool {
+	found := false
+	for _, candidate := range known {
+		if subtle.ConstantTimeCompare([]byte(candidate), []byte(token)) == 1 {
+			found = true
+		}
+	}
+	return found
+}
+
+// tokenOwner returns the restaurant or courier whose "id=token" entry matches
+// the token, comparing each in constant time
+func tokenOwner(entries []string, token string) string {
+	found := ""
+	for _, entry := range entries {
+		owner, candidate, _ := strings.Cut(entry, "=")
+		if subtle.ConstantTimeCompare([]byte(candidate), []byte(token)) == 1 {
+			found = owner
+		}
+	}
+	return found
+}
+
+func unauthorized(rw http.ResponseWriter) {
+	rw.Header().Set("WWW-Authenticate", `Bearer realm="weservefood"`)
+	http.Error(rw, "a valid bearer token is required", http.StatusUnauthorized)
+}
@@ -0,0 +1,155 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"weservefood/config"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequireAdmin(t *testing.T) {
+	protected := AuthMiddleware(config.Auth{AdminTokens: []string{"admin-secret"}})(RequireAdmin(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusNoContent)
+	})))
+
+	tests := map[string]struct {
+		authorization string
+		status        int
+	}{
+		"admin":     {"Bearer admin-secret", http.StatusNoContent},
+		"wrong":     {"Bearer admin-secre", http.StatusUnauthorized},
+		"anonymous": {"", http.StatusUnauthorized},
+		"basic":     {"Basic YWRtaW46YWRtaW4=", http.StatusUnauthorized},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "/v1/admin/dispatch/plan", nil)
+			if test.authorization != "" {
+				req.Header.Set("Authorization", test.authorization)
+			}
+			rr := httptest.NewRecorder()
+			protected.ServeHTTP(rr, req)
+
+			assert.Equal(t, test.status, rr.Code)
+			if test.status == http.StatusUnauthorized {
+				assert.Contains(t, rr.Header().Get("WWW-Authenticate"), "Bearer")
+			}
+		})
+	}
+}
+
+func TestRequireAdminWithoutTokens(t *testing.T) {
+	protected := AuthMiddleware(config.Auth{})(RequireAdmin(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusNoContent)
+	})))
+
+	req, _ := http.NewRequest("GET", "/v1/admin/dispatch/plan", nil)
+	req.Header.Set("Authorization", "Bearer anything")
+	rr := httptest.NewRecorder()
+	protected.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+}
+
+func TestRequireRestaurant(t *testing.T) {
+	router := mux.NewRouter()
+	router.Use(AuthMiddleware(config.Auth{
+		AdminTokens:      []string{"admin-secret"},
+		RestaurantTokens: []string{"r-curry-house=curry-secret", "r-noodle-bar=noodle-secret"},
+	}))
+	router.Handle("/v1/restaurants/{id}/orders/{orderId}/cancel", RequireRestaurant(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusNoContent)
+	})))
+
+	tests := map[string]struct {
+		authorization string
+		status        int
+	}{
+		"own restaurant":     {"Bearer curry-secret", http.StatusNoContent},
+		"another restaurant": {"Bearer noodle-secret", http.StatusForbidden},
+		"admin":              {"Bearer admin-secret", http.StatusUnauthorized},
+		"anonymous":          {"", http.StatusUnauthorized},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			req, _ := http.NewRequest("POST", "/v1/restaurants/r-curry-house/orders/o-1/cancel", nil)
+			if test.authorization != "" {
+				req.Header.Set("Authorization", test.authorization)
+			}
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, test.status, rr.Code)
+		})
+	}
+}
+
+func TestRequireCourier(t *testing.T) {
+	protected := AuthMiddleware(config.Auth{
+		RestaurantTokens: []string{"r-curry-house=curry-secret"},
+		CourierTokens:    []string{"c-1=courier-secret"},
+	})(RequireCourier(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		courierID, _ := CourierOf(req.Context())
+		rw.Write([]byte(courierID))
+	})))
+
+	tests := map[string]struct {
+		authorization string
+		status        int
+	}{
+		"courier":    {"Bearer courier-secret", http.StatusOK},
+		"restaurant": {"Bearer curry-secret", http.StatusUnauthorized},
+		"anonymous":  {"", http.StatusUnauthorized},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			req, _ := http.NewRequest("POST", "/v1/orders/o-1/delivery", nil)
+			if test.authorization != "" {
+				req.Header.Set("Authorization", test.authorization)
+			}
+			rr := httptest.NewRecorder()
+			protected.ServeHTTP(rr, req)
+
+			assert.Equal(t, test.status, rr.Code)
+			if test.status == http.StatusOK {
+				assert.Equal(t, "c-1", rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestRequireRouteCourier(t *testing.T) {
+	router := mux.NewRouter()
+	router.Use(AuthMiddleware(config.Auth{
+		AdminTokens:   []string{"admin-secret"},
+		CourierTokens: []string{"c-1=first-secret", "c-2=second-secret"},
+	}))
+	router.Handle("/v1/couriers/{id}/pings", RequireRouteCourier(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusNoContent)
+	})))
+
+	tests := map[string]struct {
+		authorization string
+		status        int
+	}{
+		"own courier":     {"Bearer first-secret", http.StatusNoContent},
+		"another courier": {"Bearer second-secret", http.StatusForbidden},
+		"admin":           {"Bearer admin-secret", http.StatusUnauthorized},
+		"anonymous":       {"", http.StatusUnauthorized},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			req, _ := http.NewRequest("POST", "/v1/couriers/c-1/pings", nil)
+			if test.authorization != "" {
+				req.Header.Set("Authorization", test.authorization)
+			}
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, test.status, rr.Code)
+		})
+	}
+}
@@ -0,0 +1,266 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"weservefood/config"
+	"weservefood/logging"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gorilla/mux"
+)
+
+// Content codings supported for responses, in order of preference
+const (
+	encodingBrotli = "br"
+	encodingGzip   = "gzip"
+)
+
+// uncompressedTypes are not worth compressing again, or must reach the client
+// without the buffering a compressor adds
+var uncompressedTypes = map[string]bool{
+	"text/event-stream":              true,
+	"application/vnd.apache.parquet": true,
+	"application/zip":                true,
+	"application/gzip":               true,
+}
+
+var (
+	gzipWriters   = sync.Pool{New: func() interface{} { return gzip.NewWriter(io.Discard) }}
+	brotliWriters = sync.Pool{New: func() interface{} { return brotli.NewWriterLevel(io.Discard, 5) }}
+)
+
+// CompressionMiddleware compresses responses with brotli or gzip, whichever the
+// client prefers in Accept-Encoding. Responses smaller than minBytes, already
+// encoded, or of a type listed in uncompressedTypes are sent as they are.
+func CompressionMiddleware(minBytes int) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.Header().Add("Vary", "Accept-Encoding")
+			encoding := negotiateEncoding(req.Header.Get("Accept-Encoding"))
+			if encoding == "" || req.Method == http.MethodHead {
+				next.ServeHTTP(rw, req)
+				return
+			}
+
+			writer := &compressWriter{ResponseWriter: rw, encoding: encoding, minBytes: minBytes}
+			defer writer.Close()
+			next.ServeHTTP(writer, req)
+		})
+	}
+}
+
+// negotiateEncoding picks the coding with the highest quality, preferring
+// brotli on a tie; it is empty when the client accepts neither
+func negotiateEncoding(acceptEncoding string) string {
+	qualities := make(map[string]float64)
+	for _, entry := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(entry, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		quality := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			quality = parsed
+		}
+		qualities[name] = quality
+	}
+
+	best, bestQuality := "", 0.0
+	for _, encoding := range []string{encodingBrotli, encodingGzip} {
+		quality, ok := qualities[encoding]
+		if !ok {
+			quality, ok = qualities["*"]
+		}
+		if ok && quality > bestQuality {
+			best, bestQuality = encoding, quality
+		}
+	}
+	return best
+}
+
+// compressWriter holds back the start of a response until it knows whether
+// compressing it is worthwhile
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string
+	minBytes int
+
+	status  int
+	pending []byte
+	decided bool
+	encoder io.WriteCloser
+}
+
+func (w *compressWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *compressWriter) Write(data []byte) (int, error) {
+	if !w.decided {
+		w.pending = append(w.pending, data...)
+		if len(w.pending) < w.minBytes {
+			return len(data), nil
+		}
+		if err := w.decide(); err != nil {
+			return 0, err
+		}
+		return len(data), nil
+	}
+	if w.encoder != nil {
+		return w.encoder.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// decide sends the headers and the held back bytes, compressed when the
+// response is eligible
+func (w *compressWriter) decide() error {
+	w.decided = true
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	header := w.Header()
+	if header.Get("Content-Type") == "" && len(w.pending) > 0 {
+		header.Set("Content-Type", http.DetectContentType(w.pending))
+	}
+	mediaType, _, _ := mime.ParseMediaType(header.Get("Content-Type"))
+	if len(w.pending) >= w.minBytes && len(w.pending) > 0 && header.Get("Content-Encoding") == "" &&
+		!uncompressedTypes[mediaType] && w.status != http.StatusNoContent && w.status != http.StatusNotModified {
+		header.Set("Content-Encoding", w.encoding)
+		header.Del("Content-Length")
+		w.encoder = newEncoder(w.encoding, w.ResponseWriter)
+	}
+
+	w.ResponseWriter.WriteHeader(w.status)
+	pending := w.pending
+	w.pending = nil
+	if len(pending) == 0 {
+		return nil
+	}
+	var err error
+	if w.encoder != nil {
+		_, err = w.encoder.Write(pending)
+	} else {
+		_, err = w.ResponseWriter.Write(pending)
+	}
+	return err
+}
+
+// Flush sends what has been written so far, so streaming responses keep streaming
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		w.decide()
+	}
+	if flusher, ok := w.encoder.(interface{ Flush() error }); ok {
+		flusher.Flush()
+	}
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Close finishes the response once the handler has returned
+func (w *compressWriter) Close() error {
+	if !w.decided {
+		if err := w.decide(); err != nil {
+			return err
+		}
+	}
+	if w.encoder == nil {
+		return nil
+	}
+	err := w.encoder.Close()
+	releaseEncoder(w.encoding, w.encoder)
+	w.encoder = nil
+	return err
+}
+
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func newEncoder(encoding string, w io.Writer) io.WriteCloser {
+	if encoding == encodingBrotli {
+		encoder := brotliWriters.Get().(*brotli.Writer)
+		encoder.Reset(w)
+		return encoder
+	}
+	encoder := gzipWriters.Get().(*gzip.Writer)
+	encoder.Reset(w)
+	return encoder
+}
+
+func releaseEncoder(encoding string, encoder io.WriteCloser) {
+	if encoding == encodingBrotli {
+		brotliWriters.Put(encoder)
+	} else {
+		gzipWriters.Put(encoder)
+	}
+}
+
+// DecompressionMiddleware inflates gzip request bodies, so handlers and the
+// body size limit see the decoded bytes. Other codings are rejected with 415.
+func DecompressionMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		switch strings.ToLower(strings.TrimSpace(req.Header.Get("Content-Encoding"))) {
+		case "", "identity":
+			next.ServeHTTP(rw, req)
+			return
+		case encodingGzip, "x-gzip":
+		default:
+			rw.Header().Set("Accept-Encoding", encodingGzip)
+			http.Error(rw, "unsupported Content-Encoding", http.StatusUnsupportedMediaType)
+			return
+		}
+
+		body, err := gzip.NewReader(req.Body)
+		if err != nil {
+			logging.FromContext(req.Context()).Warn("validation failed", slog.String("reason", "invalid gzip request body"))
+			http.Error(rw, "invalid gzip request body", http.StatusBadRequest)
+			return
+		}
+		defer body.Close()
+
+		req.Body = body
+		req.Header.Del("Content-Encoding")
+		req.Header.Del("Content-Length")
+		req.ContentLength = -1
+		next.ServeHTTP(rw, req)
+	})
+}
+
+// BodyLimitMiddleware caps request bodies at the limit of their route template,
+// or the default limit. Bodies declared larger are refused with 413 straight
+// away; handlers answer 413 when a body turns out larger while being read.
+func BodyLimitMiddleware(limits config.HTTP) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			limit := limits.MaxBodyBytes
+			if routeLimit, ok := limits.RouteMaxBodyBytes[routeTemplate(req)]; ok {
+				limit = routeLimit
+			}
+			if limit == 0 || req.Body == nil || req.Body == http.NoBody {
+				next.ServeHTTP(rw, req)
+				return
+			}
+
+			if req.ContentLength > limit {
+				logging.FromContext(req.Context()).Warn("validation failed", slog.String("reason", "request body too large"), slog.Int64("limit", limit))
+				http.Error(rw, "request body too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+			req.Body = http.MaxBytesReader(rw, req.Body, limit)
+			next.ServeHTTP(rw, req)
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"weservefood/config"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := map[string]string{
+		"":                          "",
+		"identity":                  "",
+		"gzip":                      "gzip",
+		"gzip, deflate, br":         "br",
+		"br;q=0.5, gzip":            "gzip",
+		"br;q=0, gzip;q=0.1":        "gzip",
+		"*":                         "br",
+		"*;q=0.2, br;q=0":           "gzip",
+		"GZIP;q=1.0, BR;q=invalid ": "gzip",
+	}
+
+	for acceptEncoding, want := range tests {
+		assert.Equal(t, want, negotiateEncoding(acceptEncoding), acceptEncoding)
+	}
+}
+
+func serveCompressed(t *testing.T, acceptEncoding string, handler http.HandlerFunc) *httptest.ResponseRecorder {
+	router := mux.NewRouter()
+	router.Use(CompressionMiddleware(1024))
+	router.HandleFunc("/v1/orders", handler)
+
+	req, _ := http.NewRequest(http.MethodGet, "/v1/orders", nil)
+	req.Header.Set("Accept-Encoding", acceptEncoding)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestCompressionMiddleware(t *testing.T) {
+	listing := `[` + strings.Repeat(`{"id":"1","status":"placed"},`, 200) + `{}]`
+	writeListing := func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("Content-Type", "application/json")
+		rw.Header().Set("Content-Length", "999")
+		// written in small pieces, as json.Encoder and CSV writers do
+		for i := 0; i < len(listing); i += 100 {
+			rw.Write([]byte(listing[i:min(i+100, len(listing))]))
+		}
+	}
+
+	rr := serveCompressed(t, "gzip", writeListing)
+	assert.Equal(t, "gzip", rr.Header().Get("Content-Encoding"))
+	assert.Empty(t, rr.Header().Get("Content-Length"))
+	assert.Contains(t, rr.Header().Values("Vary"), "Accept-Encoding")
+	assert.Less(t, rr.Body.Len(), len(listing))
+	reader, err := gzip.NewReader(rr.Body)
+	require.NoError(t, err)
+	body, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, listing, string(body))
+
+	rr = serveCompressed(t, "gzip, br", writeListing)
+	assert.Equal(t, "br", rr.Header().Get("Content-Encoding"))
+	body, err = io.ReadAll(brotli.NewReader(rr.Body))
+	require.NoError(t, err)
+	assert.Equal(t, listing, string(body))
+
+	rr = serveCompressed(t, "", writeListing)
+	assert.Empty(t, rr.Header().Get("Content-Encoding"))
+	assert.Equal(t, listing, rr.Body.String())
+}
+
+func TestCompressionSkipped(t *testing.T) {
+	rr := serveCompressed(t, "gzip", func(rw http.ResponseWriter, req *http.Request) {
+		http.Error(rw, "order not found", http.StatusNotFound)
+	})
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Empty(t, rr.Header().Get("Content-Encoding"))
+	assert.Equal(t, "order not found\n", rr.Body.String())
+
+	rr = serveCompressed(t, "gzip", func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("Content-Type", "application/vnd.apache.parquet")
+		rw.Write(bytes.Repeat([]byte("PAR1"), 1024))
+	})
+	assert.Empty(t, rr.Header().Get("Content-Encoding"))
+	assert.Equal(t, 4096, rr.Body.Len())
+
+	rr = serveCompressed(t, "gzip", func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusNoContent)
+	})
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	assert.Empty(t, rr.Header().Get("Content-Encoding"))
+}
+
+func TestCompressionFlush(t *testing.T) {
+	rr := serveCompressed(t, "gzip", func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("Content-Type", "application/x-ndjson")
+		rw.Write([]byte(`{"line":1}` + "\n"))
+		require.NoError(t, http.NewResponseController(rw).Flush())
+		rw.Write([]byte(`{"line":2}` + "\n"))
+	})
+
+	assert.True(t, rr.Flushed)
+	assert.Equal(t, `{"line":1}`+"\n"+`{"line":2}`+"\n", rr.Body.String())
+}
+
+func TestDecompressionMiddleware(t *testing.T) {
+	var received string
+	handler := DecompressionMiddleware(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		received = string(body)
+	}))
+
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	writer.Write([]byte(`{"email":"gzip@example.com"}`))
+	writer.Close()
+	req, _ := http.NewRequest(http.MethodPost, "/v1/orders", &compressed)
+	req.Header.Set("Content-Encoding", "gzip")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, `{"email":"gzip@example.com"}`, received)
+
+	req, _ = http.NewRequest(http.MethodPost, "/v1/orders", strings.NewReader("not gzip"))
+	req.Header.Set("Content-Encoding", "gzip")
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	req, _ = http.NewRequest(http.MethodPost, "/v1/orders", strings.NewReader("{}"))
+	req.Header.Set("Content-Encoding", "zstd")
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusUnsupportedMediaType, rr.Code)
+}
+
+func TestBodyLimitMiddleware(t *testing.T) {
+	router := mux.NewRouter()
+	router.Use(DecompressionMiddleware)
+	router.Use(BodyLimitMiddleware(config.HTTP{MaxBodyBytes: 16, RouteMaxBodyBytes: map[string]int64{"/v1/admin/orders/import": 0}}))
+	read := func(rw http.ResponseWriter, req *http.Request) {
+		_, err := io.ReadAll(req.Body)
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(rw, "request body too large", http.StatusRequestEntityTooLarge)
+		}
+	}
+	router.HandleFunc("/v1/orders", read)
+	router.HandleFunc("/v1/admin/orders/import", read)
+
+	post := func(path string, body io.Reader, encoding string) int {
+		req, _ := http.NewRequest(http.MethodPost, path, body)
+		req.Header.Set("Content-Encoding", encoding)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	assert.Equal(t, http.StatusOK, post("/v1/orders", strings.NewReader(`{"small":true}`), ""))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, post("/v1/orders", strings.NewReader(strings.Repeat("x", 17)), ""))
+	assert.Equal(t, http.StatusOK, post("/v1/admin/orders/import", strings.NewReader(strings.Repeat("x", 1024)), ""))
+
+	// the limit applies to the inflated body, so a small gzip bomb is refused
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	writer.Write(bytes.Repeat([]byte("x"), 4096))
+	writer.Close()
+	assert.Equal(t, http.StatusRequestEntityTooLarge, post("/v1/orders", &compressed, "gzip"))
+}
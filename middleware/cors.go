@@ -0,0 +1,117 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"weservefood/config"
+)
+
+// exposedHeaders are the response headers browser code may read besides the
+// CORS-safelisted ones
+var exposedHeaders = []string{RequestIDHeader, "Content-Disposition", "Deprecation", "Sunset", "Link"}
+
+// CORSMiddleware lets the configured origins call the API from a browser. It
+// answers preflight requests itself, so it must wrap the router: the router
+// rejects OPTIONS before route middlewares such as ValidationMiddleware run.
+func CORSMiddleware(cors config.CORS) func(http.Handler) http.Handler {
+	methods := strings.Join(cors.AllowedMethods, ", ")
+	maxAge := strconv.Itoa(int(cors.MaxAge.Seconds()))
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			origin := req.Header.Get("Origin")
+			if origin == "" || len(cors.AllowedOrigins) == 0 {
+				next.ServeHTTP(rw, req)
+				return
+			}
+
+			preflight := req.Method == http.MethodOptions && req.Header.Get("Access-Control-Request-Method") != ""
+			header := rw.Header()
+			header.Add("Vary", "Origin")
+			if preflight {
+				header.Add("Vary", "Access-Control-Request-Method")
+				header.Add("Vary", "Access-Control-Request-Headers")
+			}
+
+			if !originAllowed(cors.AllowedOrigins, origin) {
+				if preflight {
+					http.Error(rw, "CORS origin not allowed", http.StatusForbidden)
+					return
+				}
+				next.ServeHTTP(rw, req)
+				return
+			}
+
+			if slices.Contains(cors.AllowedOrigins, "*") && !cors.AllowCredentials {
+				header.Set("Access-Control-Allow-Origin", "*")
+			} else {
+				header.Set("Access-Control-Allow-Origin", origin)
+			}
+			if cors.AllowCredentials {
+				header.Set("Access-Control-Allow-Credentials", "true")
+			}
+
+			if !preflight {
+				header.Set("Access-Control-Expose-Headers", strings.Join(exposedHeaders, ", "))
+				next.ServeHTTP(rw, req)
+				return
+			}
+
+			requestedHeaders := splitHeaderList(req.Header.Get("Access-Control-Request-Headers"))
+			if !slices.Contains(cors.AllowedMethods, req.Header.Get("Access-Control-Request-Method")) ||
+				!headersAllowed(cors.AllowedHeaders, requestedHeaders) {
+				http.Error(rw, "CORS request not allowed", http.StatusForbidden)
+				return
+			}
+
+			header.Set("Access-Control-Allow-Methods", methods)
+			if len(requestedHeaders) > 0 {
+				header.Set("Access-Control-Allow-Headers", strings.Join(requestedHeaders, ", "))
+			}
+			header.Set("Access-Control-Max-Age", maxAge)
+			rw.WriteHeader(http.StatusNoContent)
+		})
+	}
+}
+
+// originAllowed matches an origin against exact origins, "*", and patterns
+// with a wildcard subdomain such as "https://*.example.com"
+func originAllowed(allowed []string, origin string) bool {
+	for _, pattern := range allowed {
+		if pattern == "*" || strings.EqualFold(pattern, origin) {
+			return true
+		}
+		if prefix, suffix, ok := strings.Cut(pattern, "*."); ok &&
+			len(origin) > len(prefix)+len(suffix)+1 &&
+			strings.HasPrefix(strings.ToLower(origin), strings.ToLower(prefix)) &&
+			strings.HasSuffix(strings.ToLower(origin), "."+strings.ToLower(suffix)) {
+			return true
+		}
+	}
+	return false
+}
+
+// headersAllowed reports whether every requested header is allowed, ignoring case
+func headersAllowed(allowed, requested []string) bool {
+	if slices.Contains(allowed, "*") {
+		return true
+	}
+	for _, name := range requested {
+		if !slices.ContainsFunc(allowed, func(allowed string) bool { return strings.EqualFold(allowed, name) }) {
+			return false
+		}
+	}
+	return true
+}
+
+func splitHeaderList(value string) []string {
+	var names []string
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"weservefood/config"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+// corsRouter mirrors the server: CORS wraps a router whose routes are validated
+func corsRouter(cors config.CORS) http.Handler {
+	router := mux.NewRouter()
+	router.Use(ValidationMiddleware)
+	router.HandleFunc("/v1/orders", func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	}).Methods("GET", "POST")
+	return CORSMiddleware(cors)(router)
+}
+
+func testCORS() config.CORS {
+	cors := config.Default().CORS
+	cors.AllowedOrigins = []string{"https://app.example.com", "https://*.preview.example.com"}
+	cors.AllowCredentials = true
+	cors.MaxAge = 5 * time.Minute
+	return cors
+}
+
+func preflight(origin, method, headers string) *http.Request {
+	req, _ := http.NewRequest(http.MethodOptions, "/v1/orders", nil)
+	req.Header.Set("Origin", origin)
+	req.Header.Set("Access-Control-Request-Method", method)
+	if headers != "" {
+		req.Header.Set("Access-Control-Request-Headers", headers)
+	}
+	return req
+}
+
+func TestCORSPreflight(t *testing.T) {
+	rr := httptest.NewRecorder()
+	corsRouter(testCORS()).ServeHTTP(rr, preflight("https://app.example.com", http.MethodPost, "content-type, x-request-id"))
+
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	assert.Equal(t, "https://app.example.com", rr.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "true", rr.Header().Get("Access-Control-Allow-Credentials"))
+	assert.Equal(t, "GET, POST, PUT, PATCH, DELETE", rr.Header().Get("Access-Control-Allow-Methods"))
+	assert.Equal(t, "content-type, x-request-id", rr.Header().Get("Access-Control-Allow-Headers"))
+	assert.Equal(t, "300", rr.Header().Get("Access-Control-Max-Age"))
+	assert.Contains(t, rr.Header().Values("Vary"), "Origin")
+}
+
+func TestCORSPreflightRejected(t *testing.T) {
+	tests := map[string]*http.Request{
+		"origin":  preflight("https://evil.example.org", http.MethodPost, ""),
+		"method":  preflight("https://app.example.com", "TRACE", ""),
+		"headers": preflight("https://app.example.com", http.MethodPost, "X-Secret"),
+		"pattern": preflight("https://preview.example.com", http.MethodGet, ""),
+	}
+
+	for name, req := range tests {
+		rr := httptest.NewRecorder()
+		corsRouter(testCORS()).ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusForbidden, rr.Code, name)
+		assert.Empty(t, rr.Header().Get("Access-Control-Allow-Methods"), name)
+	}
+}
+
+func TestCORSSimpleRequest(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "/v1/orders", nil)
+	req.Header.Set("Origin", "https://pr-12.preview.example.com")
+	rr := httptest.NewRecorder()
+	corsRouter(testCORS()).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "https://pr-12.preview.example.com", rr.Header().Get("Access-Control-Allow-Origin"))
+	assert.Contains(t, rr.Header().Get("Access-Control-Expose-Headers"), RequestIDHeader)
+
+	req.Header.Set("Origin", "https://evil.example.org")
+	rr = httptest.NewRecorder()
+	corsRouter(testCORS()).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Empty(t, rr.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestCORSWildcardOrigin(t *testing.T) {
+	cors := config.Default().CORS
+	cors.AllowedOrigins = []string{"*"}
+	rr := httptest.NewRecorder()
+	corsRouter(cors).ServeHTTP(rr, preflight("https://anywhere.test", http.MethodGet, ""))
+
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	assert.Equal(t, "*", rr.Header().Get("Access-Control-Allow-Origin"))
+	assert.Empty(t, rr.Header().Get("Access-Control-Allow-Credentials"))
+}
+
+func TestCORSDisabled(t *testing.T) {
+	rr := httptest.NewRecorder()
+	corsRouter(config.Default().CORS).ServeHTTP(rr, preflight("https://app.example.com", http.MethodPost, ""))
+
+	assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+	assert.Empty(t, rr.Header().Get("Access-Control-Allow-Origin"))
+}
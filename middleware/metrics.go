@@ -0,0 +1,18 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+	"weservefood/metrics"
+)
+
+// MetricsMiddleware records the count and latency of requests per route and status
+func MetricsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: rw}
+		next.ServeHTTP(recorder, req)
+
+		metrics.ObserveRequest(routeTemplate(req), req.Method, recorder.Status(), time.Since(start))
+	})
+}
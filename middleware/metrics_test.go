@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"weservefood/metrics"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricsMiddleware(t *testing.T) {
+	router := mux.NewRouter()
+	router.Use(MetricsMiddleware)
+	router.HandleFunc("/metrics-test/{id}", func(rw http.ResponseWriter, req *http.Request) {
+		http.Error(rw, "missing", http.StatusNotFound)
+	})
+
+	req, _ := http.NewRequest(http.MethodGet, "/metrics-test/123", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+
+	req, _ = http.NewRequest(http.MethodGet, "/metrics", nil)
+	scrape := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(scrape, req)
+
+	assert.Contains(t, scrape.Body.String(), `weservefood_http_requests_total{method="GET",route="/metrics-test/{id}",status="404"}`)
+	assert.Contains(t, scrape.Body.String(), `weservefood_http_request_duration_seconds_count{method="GET",route="/metrics-test/{id}",status="404"}`)
+	assert.NotContains(t, scrape.Body.String(), "/metrics-test/123")
+}
+
+func TestMetricsMiddlewareKeepsFlusher(t *testing.T) {
+	handler := MetricsMiddleware(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte("partial"))
+		assert.NoError(t, http.NewResponseController(rw).Flush())
+	}))
+
+	req, _ := http.NewRequest(http.MethodGet, "/", nil)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	assert.True(t, rr.Flushed)
+	assert.Equal(t, "partial", rr.Body.String())
+}
@@ -1,16 +1,98 @@
 package middleware
 
 import (
-	"log"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"time"
+	"weservefood/logging"
 
 	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/trace"
 )
 
-// LoggingMiddleware logs the incoming requests
+// RequestIDHeader carries the ID correlating a request across services and logs
+const RequestIDHeader = "X-Request-ID"
+
+// LoggingMiddleware logs every request once it completes. The request keeps the
+// X-Request-ID it arrived with, or gets a new one, which is echoed in the
+// response. Handlers reach the request logger through logging.FromContext.
 func LoggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
-		log.Printf("%s - %s %s %s", req.Method, req.Host, req.RequestURI, req.RemoteAddr)
+		start := time.Now()
+		requestID := req.Header.Get(RequestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = newRequestID()
+		}
+		rw.Header().Set(RequestIDHeader, requestID)
+
+		logger := logging.FromContext(req.Context()).With(slog.String("request_id", requestID))
+		if span := trace.SpanContextFromContext(req.Context()); span.IsValid() {
+			logger = logger.With(slog.String("trace_id", span.TraceID().String()), slog.String("span_id", span.SpanID().String()))
+		}
+
+		recorder := &statusRecorder{ResponseWriter: rw}
+		next.ServeHTTP(recorder, req.WithContext(logging.WithLogger(req.Context(), logger)))
+
+		level := slog.LevelInfo
+		if recorder.Status() >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		logger.LogAttrs(req.Context(), level, "request",
+			slog.String("method", req.Method),
+			slog.String("path", req.URL.Path),
+			slog.String("route", routeTemplate(req)),
+			slog.Int("status", recorder.Status()),
+			slog.Int64("bytes", recorder.bytes),
+			slog.Float64("duration_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.String("principal", principal(req)),
+			slog.String("remote_addr", req.RemoteAddr),
+		)
+	})
+}
+
+// validRequestID accepts caller-supplied IDs that are safe to log and echo
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	id := make([]byte, 16)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// principal identifies the caller without logging credentials: the basic auth
+// user, a fingerprint of a bearer token, or "anonymous"
+func principal(req *http.Request) string {
+	if user, _, ok := req.BasicAuth(); ok {
+		return user
+	}
+	if token, ok := bearerToken(req); ok {
+		sum := sha256.Sum256([]byte(token))
+		return "token:" + hex.EncodeToString(sum[:4])
+	}
+	return "anonymous"
+}
+
+// DeprecationMiddleware marks a legacy route as deprecated. Responses carry the
+// Deprecation and Sunset headers along with a Link to the successor route.
+func DeprecationMiddleware(deprecatedAt, sunset time.Time, successor string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("Deprecation", fmt.Sprintf("@%d", deprecatedAt.Unix()))
+		rw.Header().Set("Sunset", sunset.UTC().Format(http.TimeFormat))
+		rw.Header().Set("Link", fmt.Sprintf("<%s>; rel=\"successor-version\"", successor))
 		next.ServeHTTP(rw, req)
 	})
 }
@@ -31,6 +113,10 @@ func ValidationMiddleware(next http.Handler) http.Handler {
 			if !validatePutRequest(rw, req) {
 				return
 			}
+		case http.MethodPatch:
+			if !validatePatchRequest(rw, req) {
+				return
+			}
 		case http.MethodDelete:
 			if !validateDeleteRequest(rw, req) {
 				return
@@ -47,7 +133,7 @@ func ValidationMiddleware(next http.Handler) http.Handler {
 // validatePostRequest validates the POST request
 func validatePostRequest(rw http.ResponseWriter, req *http.Request) bool {
 	if req.Body == nil {
-		log.Println(" Validation Failed: Missing request body")
+		logging.FromContext(req.Context()).Warn("validation failed", slog.String("reason", "missing request body"))
 		http.Error(rw, " Validation Failed: Missing request body", http.StatusBadRequest)
 		return false
 	}
@@ -58,7 +144,7 @@ func validatePostRequest(rw http.ResponseWriter, req *http.Request) bool {
 func validateGetRequest(rw http.ResponseWriter, req *http.Request) bool {
 	email := req.URL.Query().Get("email")
 	if email == "" && req.URL.Path == "/get-order" {
-		log.Println("  Validation Failed: Missing email in query parameter")
+		logging.FromContext(req.Context()).Warn("validation failed", slog.String("reason", "missing email in query parameter"))
 		http.Error(rw, " Validation Failed: Missing email in query parameter", http.StatusBadRequest)
 		return false
 	}
@@ -68,23 +154,42 @@ func validateGetRequest(rw http.ResponseWriter, req *http.Request) bool {
 // validatePutRequest validates the PUT request
 func validatePutRequest(rw http.ResponseWriter, req *http.Request) bool {
 	vars := mux.Vars(req)
-	email := vars["email"]
+	email, legacy := vars["email"]
+	if !legacy && vars["id"] != "" {
+		// only the legacy routes carry the email in the path
+		return true
+	}
 	orderId := vars["id"]
 	if email == "" || orderId == "" {
-		log.Println("  Validation Failed: Missing email or orderID parameter")
+		logging.FromContext(req.Context()).Warn("validation failed", slog.String("reason", "missing email or orderID parameter"))
 		http.Error(rw, " Validation Failed: Missing email or orderID parameter", http.StatusBadRequest)
 		return false
 	}
 	return true
 }
 
+// validatePatchRequest validates the PATCH request
+func validatePatchRequest(rw http.ResponseWriter, req *http.Request) bool {
+	orderId := mux.Vars(req)["id"]
+	if orderId == "" || req.Body == nil {
+		logging.FromContext(req.Context()).Warn("validation failed", slog.String("reason", "missing orderID parameter or request body"))
+		http.Error(rw, " Validation Failed: Missing orderID parameter or request body", http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
 // validateDeleteRequest validates the DELETE request
 func validateDeleteRequest(rw http.ResponseWriter, req *http.Request) bool {
 	vars := mux.Vars(req)
-	email := vars["email"]
+	email, legacy := vars["email"]
+	if !legacy && vars["id"] != "" {
+		// only the legacy routes carry the email in the path
+		return true
+	}
 	orderId := vars["id"]
 	if email == "" || orderId == "" {
-		log.Println(" Validation Failed: Missing email or orderID Parameter")
+		logging.FromContext(req.Context()).Warn("validation failed", slog.String("reason", "missing email or orderID parameter"))
 		http.Error(rw, " Validation Failed: Missing email or orderID  parameter", http.StatusBadRequest)
 		return false
 	}
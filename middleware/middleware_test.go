@@ -1,13 +1,19 @@
 package middleware
 
 import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
+	"weservefood/logging"
 
 	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestLoggingMiddleware(t *testing.T) {
@@ -23,6 +29,59 @@ func TestLoggingMiddleware(t *testing.T) {
 	assert.Equal(t, http.StatusOK, rr.Code)
 }
 
+func TestLoggingMiddlewareStructuredRecord(t *testing.T) {
+	var logs bytes.Buffer
+	base := logging.New(&logs, slog.LevelInfo)
+
+	router := mux.NewRouter()
+	router.Use(LoggingMiddleware)
+	router.HandleFunc("/orders/{id}", func(rw http.ResponseWriter, req *http.Request) {
+		logging.FromContext(req.Context()).Info("inside handler")
+		rw.WriteHeader(http.StatusAccepted)
+		rw.Write([]byte("hello"))
+	})
+
+	req, _ := http.NewRequest(http.MethodGet, "/orders/123", nil)
+	req.Header.Set(RequestIDHeader, "caller-id-1")
+	req.SetBasicAuth("ops", "secret")
+	req = req.WithContext(logging.WithLogger(req.Context(), base))
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, "caller-id-1", rr.Header().Get(RequestIDHeader))
+
+	lines := strings.Split(strings.TrimSpace(logs.String()), "\n")
+	require.Len(t, lines, 2)
+	var inside, record map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &inside))
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &record))
+
+	assert.Equal(t, "caller-id-1", inside["request_id"])
+	assert.Equal(t, "request", record["msg"])
+	assert.Equal(t, "caller-id-1", record["request_id"])
+	assert.Equal(t, "/orders/{id}", record["route"])
+	assert.Equal(t, float64(http.StatusAccepted), record["status"])
+	assert.Equal(t, float64(5), record["bytes"])
+	assert.Equal(t, "ops", record["principal"])
+	assert.Contains(t, record, "duration_ms")
+}
+
+func TestLoggingMiddlewareGeneratesRequestID(t *testing.T) {
+	handler := LoggingMiddleware(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {}))
+
+	req, _ := http.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(RequestIDHeader, "not a valid\nid")
+	req.Header.Set("Authorization", "Bearer secret-token")
+	var logs bytes.Buffer
+	req = req.WithContext(logging.WithLogger(req.Context(), logging.New(&logs, slog.LevelInfo)))
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	assert.Len(t, rr.Header().Get(RequestIDHeader), 32)
+	assert.NotContains(t, logs.String(), "secret-token")
+	assert.Contains(t, logs.String(), `"principal":"token:`)
+}
+
 func TestValidationMiddlewarePostRequest(t *testing.T) {
 	handler := ValidationMiddleware(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
 		rw.WriteHeader(http.StatusOK)
@@ -130,3 +189,64 @@ func TestValidationMiddlewareDeleteRequestMissingParams(t *testing.T) {
 
 	assert.Equal(t, http.StatusBadRequest, rr.Code)
 }
+
+func TestValidationMiddlewareRouteWithoutEmail(t *testing.T) {
+	handler := ValidationMiddleware(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	}))
+
+	for _, method := range []string{http.MethodPut, http.MethodDelete} {
+		req, _ := http.NewRequest(method, "/v1/admin/restaurants/r-1/zones/centre", nil)
+		req = mux.SetURLVars(req, map[string]string{"id": "r-1", "name": "centre"})
+		rr := httptest.NewRecorder()
+
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code, method)
+	}
+}
+
+func TestValidationMiddlewarePatchRequest(t *testing.T) {
+	handler := ValidationMiddleware(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	}))
+
+	req, _ := http.NewRequest(http.MethodPatch, "/v1/orders/123", strings.NewReader(`{"email":"test@example.com"}`))
+	req = mux.SetURLVars(req, map[string]string{"id": "123"})
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+}
+
+func TestValidationMiddlewarePatchRequestMissingParams(t *testing.T) {
+	handler := ValidationMiddleware(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	}))
+
+	req, _ := http.NewRequest(http.MethodPatch, "/v1/orders/", strings.NewReader(`{"email":"test@example.com"}`))
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestDeprecationMiddleware(t *testing.T) {
+	deprecatedAt := time.Date(2026, time.October, 1, 0, 0, 0, 0, time.UTC)
+	sunset := time.Date(2027, time.April, 1, 0, 0, 0, 0, time.UTC)
+	handler := DeprecationMiddleware(deprecatedAt, sunset, "/v1/orders", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	}))
+
+	req, _ := http.NewRequest(http.MethodGet, "/get-all-orders", nil)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "@1790812800", rr.Header().Get("Deprecation"))
+	assert.Equal(t, "Thu, 01 Apr 2027 00:00:00 GMT", rr.Header().Get("Sunset"))
+	assert.Equal(t, `</v1/orders>; rel="successor-version"`, rr.Header().Get("Link"))
+}
@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+	"weservefood/health"
+
+	"github.com/gorilla/mux"
+)
+
+// StartupMiddleware answers 503 while the server is starting, so no request
+// sees the order store before the orders are replayed. The routes whose
+// templates are listed, such as the probes, are always served.
+func StartupMiddleware(always ...string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			if health.CurrentPhase() == health.PhaseStarting && !slices.Contains(always, routeTemplate(req)) {
+				rw.Header().Set("Retry-After", "1")
+				http.Error(rw, "server is starting", http.StatusServiceUnavailable)
+				return
+			}
+			next.ServeHTTP(rw, req)
+		})
+	}
+}
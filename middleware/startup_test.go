@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"weservefood/health"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStartupMiddleware(t *testing.T) {
+	router := mux.NewRouter()
+	router.Use(StartupMiddleware("/readyz"))
+	for _, path := range []string{"/readyz", "/v1/orders"} {
+		router.HandleFunc(path, func(rw http.ResponseWriter, req *http.Request) {
+			rw.WriteHeader(http.StatusOK)
+		})
+	}
+	serve := func(path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", path, nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	health.SetPhase(health.PhaseStarting)
+	assert.Equal(t, http.StatusOK, serve("/readyz").Code)
+	starting := serve("/v1/orders")
+	assert.Equal(t, http.StatusServiceUnavailable, starting.Code)
+	assert.Equal(t, "1", starting.Header().Get("Retry-After"))
+
+	for _, phase := range []health.Phase{health.PhaseServing, health.PhaseDraining} {
+		health.SetPhase(phase)
+		assert.Equal(t, http.StatusOK, serve("/v1/orders").Code, phase)
+	}
+	health.SetPhase(health.PhaseStarting)
+}
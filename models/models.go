@@ -1,19 +1,494 @@
 package models
 
 import (
+	"strings"
 	"sync"
+	"time"
 )
 
+// OrderStatus is the lifecycle state of an order
+type OrderStatus string
+
+const (
+	StatusPlaced OrderStatus = "placed"
+	// StatusConfirmed is only reached once the payment has been authorized
+	StatusConfirmed OrderStatus = "confirmed"
+	// StatusPreparing is reached when the restaurant starts cooking; the payment is captured then
+	StatusPreparing      OrderStatus = "preparing"
+	StatusOutForDelivery OrderStatus = "out_for_delivery"
+	// StatusDelivered is only reached with the proof of delivery the order requires
+	StatusDelivered OrderStatus = "delivered"
+	StatusCancelled OrderStatus = "cancelled"
+)
+
+// OrderStatuses lists every lifecycle state in order. Exports have a
+// timestamp column for each of them.
+var OrderStatuses = []OrderStatus{StatusPlaced, StatusConfirmed, StatusPreparing, StatusOutForDelivery, StatusDelivered, StatusCancelled}
+
+// Valid reports whether the status is a known lifecycle state
+func (s OrderStatus) Valid() bool {
+	for _, status := range OrderStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// Final reports whether an order in the status is done with: delivered or cancelled
+func (s OrderStatus) Final() bool {
+	return s == StatusDelivered || s == StatusCancelled
+}
+
+// ProofMethod is the evidence a courier must give that an order was delivered
+type ProofMethod string
+
+const (
+	// ProofPIN is the code shown to the customer, which they tell the courier
+	ProofPIN       ProofMethod = "pin"
+	ProofPhoto     ProofMethod = "photo"
+	ProofSignature ProofMethod = "signature"
+)
+
+// Valid reports whether the method is a known kind of proof
+func (m ProofMethod) Valid() bool {
+	return m == ProofPIN || m == ProofPhoto || m == ProofSignature
+}
+
+// StatusChange records when an order entered a status
+type StatusChange struct {
+	Status OrderStatus `json:"status"`
+	At     time.Time   `json:"at"`
+}
+
+// PriceBreakdown itemises what an order costs, in cents
+type PriceBreakdown struct {
+	SubtotalCents    int `json:"subtotal_cents"`
+	DeliveryFeeCents int `json:"delivery_fee_cents"`
+	TotalCents       int `json:"total_cents"`
+}
+
 type Order struct {
-	ID           string   `json:"id"`
-	Name         string   `json:"name"`
-	Email        string   `json:"email"`
-	Address      string   `json:"address"`
-	Items        []string `json:"items"`
-	DeliveryTime string   `json:"delivery_time"`
+	ID           string      `json:"id"`
+	Name         string      `json:"name"`
+	Email        string      `json:"email"`
+	Address      string      `json:"address"`
+	Items        []string    `json:"items"`
+	DeliveryTime string      `json:"delivery_time"`
+	Status       OrderStatus `json:"status"`
+	RestaurantID string      `json:"restaurant_id,omitempty"`
+	CourierID    string      `json:"courier_id,omitempty"`
+	// Price is only known for orders placed against a restaurant menu
+	Price         PriceBreakdown `json:"price"`
+	CreatedAt     time.Time      `json:"created_at"`
+	StatusHistory []StatusChange `json:"status_history"`
+	// Payment is set once payment has been attempted for the order
+	Payment *PaymentIntent `json:"payment,omitempty"`
+	// Cancellation is set once the order is cancelled
+	Cancellation *Cancellation `json:"cancellation,omitempty"`
+	// DueAt is when the order is expected to be delivered
+	DueAt time.Time `json:"due_at"`
+	// PromisedAt is the delivery time the customer was given when ordering, or
+	// when they last changed the address; DueAt moves on from it as the order
+	// is fulfilled
+	PromisedAt time.Time `json:"promised_at,omitempty"`
+	// Estimate explains DueAt for orders placed against a restaurant; it is
+	// updated as the order moves on
+	Estimate *DeliveryEstimate `json:"eta,omitempty"`
+	// AddressHistory records every change of the delivery address, oldest first
+	AddressHistory []AddressChange `json:"address_history,omitempty"`
+	// DeliveryAddress is the structured form of Address; when it is given,
+	// Address is its single line form
+	DeliveryAddress *Address `json:"delivery_address,omitempty"`
+	// DeliveryZone names the restaurant zone the address is in
+	DeliveryZone string `json:"delivery_zone,omitempty"`
+	// BatchID is the courier run the order was dispatched in
+	BatchID string `json:"batch_id,omitempty"`
+	// CourierPosition is the courier's latest fix while the order is out for delivery
+	CourierPosition *LocationPing `json:"courier_position,omitempty"`
+	// ProofRequired is the proof the courier must give to complete the order
+	ProofRequired ProofMethod `json:"proof_required,omitempty" example:"pin"`
+	// DeliveryPIN is shown to the customer, who tells it to the courier on
+	// handover; it is only set when the order requires a PIN. It is only sent
+	// to the customer placing the order, as PlacedOrder.
+	DeliveryPIN string `json:"-"`
+	// Delivery records the proof given once the order is delivered
+	Delivery *DeliveryProof `json:"delivery,omitempty"`
+}
+
+// PlacedOrder is an order as returned to the customer who placed it, the only
+// response that carries its delivery PIN
+type PlacedOrder struct {
+	Order
+	DeliveryPIN string `json:"delivery_pin,omitempty" example:"4821"`
+}
+
+// Address is a structured delivery address
+type Address struct {
+	Street     string `json:"street" example:"Invalidenstraße 43"`
+	Unit       string `json:"unit,omitempty" example:"3rd floor"`
+	City       string `json:"city" example:"Berlin"`
+	PostalCode string `json:"postal_code" example:"10115"`
+	// Country is an ISO 3166-1 alpha-2 code
+	Country string `json:"country" example:"DE"`
+	// Location is filled in by geocoding unless the customer gives it
+	Location     *LatLng `json:"location,omitempty"`
+	Instructions string  `json:"delivery_instructions,omitempty" example:"ring twice"`
+}
+
+// String returns the address on a single line, without the instructions
+func (a Address) String() string {
+	locality := strings.TrimSpace(a.PostalCode + " " + a.City)
+	var parts []string
+	for _, part := range []string{a.Street, a.Unit, locality, a.Country} {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// LatLng is a point on the earth in decimal degrees
+type LatLng struct {
+	Lat float64 `json:"lat" example:"52.5323"`
+	Lng float64 `json:"lng" example:"13.3846"`
+}
+
+// Valid reports whether the point lies within the range of latitudes and longitudes
+func (p LatLng) Valid() bool {
+	return p.Lat >= -90 && p.Lat <= 90 && p.Lng >= -180 && p.Lng <= 180
+}
+
+// AddressChange records a delivery address being replaced
+type AddressChange struct {
+	From             string    `json:"from"`
+	To               string    `json:"to"`
+	DeliveryFeeCents int       `json:"delivery_fee_cents"`
+	At               time.Time `json:"at"`
+}
+
+// DeliveryEstimate breaks down how long was left until delivery when the
+// order was last estimated, in whole minutes rounded up
+type DeliveryEstimate struct {
+	// QueueMinutes is spent waiting for the orders ahead in the kitchen
+	QueueMinutes       int `json:"queue_minutes"`
+	PrepMinutes        int `json:"prep_minutes"`
+	CourierWaitMinutes int `json:"courier_wait_minutes"`
+	TravelMinutes      int `json:"travel_minutes"`
+	HandoverMinutes    int `json:"handover_minutes"`
+	// DistanceMetres is the ride along the road, when both ends are located
+	DistanceMetres int       `json:"distance_metres,omitempty"`
+	EstimatedAt    time.Time `json:"estimated_at"`
+}
+
+// DueAt returns when the order arrives according to the estimate
+func (e DeliveryEstimate) DueAt() time.Time {
+	total := e.QueueMinutes + e.PrepMinutes + e.CourierWaitMinutes + e.TravelMinutes + e.HandoverMinutes
+	return e.EstimatedAt.Add(time.Duration(total) * time.Minute)
+}
+
+// StatusAt returns when the order entered the status, if it did
+func (o Order) StatusAt(status OrderStatus) (time.Time, bool) {
+	for _, change := range o.StatusHistory {
+		if change.Status == status {
+			return change.At, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// SetStatus moves the order to a new status and records when it happened.
+// StatusHistory is copied so earlier snapshots of the order are left untouched.
+func (o *Order) SetStatus(status OrderStatus, at time.Time) {
+	o.Status = status
+	history := make([]StatusChange, len(o.StatusHistory), len(o.StatusHistory)+1)
+	copy(history, o.StatusHistory)
+	o.StatusHistory = append(history, StatusChange{Status: status, At: at})
+}
+
+// PaymentStatus is the state of the money for an order
+type PaymentStatus string
+
+const (
+	// PaymentFailed means the last authorization was declined; it may be retried
+	PaymentFailed     PaymentStatus = "failed"
+	PaymentAuthorized PaymentStatus = "authorized"
+	PaymentCaptured   PaymentStatus = "captured"
+	PaymentVoided     PaymentStatus = "voided"
+	// PaymentRefunded means everything captured has been refunded
+	PaymentRefunded PaymentStatus = "refunded"
+)
+
+// PaymentIntent tracks collecting the total of an order through the payment gateway
+type PaymentIntent struct {
+	ID              string        `json:"id"`
+	Status          PaymentStatus `json:"status"`
+	AmountCents     int           `json:"amount_cents"`
+	Currency        string        `json:"currency"`
+	AuthorizationID string        `json:"authorization_id,omitempty"`
+	CapturedCents   int           `json:"captured_cents"`
+	RefundedCents   int           `json:"refunded_cents"`
+	Refunds         []Refund      `json:"refunds,omitempty"`
+	FailureReason   string        `json:"failure_reason,omitempty"`
+	CreatedAt       time.Time     `json:"created_at"`
+	UpdatedAt       time.Time     `json:"updated_at"`
+}
+
+// Refund returns part or all of a captured payment
+type Refund struct {
+	ID          string    `json:"id"`
+	AmountCents int       `json:"amount_cents"`
+	Reason      string    `json:"reason"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// PaymentRequest authorizes the payment of an order
+type PaymentRequest struct {
+	Email string `json:"email"`
+	// PaymentMethod is the gateway token of the customer's card
+	PaymentMethod string `json:"payment_method" example:"tok_visa"`
+}
+
+// MenuItem is a dish offered by a restaurant, priced in cents
+type MenuItem struct {
+	Name       string `json:"name"`
+	PriceCents int    `json:"price_cents"`
+	// PrepMinutes is how long the dish takes to prepare; zero takes the default
+	PrepMinutes int `json:"prep_minutes,omitempty"`
+}
+
+// Restaurant prepares the orders placed against its menu
+type Restaurant struct {
+	ID      string     `json:"id"`
+	Name    string     `json:"name"`
+	Address string     `json:"address"`
+	Menu    []MenuItem `json:"menu"`
+	// Location is where couriers pick orders up, used to measure the ride
+	Location *LatLng `json:"location,omitempty"`
+	// Zones are the areas the restaurant delivers to; without zones it
+	// delivers anywhere at the default fee and time
+	Zones []DeliveryZone `json:"zones,omitempty"`
+}
+
+// DeliveryZone is an area a restaurant delivers to, with its own fee,
+// delivery time and minimum order
+type DeliveryZone struct {
+	Name string `json:"name"`
+	// Areas are postal codes or district names; an address is in the zone
+	// when its postal code or locality is one of them
+	Areas []string `json:"areas,omitempty"`
+	// Boundary outlines the zone; a located address is in the zone when it
+	// lies inside the polygon
+	Boundary         *Polygon `json:"boundary,omitempty"`
+	DeliveryFeeCents int      `json:"delivery_fee_cents"`
+	// DeliveryMinutes is the ride into the zone, used when the distance to an
+	// address cannot be measured
+	DeliveryMinutes int `json:"delivery_minutes"`
+	// MinimumOrderCents is the smallest subtotal delivered to the zone
+	MinimumOrderCents int `json:"minimum_order_cents,omitempty"`
+}
+
+// Polygon is a GeoJSON Polygon geometry: an outer ring followed by any holes,
+// each a closed ring of [longitude, latitude] positions
+type Polygon struct {
+	Type        string        `json:"type" example:"Polygon"`
+	Coordinates [][][]float64 `json:"coordinates"`
+}
+
+// Price returns the menu price of an item in cents
+func (r Restaurant) Price(item string) (int, bool) {
+	for _, menuItem := range r.Menu {
+		if menuItem.Name == item {
+			return menuItem.PriceCents, true
+		}
+	}
+	return 0, false
+}
+
+// Serves reports whether the item is on the restaurant's menu
+func (r Restaurant) Serves(item string) bool {
+	_, ok := r.Price(item)
+	return ok
+}
+
+// Batch is one courier's run from a restaurant through several drop-offs
+type Batch struct {
+	// ID is only set once the batch is dispatched
+	ID           string `json:"id,omitempty"`
+	RestaurantID string `json:"restaurant_id"`
+	// CourierID is empty when no courier is free to take the batch
+	CourierID      string      `json:"courier_id,omitempty"`
+	DepartAt       time.Time   `json:"depart_at"`
+	DistanceMetres int         `json:"distance_metres"`
+	Stops          []BatchStop `json:"stops"`
+	CreatedAt      time.Time   `json:"created_at"`
+}
+
+// BatchStop is a drop-off of a batch, listed in the order the courier makes them
+type BatchStop struct {
+	OrderID  string    `json:"order_id"`
+	Location LatLng    `json:"location"`
+	ArriveAt time.Time `json:"arrive_at"`
+	DueAt    time.Time `json:"due_at"`
+	// LegMetres is the ride from the previous stop, or from the restaurant
+	LegMetres int  `json:"leg_metres"`
+	OnTime    bool `json:"on_time"`
+}
+
+// LocationPing is a GPS fix reported by a courier's app
+type LocationPing struct {
+	Location LatLng `json:"location"`
+	// AccuracyMetres is the radius the fix is accurate to, when the app reports it
+	AccuracyMetres float64   `json:"accuracy_metres,omitempty" example:"12"`
+	RecordedAt     time.Time `json:"recorded_at"`
+}
+
+// CourierPings is a batch of fixes a courier's app sends, possibly buffered
+// while it was offline
+type CourierPings struct {
+	Pings []LocationPing `json:"pings"`
+}
+
+// PingReceipt lists the deliveries the pings were recorded against
+type PingReceipt struct {
+	Orders []string `json:"orders"`
+}
+
+// DeliveryTrail is the route a courier took with an order, oldest fix first
+type DeliveryTrail struct {
+	OrderID   string         `json:"order_id"`
+	CourierID string         `json:"courier_id,omitempty"`
+	Pings     []LocationPing `json:"pings"`
+	// DistanceMetres is the straight-line length of the trail
+	DistanceMetres int `json:"distance_metres"`
+}
+
+// DeliveryConfirmation is what a courier gives to complete an order. A photo
+// or signature is uploaded alongside it.
+type DeliveryConfirmation struct {
+	// CourierID is the courier delivering, taken from their bearer token
+	CourierID string `json:"-"`
+	PIN       string `json:"pin,omitempty"`
+	// Location is where the courier was on handover, when the app knows it
+	Location *LatLng `json:"location,omitempty"`
+}
+
+// DeliveryProof records how an order was shown to be delivered
+type DeliveryProof struct {
+	Method       ProofMethod `json:"method"`
+	CourierID    string      `json:"courier_id"`
+	PINConfirmed bool        `json:"pin_confirmed,omitempty"`
+	// PhotoKey and SignatureKey name the uploads in the blob store
+	PhotoKey     string    `json:"photo_key,omitempty"`
+	SignatureKey string    `json:"signature_key,omitempty"`
+	Location     *LatLng   `json:"location,omitempty"`
+	DeliveredAt  time.Time `json:"delivered_at"`
+}
+
+// Courier delivers orders to customers
+type Courier struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Phone     string `json:"phone"`
+	Available bool   `json:"available"`
 }
 
 type InMemoryStore struct {
 	Orders map[string]Order
 	Mutex  sync.Mutex
 }
+
+// Catalog holds the restaurants and couriers orders are placed with
+type Catalog struct {
+	Restaurants map[string]Restaurant
+	Couriers    map[string]Courier
+	Mutex       sync.RWMutex
+}
+
+// OrderPatch holds the changes accepted by PATCH /v1/orders/{id}
+type OrderPatch struct {
+	Email   string `json:"email"`
+	Address string `json:"address"`
+	// DeliveryAddress replaces the address with a structured one; Address is
+	// ignored when it is given
+	DeliveryAddress *Address `json:"delivery_address,omitempty"`
+	// ConfirmTotalCents accepts the new total when the address changes the price
+	ConfirmTotalCents int `json:"confirm_total_cents,omitempty"`
+}
+
+// CancelInitiator is who asks for an order to be cancelled
+type CancelInitiator string
+
+const (
+	InitiatorCustomer   CancelInitiator = "customer"
+	InitiatorRestaurant CancelInitiator = "restaurant"
+	InitiatorSupport    CancelInitiator = "support"
+)
+
+// Valid reports whether the initiator is known
+func (i CancelInitiator) Valid() bool {
+	return i == InitiatorCustomer || i == InitiatorRestaurant || i == InitiatorSupport
+}
+
+// Cancellation records who cancelled an order and why
+type Cancellation struct {
+	Initiator CancelInitiator `json:"initiator"`
+	Reason    string          `json:"reason"`
+	At        time.Time       `json:"at"`
+}
+
+// OrderCancellation asks for an order to be cancelled. Customers identify
+// themselves with the email of the order. Who is cancelling is never taken from
+// the request body: the route called sets Initiator, and RestaurantID for
+// restaurants.
+type OrderCancellation struct {
+	// Initiator defaults to customer
+	Initiator    CancelInitiator `json:"-"`
+	RestaurantID string          `json:"-"`
+	// Email is only needed from customers
+	Email  string `json:"email,omitempty"`
+	Reason string `json:"reason" example:"ordered by mistake"`
+}
+
+// OrderFilter selects orders by creation time, status and restaurant.
+// Zero fields match every order.
+type OrderFilter struct {
+	From         time.Time
+	To           time.Time
+	Status       OrderStatus
+	RestaurantID string
+}
+
+// Matches reports whether the order passes the filter. From is inclusive and
+// To is exclusive.
+func (f OrderFilter) Matches(order Order) bool {
+	if !f.From.IsZero() && order.CreatedAt.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && !order.CreatedAt.Before(f.To) {
+		return false
+	}
+	if f.Status != "" && order.Status != f.Status {
+		return false
+	}
+	if f.RestaurantID != "" && order.RestaurantID != f.RestaurantID {
+		return false
+	}
+	return true
+}
+
+// OrderStats summarises the order store for monitoring
+type OrderStats struct {
+	// Placed, Delivered and Cancelled count orders since the process started
+	Placed    int
+	Delivered int
+	Cancelled int
+	// Active counts orders per status, excluding delivered and cancelled ones
+	Active map[OrderStatus]int
+	// StoreSize is the number of orders held, cancelled ones included
+	StoreSize int
+	// Slots is how many orders the couriers in the catalog can carry at once
+	// and BusySlots how many of those are taken by active orders
+	Slots     int
+	BusySlots int
+}
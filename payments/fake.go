@@ -0,0 +1,142 @@
+package payments
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Payment methods understood by FakeGateway. Any other method is invalid.
+const (
+	MethodVisa              = "tok_visa"
+	MethodMastercard        = "tok_mastercard"
+	MethodDeclined          = "tok_declined"
+	MethodInsufficientFunds = "tok_insufficient_funds"
+)
+
+// FakeGateway is a deterministic in-memory gateway: the payment method alone
+// decides the outcome, and IDs are numbered in the order operations happen
+type FakeGateway struct {
+	mu             sync.Mutex
+	authorizations map[string]*FakeAuthorization
+	byKey          map[string]string
+	refunds        int
+}
+
+// FakeAuthorization is the state of an authorization in FakeGateway
+type FakeAuthorization struct {
+	Reference     string
+	AmountCents   int
+	CapturedCents int
+	RefundedCents int
+	Voided        bool
+}
+
+// NewFakeGateway returns an empty fake gateway
+func NewFakeGateway() *FakeGateway {
+	return &FakeGateway{
+		authorizations: make(map[string]*FakeAuthorization),
+		byKey:          make(map[string]string),
+	}
+}
+
+func (g *FakeGateway) Authorize(ctx context.Context, req AuthorizeRequest) (string, error) {
+	switch req.PaymentMethod {
+	case MethodVisa, MethodMastercard:
+	case MethodDeclined:
+		return "", fmt.Errorf("%w: card_declined", ErrDeclined)
+	case MethodInsufficientFunds:
+		return "", fmt.Errorf("%w: insufficient_funds", ErrDeclined)
+	default:
+		return "", fmt.Errorf("%w: %q", ErrInvalidPaymentMethod, req.PaymentMethod)
+	}
+	if req.AmountCents <= 0 {
+		return "", fmt.Errorf("%w: amount must be positive", ErrDeclined)
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if id, ok := g.byKey[req.IdempotencyKey]; ok && req.IdempotencyKey != "" {
+		return id, nil
+	}
+	id := fmt.Sprintf("auth_%06d", len(g.authorizations)+1)
+	g.authorizations[id] = &FakeAuthorization{Reference: req.Reference, AmountCents: req.AmountCents}
+	g.byKey[req.IdempotencyKey] = id
+	return id, nil
+}
+
+func (g *FakeGateway) Capture(ctx context.Context, authorizationID string, amountCents int) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	auth, err := g.authorization(authorizationID)
+	if err != nil {
+		return err
+	}
+	if auth.Voided || auth.CapturedCents > 0 || amountCents > auth.AmountCents {
+		return fmt.Errorf("%w: authorization %s cannot be captured", ErrInvalidTransition, authorizationID)
+	}
+	auth.CapturedCents = amountCents
+	return nil
+}
+
+func (g *FakeGateway) Void(ctx context.Context, authorizationID string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	auth, err := g.authorization(authorizationID)
+	if err != nil {
+		return err
+	}
+	if auth.CapturedCents > 0 {
+		return fmt.Errorf("%w: authorization %s is captured", ErrInvalidTransition, authorizationID)
+	}
+	auth.Voided = true
+	return nil
+}
+
+func (g *FakeGateway) Refund(ctx context.Context, authorizationID string, amountCents int) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	auth, err := g.authorization(authorizationID)
+	if err != nil {
+		return "", err
+	}
+	if amountCents <= 0 || auth.RefundedCents+amountCents > auth.CapturedCents {
+		return "", fmt.Errorf("%w: cannot refund %d cents of authorization %s", ErrInvalidTransition, amountCents, authorizationID)
+	}
+	auth.RefundedCents += amountCents
+	g.refunds++
+	return fmt.Sprintf("re_%06d", g.refunds), nil
+}
+
+func (g *FakeGateway) Reference(ctx context.Context, authorizationID string) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	auth, err := g.authorization(authorizationID)
+	if err != nil {
+		return "", err
+	}
+	return auth.Reference, nil
+}
+
+// Authorization returns a copy of an authorization, for assertions in tests
+func (g *FakeGateway) Authorization(authorizationID string) (FakeAuthorization, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	auth, ok := g.authorizations[authorizationID]
+	if !ok {
+		return FakeAuthorization{}, false
+	}
+	return *auth, true
+}
+
+func (g *FakeGateway) authorization(authorizationID string) (*FakeAuthorization, error) {
+	auth, ok := g.authorizations[authorizationID]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown authorization %s", ErrInvalidTransition, authorizationID)
+	}
+	return auth, nil
+}
@@ -0,0 +1,172 @@
+// Package payments collects the money for orders through a PaymentGateway.
+// Payment intents are kept on the order they belong to; the functions here
+// move an intent through authorization, capture, void and refund.
+package payments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+	"weservefood/models"
+)
+
+var (
+	// ErrDeclined is returned when the gateway refuses a payment method
+	ErrDeclined = errors.New("payment declined")
+	// ErrInvalidPaymentMethod is returned for a payment method the gateway does not know
+	ErrInvalidPaymentMethod = errors.New("invalid payment method")
+	// ErrInvalidTransition is returned when an intent is not in a state that allows the operation
+	ErrInvalidTransition = errors.New("payment is not in a state that allows this")
+	// ErrForeignPayment is returned for an intent whose authorization was not made for its order
+	ErrForeignPayment = errors.New("payment does not belong to the order")
+)
+
+// Currency is charged for every order
+var Currency = "USD"
+
+// AuthorizeRequest asks the gateway to hold an amount on a payment method
+type AuthorizeRequest struct {
+	AmountCents   int
+	Currency      string
+	PaymentMethod string
+	// IdempotencyKey makes retrying the same authorization safe
+	IdempotencyKey string
+	// Reference names what the authorization pays for: the payment intent ID
+	Reference string
+}
+
+// PaymentGateway is a payment service provider. Declines are reported as
+// errors wrapping ErrDeclined.
+type PaymentGateway interface {
+	// Authorize holds the amount and returns the authorization ID
+	Authorize(ctx context.Context, req AuthorizeRequest) (authorizationID string, err error)
+	// Capture collects up to the authorized amount
+	Capture(ctx context.Context, authorizationID string, amountCents int) error
+	// Void releases an authorization that was not captured
+	Void(ctx context.Context, authorizationID string) error
+	// Refund returns part or all of the captured amount and returns the refund ID
+	Refund(ctx context.Context, authorizationID string, amountCents int) (refundID string, err error)
+	// Reference returns the reference the authorization was made with
+	Reference(ctx context.Context, authorizationID string) (string, error)
+}
+
+// IntentID is the ID of the payment intent of an order
+func IntentID(orderID string) string {
+	return "pi_" + orderID
+}
+
+// NewIntent starts collecting the amount for an order
+func NewIntent(orderID string, amountCents int) *models.PaymentIntent {
+	now := time.Now().UTC()
+	return &models.PaymentIntent{
+		ID:          IntentID(orderID),
+		AmountCents: amountCents,
+		Currency:    Currency,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+}
+
+// Authorize holds the intent's amount on the payment method. A declined
+// authorization marks the intent failed and can be retried with another method.
+func Authorize(ctx context.Context, gateway PaymentGateway, intent *models.PaymentIntent, paymentMethod string) error {
+	if intent.Status != "" && intent.Status != models.PaymentFailed {
+		return fmt.Errorf("%w: cannot authorize a %s payment", ErrInvalidTransition, intent.Status)
+	}
+
+	authorizationID, err := gateway.Authorize(ctx, AuthorizeRequest{
+		AmountCents:    intent.AmountCents,
+		Currency:       intent.Currency,
+		PaymentMethod:  paymentMethod,
+		IdempotencyKey: intent.ID + ":" + paymentMethod,
+		Reference:      intent.ID,
+	})
+	intent.UpdatedAt = time.Now().UTC()
+	if err != nil {
+		intent.Status = models.PaymentFailed
+		intent.FailureReason = err.Error()
+		return err
+	}
+
+	intent.Status = models.PaymentAuthorized
+	intent.AuthorizationID = authorizationID
+	intent.FailureReason = ""
+	return nil
+}
+
+// Verify checks that the intent is the order's and that the gateway authorized
+// it for that intent, so money is only moved on authorizations this service made
+// for the order
+func Verify(ctx context.Context, gateway PaymentGateway, intent *models.PaymentIntent, orderID string) error {
+	if intent.ID != IntentID(orderID) {
+		return fmt.Errorf("%w: intent %s is not the intent of order %s", ErrForeignPayment, intent.ID, orderID)
+	}
+	reference, err := gateway.Reference(ctx, intent.AuthorizationID)
+	if err != nil {
+		return err
+	}
+	if reference != intent.ID {
+		return fmt.Errorf("%w: authorization %s was made for %s", ErrForeignPayment, intent.AuthorizationID, reference)
+	}
+	return nil
+}
+
+// Capture collects the full authorized amount
+func Capture(ctx context.Context, gateway PaymentGateway, intent *models.PaymentIntent) error {
+	if intent.Status != models.PaymentAuthorized {
+		return fmt.Errorf("%w: cannot capture a %s payment", ErrInvalidTransition, intent.Status)
+	}
+	if err := gateway.Capture(ctx, intent.AuthorizationID, intent.AmountCents); err != nil {
+		return err
+	}
+
+	intent.Status = models.PaymentCaptured
+	intent.CapturedCents = intent.AmountCents
+	intent.UpdatedAt = time.Now().UTC()
+	return nil
+}
+
+// Void releases an authorized payment without collecting it
+func Void(ctx context.Context, gateway PaymentGateway, intent *models.PaymentIntent) error {
+	if intent.Status != models.PaymentAuthorized {
+		return fmt.Errorf("%w: cannot void a %s payment", ErrInvalidTransition, intent.Status)
+	}
+	if err := gateway.Void(ctx, intent.AuthorizationID); err != nil {
+		return err
+	}
+
+	intent.Status = models.PaymentVoided
+	intent.UpdatedAt = time.Now().UTC()
+	return nil
+}
+
+// Refund returns part of the captured amount and records the refund on the
+// intent. The intent is marked refunded once nothing captured is left.
+func Refund(ctx context.Context, gateway PaymentGateway, intent *models.PaymentIntent, amountCents int, reason string) error {
+	if intent.Status != models.PaymentCaptured {
+		return fmt.Errorf("%w: cannot refund a %s payment", ErrInvalidTransition, intent.Status)
+	}
+	if amountCents <= 0 || intent.RefundedCents+amountCents > intent.CapturedCents {
+		return fmt.Errorf("%w: cannot refund %d of %d cents", ErrInvalidTransition, amountCents, intent.CapturedCents-intent.RefundedCents)
+	}
+	refundID, err := gateway.Refund(ctx, intent.AuthorizationID, amountCents)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	// copied rather than appended in place, so earlier snapshots of the intent keep their refunds
+	intent.Refunds = append(intent.Refunds[:len(intent.Refunds):len(intent.Refunds)], models.Refund{
+		ID:          refundID,
+		AmountCents: amountCents,
+		Reason:      reason,
+		CreatedAt:   now,
+	})
+	intent.RefundedCents += amountCents
+	if intent.RefundedCents == intent.CapturedCents {
+		intent.Status = models.PaymentRefunded
+	}
+	intent.UpdatedAt = now
+	return nil
+}
@@ -0,0 +1,138 @@
+package payments
+
+import (
+	"context"
+	"testing"
+	"weservefood/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthorizeAndCapture(t *testing.T) {
+	gateway := NewFakeGateway()
+	intent := NewIntent("order-1", 1299)
+
+	require.NoError(t, Authorize(context.Background(), gateway, intent, MethodVisa))
+	assert.Equal(t, models.PaymentAuthorized, intent.Status)
+	assert.Equal(t, "auth_000001", intent.AuthorizationID)
+	assert.Equal(t, "USD", intent.Currency)
+
+	require.NoError(t, Capture(context.Background(), gateway, intent))
+	assert.Equal(t, models.PaymentCaptured, intent.Status)
+	assert.Equal(t, 1299, intent.CapturedCents)
+	auth, ok := gateway.Authorization(intent.AuthorizationID)
+	require.True(t, ok)
+	assert.Equal(t, FakeAuthorization{Reference: "pi_order-1", AmountCents: 1299, CapturedCents: 1299}, auth)
+
+	assert.ErrorIs(t, Capture(context.Background(), gateway, intent), ErrInvalidTransition)
+	assert.ErrorIs(t, Void(context.Background(), gateway, intent), ErrInvalidTransition)
+	assert.ErrorIs(t, Authorize(context.Background(), gateway, intent, MethodVisa), ErrInvalidTransition)
+}
+
+func TestAuthorizeDeclined(t *testing.T) {
+	gateway := NewFakeGateway()
+	intent := NewIntent("order-2", 500)
+
+	err := Authorize(context.Background(), gateway, intent, MethodInsufficientFunds)
+	assert.ErrorIs(t, err, ErrDeclined)
+	assert.Equal(t, models.PaymentFailed, intent.Status)
+	assert.Equal(t, "payment declined: insufficient_funds", intent.FailureReason)
+	assert.Empty(t, intent.AuthorizationID)
+
+	assert.ErrorIs(t, Authorize(context.Background(), gateway, intent, "tok_unknown"), ErrInvalidPaymentMethod)
+
+	// a failed payment may be retried with another method
+	require.NoError(t, Authorize(context.Background(), gateway, intent, MethodMastercard))
+	assert.Equal(t, models.PaymentAuthorized, intent.Status)
+	assert.Empty(t, intent.FailureReason)
+}
+
+func TestVoid(t *testing.T) {
+	gateway := NewFakeGateway()
+	intent := NewIntent("order-3", 800)
+	require.NoError(t, Authorize(context.Background(), gateway, intent, MethodVisa))
+
+	require.NoError(t, Void(context.Background(), gateway, intent))
+	assert.Equal(t, models.PaymentVoided, intent.Status)
+	auth, _ := gateway.Authorization(intent.AuthorizationID)
+	assert.True(t, auth.Voided)
+
+	assert.ErrorIs(t, Capture(context.Background(), gateway, intent), ErrInvalidTransition)
+	assert.ErrorIs(t, gateway.Capture(context.Background(), intent.AuthorizationID, 800), ErrInvalidTransition)
+}
+
+func TestVerify(t *testing.T) {
+	gateway := NewFakeGateway()
+	intent := NewIntent("order-5", 800)
+	require.NoError(t, Authorize(context.Background(), gateway, intent, MethodVisa))
+	other := NewIntent("order-6", 800)
+	require.NoError(t, Authorize(context.Background(), gateway, other, MethodVisa))
+
+	assert.NoError(t, Verify(context.Background(), gateway, intent, "order-5"))
+	assert.ErrorIs(t, Verify(context.Background(), gateway, intent, "order-6"), ErrForeignPayment)
+
+	// an intent claiming another order's authorization
+	forged := *intent
+	forged.AuthorizationID = other.AuthorizationID
+	assert.ErrorIs(t, Verify(context.Background(), gateway, &forged, "order-5"), ErrForeignPayment)
+
+	forged.AuthorizationID = "auth_unknown"
+	assert.ErrorIs(t, Verify(context.Background(), gateway, &forged, "order-5"), ErrInvalidTransition)
+}
+
+func TestFakeGateway(t *testing.T) {
+	gateway := NewFakeGateway()
+	ctx := context.Background()
+
+	first, err := gateway.Authorize(ctx, AuthorizeRequest{AmountCents: 1000, PaymentMethod: MethodVisa, IdempotencyKey: "a"})
+	require.NoError(t, err)
+	again, err := gateway.Authorize(ctx, AuthorizeRequest{AmountCents: 1000, PaymentMethod: MethodVisa, IdempotencyKey: "a"})
+	require.NoError(t, err)
+	second, err := gateway.Authorize(ctx, AuthorizeRequest{AmountCents: 1000, PaymentMethod: MethodVisa, IdempotencyKey: "b"})
+	require.NoError(t, err)
+	assert.Equal(t, "auth_000001", first)
+	assert.Equal(t, first, again)
+	assert.Equal(t, "auth_000002", second)
+
+	_, err = gateway.Refund(ctx, first, 100)
+	assert.ErrorIs(t, err, ErrInvalidTransition, "nothing captured yet")
+	require.NoError(t, gateway.Capture(ctx, first, 1000))
+	refundID, err := gateway.Refund(ctx, first, 400)
+	require.NoError(t, err)
+	assert.Equal(t, "re_000001", refundID)
+	refundID, err = gateway.Refund(ctx, first, 600)
+	require.NoError(t, err)
+	assert.Equal(t, "re_000002", refundID)
+	_, err = gateway.Refund(ctx, first, 1)
+	assert.ErrorIs(t, err, ErrInvalidTransition)
+
+	assert.ErrorIs(t, gateway.Void(ctx, "auth_999999"), ErrInvalidTransition)
+	_, err = gateway.Authorize(ctx, AuthorizeRequest{AmountCents: 0, PaymentMethod: MethodVisa})
+	assert.ErrorIs(t, err, ErrDeclined)
+}
+
+func TestRefund(t *testing.T) {
+	gateway := NewFakeGateway()
+	intent := NewIntent("order-4", 1000)
+	require.NoError(t, Authorize(context.Background(), gateway, intent, MethodVisa))
+	assert.ErrorIs(t, Refund(context.Background(), gateway, intent, 100, "early"), ErrInvalidTransition, "not captured yet")
+	require.NoError(t, Capture(context.Background(), gateway, intent))
+
+	require.NoError(t, Refund(context.Background(), gateway, intent, 400, "partial"))
+	assert.Equal(t, models.PaymentCaptured, intent.Status)
+	assert.Equal(t, 400, intent.RefundedCents)
+	snapshot := intent.Refunds
+
+	assert.ErrorIs(t, Refund(context.Background(), gateway, intent, 601, "too much"), ErrInvalidTransition)
+	require.NoError(t, Refund(context.Background(), gateway, intent, 600, "rest"))
+	assert.Equal(t, models.PaymentRefunded, intent.Status)
+	assert.Equal(t, 1000, intent.RefundedCents)
+	require.Len(t, intent.Refunds, 2)
+	assert.Equal(t, models.Refund{ID: "re_000002", AmountCents: 600, Reason: "rest", CreatedAt: intent.Refunds[1].CreatedAt}, intent.Refunds[1])
+	assert.Len(t, snapshot, 1)
+
+	auth, _ := gateway.Authorization(intent.AuthorizationID)
+	assert.Equal(t, 1000, auth.RefundedCents)
+	assert.ErrorIs(t, Refund(context.Background(), gateway, intent, 1, "again"), ErrInvalidTransition)
+}
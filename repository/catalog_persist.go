@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"sync"
+	"weservefood/models"
+)
+
+// catalogFile remembers the file the catalog is saved to when delivery zones
+// change. Its lock also orders zone changes, so a later change is never
+// overwritten on disk by an earlier one.
+var catalogFile struct {
+	sync.Mutex
+	path string
+}
+
+// SaveCatalog writes the restaurants and couriers, ordered by ID, as a JSON
+// document LoadCatalog reads back
+func SaveCatalog(w io.Writer) error {
+	restaurants, couriers := GetRestaurants(), GetCouriers()
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(struct {
+		Restaurants []models.Restaurant `json:"restaurants"`
+		Couriers    []models.Courier    `json:"couriers"`
+	}{restaurants, couriers})
+}
+
+// OpenCatalog loads the catalog saved at path, when the file exists, and
+// makes PutZone and DeleteZone save the catalog back to it
+func OpenCatalog(path string) error {
+	file, err := os.Open(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return err
+	default:
+		defer file.Close()
+		if err := LoadCatalog(file); err != nil {
+			return err
+		}
+	}
+
+	catalogFile.Lock()
+	catalogFile.path = path
+	catalogFile.Unlock()
+	return nil
+}
+
+// saveCatalog replaces the file given to OpenCatalog with the catalog as it
+// is now. The caller holds catalogFile.
+func saveCatalog() error {
+	if catalogFile.path == "" {
+		return nil
+	}
+	return writeFile(catalogFile.path, SaveCatalog)
+}
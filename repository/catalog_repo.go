@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"weservefood/models"
+)
+
+var (
+	ErrRestaurantNotFound = errors.New("restaurant not found")
+	ErrCourierNotFound    = errors.New("courier not found")
+)
+
+var catalog = models.Catalog{
+	Restaurants: make(map[string]models.Restaurant),
+	Couriers:    make(map[string]models.Courier),
+}
+
+// LoadCatalog adds the restaurants and couriers of a JSON catalog document.
+// Nothing is added when a delivery zone is invalid.
+func LoadCatalog(r io.Reader) error {
+	var document struct {
+		Restaurants []models.Restaurant `json:"restaurants"`
+		Couriers    []models.Courier    `json:"couriers"`
+	}
+	if err := json.NewDecoder(r).Decode(&document); err != nil {
+		return err
+	}
+
+	for _, restaurant := range document.Restaurants {
+		names := make(map[string]bool, len(restaurant.Zones))
+		for _, zone := range restaurant.Zones {
+			if err := validateZone(zone); err != nil {
+				return fmt.Errorf("restaurant %s: %w", restaurant.ID, err)
+			}
+			if names[zone.Name] {
+				return fmt.Errorf("restaurant %s: %w: zone %s is listed twice", restaurant.ID, ErrInvalidZone, zone.Name)
+			}
+			names[zone.Name] = true
+		}
+	}
+	for _, restaurant := range document.Restaurants {
+		AddRestaurant(restaurant)
+	}
+	for _, courier := range document.Couriers {
+		AddCourier(courier)
+	}
+
+	return nil
+}
+
+// AddRestaurant adds or replaces a restaurant in the catalog
+func AddRestaurant(restaurant models.Restaurant) {
+	catalog.Mutex.Lock()
+	catalog.Restaurants[restaurant.ID] = restaurant
+	catalog.Mutex.Unlock()
+}
+
+// AddCourier adds or replaces a courier in the catalog
+func AddCourier(courier models.Courier) {
+	catalog.Mutex.Lock()
+	catalog.Couriers[courier.ID] = courier
+	catalog.Mutex.Unlock()
+}
+
+// GetRestaurants retrieves all restaurants ordered by ID
+func GetRestaurants() []models.Restaurant {
+	catalog.Mutex.RLock()
+	restaurants := make([]models.Restaurant, 0, len(catalog.Restaurants))
+	for _, restaurant := range catalog.Restaurants {
+		restaurants = append(restaurants, restaurant)
+	}
+	catalog.Mutex.RUnlock()
+
+	sort.Slice(restaurants, func(i, j int) bool { return restaurants[i].ID < restaurants[j].ID })
+	return restaurants
+}
+
+// GetRestaurant retrieves a single restaurant by its ID
+func GetRestaurant(restaurantID string) (models.Restaurant, error) {
+	catalog.Mutex.RLock()
+	defer catalog.Mutex.RUnlock()
+
+	restaurant, exist := catalog.Restaurants[restaurantID]
+	if !exist {
+		return models.Restaurant{}, ErrRestaurantNotFound
+	}
+
+	return restaurant, nil
+}
+
+// GetRestaurantsByIDs retrieves the restaurants with the given IDs in one lookup.
+// Unknown IDs are left out of the result.
+func GetRestaurantsByIDs(restaurantIDs []string) map[string]models.Restaurant {
+	catalog.Mutex.RLock()
+	defer catalog.Mutex.RUnlock()
+
+	restaurants := make(map[string]models.Restaurant, len(restaurantIDs))
+	for _, id := range restaurantIDs {
+		if restaurant, exist := catalog.Restaurants[id]; exist {
+			restaurants[id] = restaurant
+		}
+	}
+
+	return restaurants
+}
+
+// GetCouriers retrieves all couriers ordered by ID
+func GetCouriers() []models.Courier {
+	catalog.Mutex.RLock()
+	couriers := make([]models.Courier, 0, len(catalog.Couriers))
+	for _, courier := range catalog.Couriers {
+		couriers = append(couriers, courier)
+	}
+	catalog.Mutex.RUnlock()
+
+	sort.Slice(couriers, func(i, j int) bool { return couriers[i].ID < couriers[j].ID })
+	return couriers
+}
+
+// GetCouriersByIDs retrieves the couriers with the given IDs in one lookup.
+// Unknown IDs are left out of the result.
+func GetCouriersByIDs(courierIDs []string) map[string]models.Courier {
+	catalog.Mutex.RLock()
+	defer catalog.Mutex.RUnlock()
+
+	couriers := make(map[string]models.Courier, len(courierIDs))
+	for _, id := range courierIDs {
+		if courier, exist := catalog.Couriers[id]; exist {
+			couriers[id] = courier
+		}
+	}
+
+	return couriers
+}
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"weservefood/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadCatalog(t *testing.T) {
+	document := `{
+		"restaurants": [{"id": "r-test", "name": "Test Kitchen", "menu": [{"name": "Soup", "price_cents": 500}]}],
+		"couriers": [{"id": "c-test", "name": "Tess", "available": true}]
+	}`
+
+	err := LoadCatalog(strings.NewReader(document))
+	assert.NoError(t, err)
+
+	restaurant, err := GetRestaurant("r-test")
+	assert.NoError(t, err)
+	assert.Equal(t, "Test Kitchen", restaurant.Name)
+	assert.Equal(t, []models.MenuItem{{Name: "Soup", PriceCents: 500}}, restaurant.Menu)
+	assert.Contains(t, GetCouriers(), models.Courier{ID: "c-test", Name: "Tess", Available: true})
+}
+
+func TestLoadCatalogInvalid(t *testing.T) {
+	err := LoadCatalog(strings.NewReader("not json"))
+	assert.Error(t, err)
+}
+
+func TestGetRestaurantsByIDs(t *testing.T) {
+	AddRestaurant(models.Restaurant{ID: "r-one", Name: "One"})
+	AddRestaurant(models.Restaurant{ID: "r-two", Name: "Two"})
+
+	restaurants := GetRestaurantsByIDs([]string{"r-one", "r-two", "r-missing"})
+	assert.Len(t, restaurants, 2)
+	assert.Equal(t, "Two", restaurants["r-two"].Name)
+}
+
+func TestGetRestaurantNotFound(t *testing.T) {
+	_, err := GetRestaurant("r-missing")
+	assert.ErrorIs(t, err, ErrRestaurantNotFound)
+}
+
+func TestCreateOrderUnknownRestaurant(t *testing.T) {
+	_, err := CreateOrder(context.Background(), models.Order{Email: "test@example.com", Address: "123 Test St", RestaurantID: "r-missing"})
+	assert.ErrorIs(t, err, ErrRestaurantNotFound)
+}
+
+func TestAssignCourier(t *testing.T) {
+	AddCourier(models.Courier{ID: "c-assign", Name: "Ada", Available: true})
+	createdOrder, err := CreateOrder(context.Background(), models.Order{Email: "test@example.com", Address: "123 Test St"})
+	assert.NoError(t, err)
+
+	order, err := AssignCourier(context.Background(), createdOrder.ID, "c-assign")
+	assert.NoError(t, err)
+	assert.Equal(t, "c-assign", order.CourierID)
+	t.Cleanup(func() {
+		CancelOrder(context.Background(), createdOrder.ID, models.OrderCancellation{Email: "test@example.com", Reason: "changed my mind"})
+	})
+
+	_, err = AssignCourier(context.Background(), createdOrder.ID, "c-missing")
+	assert.ErrorIs(t, err, ErrCourierNotFound)
+}
+
+func TestAssignCourierSlotCapacity(t *testing.T) {
+	AddCourier(models.Courier{ID: "c-capacity", Name: "Cap"})
+	email := "capacity@example.com"
+	first, err := CreateOrder(context.Background(), models.Order{Email: email, Address: "1 Slot St"})
+	assert.NoError(t, err)
+	second, err := CreateOrder(context.Background(), models.Order{Email: email, Address: "2 Slot St"})
+	assert.NoError(t, err)
+
+	_, err = AssignCourier(context.Background(), first.ID, "c-capacity")
+	assert.NoError(t, err)
+	_, err = AssignCourier(context.Background(), first.ID, "c-capacity")
+	assert.NoError(t, err, "reassigning the same courier keeps its slot")
+	_, err = AssignCourier(context.Background(), second.ID, "c-capacity")
+	assert.ErrorIs(t, err, ErrCourierFull)
+
+	_, err = CancelOrder(context.Background(), first.ID, models.OrderCancellation{Email: email, Reason: "changed my mind"})
+	assert.NoError(t, err)
+	_, err = AssignCourier(context.Background(), second.ID, "c-capacity")
+	assert.NoError(t, err)
+	t.Cleanup(func() {
+		CancelOrder(context.Background(), second.ID, models.OrderCancellation{Email: email, Reason: "changed my mind"})
+	})
+}
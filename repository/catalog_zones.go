@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"weservefood/geo"
+	"weservefood/models"
+)
+
+var (
+	ErrZoneNotFound = errors.New("delivery zone not found")
+	ErrInvalidZone  = errors.New("invalid delivery zone")
+)
+
+// GetZones retrieves the delivery zones of a restaurant in the order they are matched
+func GetZones(restaurantID string) ([]models.DeliveryZone, error) {
+	restaurant, err := GetRestaurant(restaurantID)
+	if err != nil {
+		return nil, err
+	}
+	return slices.Clone(restaurant.Zones), nil
+}
+
+// PutZone adds a delivery zone to a restaurant, or replaces the zone of the
+// same name in its place, and saves the catalog. It reports whether the zone
+// was added.
+func PutZone(restaurantID string, zone models.DeliveryZone) (created bool, err error) {
+	if err := validateZone(zone); err != nil {
+		return false, err
+	}
+
+	catalogFile.Lock()
+	defer catalogFile.Unlock()
+	catalog.Mutex.Lock()
+
+	restaurant, exist := catalog.Restaurants[restaurantID]
+	if !exist {
+		catalog.Mutex.Unlock()
+		return false, ErrRestaurantNotFound
+	}
+	previous := restaurant
+	// copied, so restaurants already handed out keep their zones
+	zones := slices.Clone(restaurant.Zones)
+	index := slices.IndexFunc(zones, func(z models.DeliveryZone) bool { return z.Name == zone.Name })
+	if index < 0 {
+		zones = append(zones, zone)
+	} else {
+		zones[index] = zone
+	}
+	restaurant.Zones = zones
+	catalog.Restaurants[restaurantID] = restaurant
+	catalog.Mutex.Unlock()
+
+	if err := saveZones(previous); err != nil {
+		return false, err
+	}
+	return index < 0, nil
+}
+
+// DeleteZone removes a delivery zone from a restaurant and saves the catalog.
+// Orders already placed in the zone keep their price.
+func DeleteZone(restaurantID, name string) error {
+	catalogFile.Lock()
+	defer catalogFile.Unlock()
+	catalog.Mutex.Lock()
+
+	restaurant, exist := catalog.Restaurants[restaurantID]
+	if !exist {
+		catalog.Mutex.Unlock()
+		return ErrRestaurantNotFound
+	}
+	index := slices.IndexFunc(restaurant.Zones, func(z models.DeliveryZone) bool { return z.Name == name })
+	if index < 0 {
+		catalog.Mutex.Unlock()
+		return ErrZoneNotFound
+	}
+	previous := restaurant
+	restaurant.Zones = slices.Delete(slices.Clone(restaurant.Zones), index, index+1)
+	catalog.Restaurants[restaurantID] = restaurant
+	catalog.Mutex.Unlock()
+
+	return saveZones(previous)
+}
+
+// saveZones saves the catalog after a zone change, and puts the restaurant's
+// previous zones back when that fails, so what is served matches the file.
+// The caller holds catalogFile.
+func saveZones(previous models.Restaurant) error {
+	err := saveCatalog()
+	if err != nil {
+		catalog.Mutex.Lock()
+		restaurant := catalog.Restaurants[previous.ID]
+		restaurant.Zones = previous.Zones
+		catalog.Restaurants[previous.ID] = restaurant
+		catalog.Mutex.Unlock()
+		return fmt.Errorf("saving the catalog: %w", err)
+	}
+	return nil
+}
+
+// validateZone checks that a zone has a name, covers some area, and has sane
+// delivery terms
+func validateZone(zone models.DeliveryZone) error {
+	switch {
+	case strings.TrimSpace(zone.Name) == "":
+		return fmt.Errorf("%w: name is required", ErrInvalidZone)
+	case len(zone.Areas) == 0 && zone.Boundary == nil:
+		return fmt.Errorf("%w: zone %s needs areas or a boundary", ErrInvalidZone, zone.Name)
+	case zone.DeliveryFeeCents < 0 || zone.MinimumOrderCents < 0:
+		return fmt.Errorf("%w: zone %s has a negative fee or minimum order", ErrInvalidZone, zone.Name)
+	case zone.DeliveryMinutes <= 0:
+		return fmt.Errorf("%w: zone %s needs positive delivery_minutes", ErrInvalidZone, zone.Name)
+	}
+	if zone.Boundary != nil {
+		if err := geo.ValidatePolygon(*zone.Boundary); err != nil {
+			return fmt.Errorf("%w: zone %s: %w", ErrInvalidZone, zone.Name, err)
+		}
+	}
+	return nil
+}
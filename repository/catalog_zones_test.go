@@ -0,0 +1,162 @@
+package repository
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"weservefood/geo"
+	"weservefood/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mitteBoundary is a GeoJSON polygon around Berlin Mitte
+var mitteBoundary = &models.Polygon{
+	Type:        "Polygon",
+	Coordinates: [][][]float64{{{13.36, 52.51}, {13.42, 52.51}, {13.42, 52.55}, {13.36, 52.55}, {13.36, 52.51}}},
+}
+
+func TestPutZone(t *testing.T) {
+	AddRestaurant(models.Restaurant{ID: "r-admin-zones", Name: "Admin Zones"})
+
+	zones, err := GetZones("r-admin-zones")
+	require.NoError(t, err)
+	assert.Empty(t, zones)
+
+	centre := models.DeliveryZone{Name: "centre", Boundary: mitteBoundary, DeliveryFeeCents: 199, DeliveryMinutes: 20}
+	created, err := PutZone("r-admin-zones", centre)
+	require.NoError(t, err)
+	assert.True(t, created)
+	created, err = PutZone("r-admin-zones", models.DeliveryZone{Name: "outer", Areas: []string{"12049"}, DeliveryFeeCents: 399, DeliveryMinutes: 40})
+	require.NoError(t, err)
+	assert.True(t, created)
+	before, _ := GetRestaurant("r-admin-zones")
+
+	// replacing keeps the zone in its place
+	centre.MinimumOrderCents = 1500
+	created, err = PutZone("r-admin-zones", centre)
+	require.NoError(t, err)
+	assert.False(t, created)
+	zones, _ = GetZones("r-admin-zones")
+	require.Len(t, zones, 2)
+	assert.Equal(t, centre, zones[0])
+	assert.Zero(t, before.Zones[0].MinimumOrderCents, "restaurants handed out earlier keep their zones")
+
+	require.NoError(t, DeleteZone("r-admin-zones", "centre"))
+	zones, _ = GetZones("r-admin-zones")
+	assert.Equal(t, []string{"12049"}, zones[0].Areas)
+	assert.Len(t, before.Zones, 2)
+
+	assert.ErrorIs(t, DeleteZone("r-admin-zones", "centre"), ErrZoneNotFound)
+	assert.ErrorIs(t, DeleteZone("r-missing", "centre"), ErrRestaurantNotFound)
+	_, err = PutZone("r-missing", centre)
+	assert.ErrorIs(t, err, ErrRestaurantNotFound)
+	_, err = GetZones("r-missing")
+	assert.ErrorIs(t, err, ErrRestaurantNotFound)
+}
+
+// useCatalogFile saves the catalog to a file of the test's own from zone changes
+func useCatalogFile(t *testing.T) string {
+	path := filepath.Join(t.TempDir(), "catalog.json")
+	require.NoError(t, OpenCatalog(path))
+	t.Cleanup(func() {
+		catalogFile.Lock()
+		catalogFile.path = ""
+		catalogFile.Unlock()
+	})
+	return path
+}
+
+// savedZones reads the zones of a restaurant from a saved catalog
+func savedZones(t *testing.T, path, restaurantID string) []models.DeliveryZone {
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var document struct {
+		Restaurants []models.Restaurant `json:"restaurants"`
+	}
+	require.NoError(t, json.Unmarshal(data, &document))
+	for _, restaurant := range document.Restaurants {
+		if restaurant.ID == restaurantID {
+			return restaurant.Zones
+		}
+	}
+	t.Fatalf("restaurant %s is not in the saved catalog", restaurantID)
+	return nil
+}
+
+func TestZoneChangesSaveCatalog(t *testing.T) {
+	path := useCatalogFile(t)
+	AddRestaurant(models.Restaurant{ID: "r-saved-zones", Name: "Saved Zones"})
+
+	_, err := PutZone("r-saved-zones", models.DeliveryZone{Name: "centre", Areas: []string{"10115"}, DeliveryFeeCents: 199, DeliveryMinutes: 20})
+	require.NoError(t, err)
+	_, err = PutZone("r-saved-zones", models.DeliveryZone{Name: "outer", Areas: []string{"12049"}, DeliveryFeeCents: 399, DeliveryMinutes: 40})
+	require.NoError(t, err)
+	assert.Len(t, savedZones(t, path, "r-saved-zones"), 2)
+
+	require.NoError(t, DeleteZone("r-saved-zones", "centre"))
+	zones := savedZones(t, path, "r-saved-zones")
+	require.Len(t, zones, 1)
+	assert.Equal(t, "outer", zones[0].Name)
+
+	// the saved catalog is what a restart loads
+	AddRestaurant(models.Restaurant{ID: "r-saved-zones", Name: "Saved Zones"})
+	require.NoError(t, OpenCatalog(path))
+	zones, err = GetZones("r-saved-zones")
+	require.NoError(t, err)
+	require.Len(t, zones, 1)
+	assert.Equal(t, "outer", zones[0].Name)
+}
+
+func TestZoneChangeRolledBackWhenCatalogNotSaved(t *testing.T) {
+	useCatalogFile(t)
+	AddRestaurant(models.Restaurant{ID: "r-unsaved-zones", Name: "Unsaved Zones", Zones: []models.DeliveryZone{
+		{Name: "centre", Areas: []string{"10115"}, DeliveryFeeCents: 199, DeliveryMinutes: 20},
+	}})
+	catalogFile.Lock()
+	catalogFile.path = filepath.Join(t.TempDir(), "missing", "catalog.json")
+	catalogFile.Unlock()
+
+	_, err := PutZone("r-unsaved-zones", models.DeliveryZone{Name: "outer", Areas: []string{"12049"}, DeliveryFeeCents: 399, DeliveryMinutes: 40})
+	assert.ErrorContains(t, err, "saving the catalog")
+	assert.Error(t, DeleteZone("r-unsaved-zones", "centre"))
+
+	zones, err := GetZones("r-unsaved-zones")
+	require.NoError(t, err)
+	require.Len(t, zones, 1)
+	assert.Equal(t, "centre", zones[0].Name)
+}
+
+func TestValidateZone(t *testing.T) {
+	invalid := map[string]models.DeliveryZone{
+		"name is required":                {Areas: []string{"10115"}, DeliveryMinutes: 20},
+		"needs areas or a boundary":       {Name: "empty", DeliveryMinutes: 20},
+		"negative fee or minimum":         {Name: "cheap", Areas: []string{"10115"}, DeliveryFeeCents: -1, DeliveryMinutes: 20},
+		"needs positive delivery_minutes": {Name: "instant", Areas: []string{"10115"}},
+		"ring 0 is not closed": {Name: "open", DeliveryMinutes: 20, Boundary: &models.Polygon{
+			Type: "Polygon", Coordinates: [][][]float64{{{0, 0}, {1, 0}, {1, 1}, {0, 1}}},
+		}},
+	}
+	for message, zone := range invalid {
+		err := validateZone(zone)
+		assert.ErrorIs(t, err, ErrInvalidZone, message)
+		assert.ErrorContains(t, err, message)
+	}
+	assert.ErrorIs(t, validateZone(invalid["ring 0 is not closed"]), geo.ErrInvalidPolygon)
+}
+
+func TestLoadCatalogInvalidZone(t *testing.T) {
+	document := `{"restaurants": [{"id": "r-bad-zone", "name": "Bad Zone", "zones": [{"name": "nowhere", "delivery_minutes": 20}]}]}`
+	err := LoadCatalog(strings.NewReader(document))
+	assert.ErrorIs(t, err, ErrInvalidZone)
+	_, err = GetRestaurant("r-bad-zone")
+	assert.ErrorIs(t, err, ErrRestaurantNotFound, "nothing is loaded")
+
+	document = `{"restaurants": [{"id": "r-twice", "name": "Twice", "zones": [
+		{"name": "centre", "areas": ["10115"], "delivery_minutes": 20},
+		{"name": "centre", "areas": ["10117"], "delivery_minutes": 20}]}]}`
+	assert.ErrorContains(t, LoadCatalog(strings.NewReader(document)), "zone centre is listed twice")
+}
@@ -0,0 +1,271 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+	"weservefood/geo"
+	"weservefood/models"
+	"weservefood/tracing"
+)
+
+var (
+	ErrOutsideDeliveryZone    = errors.New("address is outside the delivery zone")
+	ErrAddressChangeRejected  = errors.New("address change rejected")
+	ErrPriceChangeUnconfirmed = errors.New("address change alters the price")
+	ErrBelowMinimumOrder      = errors.New("order is below the minimum for the delivery zone")
+)
+
+// Geocoder locates structured delivery addresses from their postal code
+var Geocoder geo.Geocoder = geo.Bundled()
+
+// LocationTolerance is how far, in metres, the location a customer gives may
+// be from the centre of their postal code
+var LocationTolerance = 3000.0
+
+// AddressChangeStatuses are the statuses in which the delivery address may
+// still change; once the courier has left it is too late
+var AddressChangeStatuses = []models.OrderStatus{models.StatusPlaced, models.StatusConfirmed, models.StatusPreparing}
+
+// UpdateAddress moves an order to a new delivery address within its
+// restaurant's delivery zones, recomputing the delivery fee and estimate and
+// checking the zone's minimum order. A
+// change of total must be accepted with patch.ConfirmTotalCents, and every
+// change is kept in the order's address history. A structured
+// patch.DeliveryAddress is geocoded and takes the place of patch.Address.
+func UpdateAddress(ctx context.Context, orderID string, patch models.OrderPatch) (_ models.Order, err error) {
+	ctx, span := tracer.Start(ctx, "repository.UpdateAddress", withOrderID(orderID))
+	defer func() { tracing.End(span, err) }()
+
+	newAddress := strings.TrimSpace(patch.Address)
+	var structured *models.Address
+	if patch.DeliveryAddress != nil {
+		// geocoded before taking the store, as a geocoder may be slow
+		located, err := locateAddress(ctx, *patch.DeliveryAddress)
+		if err != nil {
+			return models.Order{}, err
+		}
+		structured, newAddress = &located, located.String()
+	}
+	if newAddress == "" {
+		return models.Order{}, fmt.Errorf("%w: address is required", ErrInvalidOrder)
+	}
+
+	store.Mutex.Lock()
+	defer store.Mutex.Unlock()
+
+	order, exist := store.Orders[orderID]
+	if !exist {
+		return models.Order{}, ErrOrderNotFound
+	}
+	if order.Email != patch.Email {
+		return models.Order{}, ErrEmailMismatch
+	}
+	if order.Status == models.StatusCancelled {
+		return models.Order{}, ErrOrderCancelled
+	}
+	if !slices.Contains(AddressChangeStatuses, order.Status) {
+		return models.Order{}, fmt.Errorf("%w: the order is already %s", ErrAddressChangeRejected, order.Status)
+	}
+
+	price, zoneName := order.Price, order.DeliveryZone
+	if order.RestaurantID != "" {
+		restaurant, err := GetRestaurant(order.RestaurantID)
+		if err != nil {
+			return models.Order{}, err
+		}
+		zone, _, err := deliveryTerms(restaurant, newAddress, structured)
+		if err != nil {
+			return models.Order{}, err
+		}
+		if err := checkMinimumOrder(restaurant, zone, price.SubtotalCents); err != nil {
+			return models.Order{}, err
+		}
+		price.DeliveryFeeCents = zone.DeliveryFeeCents
+		price.TotalCents = price.SubtotalCents + zone.DeliveryFeeCents
+		zoneName = zone.Name
+	}
+	if price.TotalCents != order.Price.TotalCents {
+		if order.Payment != nil && (order.Payment.Status == models.PaymentAuthorized || order.Payment.Status == models.PaymentCaptured) {
+			return models.Order{}, fmt.Errorf("%w: the new address changes the total of a paid order", ErrAddressChangeRejected)
+		}
+		if patch.ConfirmTotalCents != price.TotalCents {
+			return models.Order{}, fmt.Errorf("%w: the total becomes %d cents instead of %d; confirm the new total to go ahead",
+				ErrPriceChangeUnconfirmed, price.TotalCents, order.Price.TotalCents)
+		}
+	}
+
+	now := time.Now().UTC()
+	history := make([]models.AddressChange, len(order.AddressHistory), len(order.AddressHistory)+1)
+	copy(history, order.AddressHistory)
+	order.AddressHistory = append(history, models.AddressChange{
+		From:             order.Address,
+		To:               newAddress,
+		DeliveryFeeCents: price.DeliveryFeeCents,
+		At:               now,
+	})
+	order.Address = newAddress
+	order.DeliveryAddress = structured
+	order.DeliveryZone = zoneName
+	order.Price = price
+	estimateDelivery(&order, now)
+	order.PromisedAt = order.DueAt
+	store.Orders[orderID] = order
+	notifyWatchers(order)
+
+	return order, nil
+}
+
+// deliveryTerms returns the restaurant zone an address is in and how long
+// delivery there takes. A zone covers the address when its boundary contains
+// the address's location or one of its areas is the address's postal code or
+// district; the first zone that does is used. Restaurants without zones
+// deliver anywhere at the default fee and time, returned as a zone without a
+// name.
+func deliveryTerms(restaurant models.Restaurant, address string, structured *models.Address) (models.DeliveryZone, time.Duration, error) {
+	if len(restaurant.Zones) == 0 {
+		return models.DeliveryZone{DeliveryFeeCents: DeliveryFeeCents}, DeliveryOffset, nil
+	}
+
+	areas, location := addressAreas(address, structured), addressLocation(structured)
+	for _, zone := range restaurant.Zones {
+		if inZone(zone, areas, location) {
+			return zone, time.Duration(zone.DeliveryMinutes) * time.Minute, nil
+		}
+	}
+	return models.DeliveryZone{}, 0, fmt.Errorf("%w: %s does not deliver to %q", ErrOutsideDeliveryZone, restaurant.Name, address)
+}
+
+// inZone reports whether a zone covers an address, given as the areas it
+// can be in and, when it is known, its location
+func inZone(zone models.DeliveryZone, areas []string, location *models.LatLng) bool {
+	if zone.Boundary != nil && location != nil && geo.Contains(*zone.Boundary, *location) {
+		return true
+	}
+	for _, area := range zone.Areas {
+		if area := strings.Join(addressWords(area), " "); area != "" && slices.Contains(areas, area) {
+			return true
+		}
+	}
+	return false
+}
+
+// addressAreas returns the areas an address can be in, as lower case words.
+// A structured address is only in the area of its postal code, which was
+// geocoded. Free text is read for its locality, the part after the last
+// comma, such as "10115 Berlin" or "Mitte": its postal code and its place
+// name. Areas named anywhere else, such as in the street, do not count.
+func addressAreas(address string, structured *models.Address) []string {
+	if structured != nil {
+		fields := strings.Fields(structured.PostalCode)
+		areas := []string{strings.Join(addressWords(structured.PostalCode), " ")}
+		if len(fields) > 1 {
+			areas = append(areas, strings.Join(addressWords(fields[0]), " "))
+		}
+		return areas
+	}
+
+	locality := address
+	if i := strings.LastIndex(address, ","); i >= 0 {
+		locality = address[i+1:]
+	}
+	var codes, names []string
+	for _, word := range addressWords(locality) {
+		if strings.ContainsFunc(word, unicode.IsDigit) {
+			codes = append(codes, word)
+		} else {
+			names = append(names, word)
+		}
+	}
+	areas := codes
+	if len(codes) > 1 {
+		areas = append(areas, strings.Join(codes, " "))
+	}
+	if len(names) > 0 {
+		areas = append(areas, strings.Join(names, " "))
+	}
+	return areas
+}
+
+// checkMinimumOrder refuses a subtotal below the minimum order of the zone
+func checkMinimumOrder(restaurant models.Restaurant, zone models.DeliveryZone, subtotalCents int) error {
+	if subtotalCents < zone.MinimumOrderCents {
+		return fmt.Errorf("%w: %s delivers to %s from %d cents, and the order is %d cents",
+			ErrBelowMinimumOrder, restaurant.Name, zone.Name, zone.MinimumOrderCents, subtotalCents)
+	}
+	return nil
+}
+
+// addressLocation returns where a structured address is, or nil when the
+// address is free text
+func addressLocation(address *models.Address) *models.LatLng {
+	if address == nil {
+		return nil
+	}
+	return address.Location
+}
+
+// addressWords splits an address into lower case words, so areas match
+// regardless of case and punctuation
+func addressWords(address string) []string {
+	return strings.FieldsFunc(strings.ToLower(address), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// validateAddress checks that a structured address has the fields needed to
+// deliver to it
+func validateAddress(address models.Address) error {
+	required := []struct{ name, value string }{
+		{"street", address.Street},
+		{"city", address.City},
+		{"postal_code", address.PostalCode},
+		{"country", address.Country},
+	}
+	for _, field := range required {
+		if strings.TrimSpace(field.value) == "" {
+			return fmt.Errorf("%w: delivery_address.%s is required", ErrInvalidOrder, field.name)
+		}
+	}
+	if len(strings.TrimSpace(address.Country)) != 2 {
+		return fmt.Errorf("%w: delivery_address.country must be a two letter ISO code, not %q", ErrInvalidOrder, address.Country)
+	}
+	if address.Location != nil && !address.Location.Valid() {
+		return fmt.Errorf("%w: delivery_address.location %v,%v is not on the map", ErrInvalidOrder, address.Location.Lat, address.Location.Lng)
+	}
+	return nil
+}
+
+// locateAddress validates and tidies a structured address and geocodes its
+// postal code. A location the customer gives refines the geocoded one, and is
+// refused when it is not near the postal code.
+func locateAddress(ctx context.Context, address models.Address) (models.Address, error) {
+	if err := validateAddress(address); err != nil {
+		return models.Address{}, err
+	}
+
+	address.Street = strings.TrimSpace(address.Street)
+	address.Unit = strings.TrimSpace(address.Unit)
+	address.City = strings.TrimSpace(address.City)
+	address.PostalCode = strings.ToUpper(strings.TrimSpace(address.PostalCode))
+	address.Country = strings.ToUpper(strings.TrimSpace(address.Country))
+	address.Instructions = strings.TrimSpace(address.Instructions)
+
+	location, err := Geocoder.Geocode(ctx, address)
+	if err != nil {
+		return models.Address{}, fmt.Errorf("%w: %w", ErrInvalidOrder, err)
+	}
+	if address.Location != nil {
+		if distance := geo.Distance(location, *address.Location); distance > LocationTolerance {
+			return models.Address{}, fmt.Errorf("%w: delivery_address.location is %.0f metres from postal code %s",
+				ErrInvalidOrder, distance, address.PostalCode)
+		}
+		location = *address.Location
+	}
+	address.Location = &location
+	return address, nil
+}
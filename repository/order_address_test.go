@@ -0,0 +1,244 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+	"weservefood/geo"
+	"weservefood/models"
+	"weservefood/payments"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var zonedRestaurant = models.Restaurant{
+	ID:   "r-zoned",
+	Name: "Zoned Noodles",
+	Menu: []models.MenuItem{{Name: "Ramen", PriceCents: 1200}},
+	Zones: []models.DeliveryZone{
+		{Name: "centre", Areas: []string{"10115", "Mitte"}, DeliveryFeeCents: 199, DeliveryMinutes: 20},
+		{Name: "outer", Areas: []string{"12049", "Neukölln"}, DeliveryFeeCents: 399, DeliveryMinutes: 40},
+	},
+}
+
+func TestDeliveryTerms(t *testing.T) {
+	zone, eta, err := deliveryTerms(models.Restaurant{Name: "Anywhere"}, "1 Main St", nil)
+	require.NoError(t, err)
+	assert.Equal(t, DeliveryFeeCents, zone.DeliveryFeeCents)
+	assert.Equal(t, DeliveryOffset, eta)
+
+	zone, eta, err = deliveryTerms(zonedRestaurant, "Torstraße 1, 10115 Berlin", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "centre", zone.Name)
+	assert.Equal(t, 199, zone.DeliveryFeeCents)
+	assert.Equal(t, 20*time.Minute, eta)
+
+	zone, _, err = deliveryTerms(zonedRestaurant, "Sonnenallee 5, NEUKÖLLN", nil)
+	require.NoError(t, err)
+	assert.Equal(t, 399, zone.DeliveryFeeCents)
+
+	// areas match whole words only
+	_, _, err = deliveryTerms(zonedRestaurant, "Somewhere 101150", nil)
+	assert.ErrorIs(t, err, ErrOutsideDeliveryZone)
+}
+
+func TestDeliveryTermsIgnoreAreasOutsideTheLocality(t *testing.T) {
+	for _, address := range []string{"Mitte Straße 10115, 99999 Spandau", "Near Mitte, Spandau", "10115 Street 1, Spandau"} {
+		_, _, err := deliveryTerms(zonedRestaurant, address, nil)
+		assert.ErrorIs(t, err, ErrOutsideDeliveryZone, address)
+	}
+
+	// a structured address is only in the area of its postal code
+	_, _, err := deliveryTerms(zonedRestaurant, "Mitte 1, 99999 Mitte, DE", &models.Address{Street: "Mitte 1", City: "Mitte", PostalCode: "99999", Country: "DE"})
+	assert.ErrorIs(t, err, ErrOutsideDeliveryZone)
+	zone, _, err := deliveryTerms(zonedRestaurant, "Sonnenallee 5, 12049 Mitte, DE", &models.Address{Street: "Sonnenallee 5", City: "Mitte", PostalCode: "12049", Country: "DE"})
+	require.NoError(t, err)
+	assert.Equal(t, "outer", zone.Name)
+}
+
+func TestCreateOrderInZone(t *testing.T) {
+	AddRestaurant(zonedRestaurant)
+
+	order, err := CreateOrder(context.Background(), models.Order{Email: "zone@example.com", Address: "Torstraße 1, 10115 Berlin", RestaurantID: "r-zoned", Items: []string{"Ramen"}})
+	require.NoError(t, err)
+	assert.Equal(t, models.PriceBreakdown{SubtotalCents: 1200, DeliveryFeeCents: 199, TotalCents: 1399}, order.Price)
+	assert.Equal(t, 20, order.Estimate.TravelMinutes, "the zone sets the ride")
+
+	_, err = CreateOrder(context.Background(), models.Order{Email: "zone@example.com", Address: "1 Far Away", RestaurantID: "r-zoned", Items: []string{"Ramen"}})
+	assert.ErrorIs(t, err, ErrOutsideDeliveryZone)
+}
+
+func TestUpdateAddressInZone(t *testing.T) {
+	AddRestaurant(zonedRestaurant)
+	order, err := CreateOrder(context.Background(), models.Order{Email: "move@example.com", Address: "Torstraße 1, 10115 Berlin", RestaurantID: "r-zoned", Items: []string{"Ramen"}})
+	require.NoError(t, err)
+
+	// same zone, same price
+	moved, err := UpdateAddress(context.Background(), order.ID, models.OrderPatch{Email: "move@example.com", Address: "Invalidenstraße 2, Mitte"})
+	require.NoError(t, err)
+	assert.Equal(t, order.Price, moved.Price)
+	require.Len(t, moved.AddressHistory, 1)
+	assert.Equal(t, "Torstraße 1, 10115 Berlin", moved.AddressHistory[0].From)
+	assert.Equal(t, "Invalidenstraße 2, Mitte", moved.AddressHistory[0].To)
+
+	_, err = UpdateAddress(context.Background(), order.ID, models.OrderPatch{Email: "move@example.com", Address: "1 Far Away"})
+	assert.ErrorIs(t, err, ErrOutsideDeliveryZone)
+
+	// another zone costs more, so the new total has to be confirmed
+	patch := models.OrderPatch{Email: "move@example.com", Address: "Sonnenallee 5, 12049 Berlin"}
+	_, err = UpdateAddress(context.Background(), order.ID, patch)
+	assert.ErrorIs(t, err, ErrPriceChangeUnconfirmed)
+	assert.EqualError(t, err, "address change alters the price: the total becomes 1599 cents instead of 1399; confirm the new total to go ahead")
+	unchanged, _ := GetOrderByID(context.Background(), order.ID)
+	assert.Equal(t, "Invalidenstraße 2, Mitte", unchanged.Address)
+
+	patch.ConfirmTotalCents = 1599
+	moved, err = UpdateAddress(context.Background(), order.ID, patch)
+	require.NoError(t, err)
+	assert.Equal(t, models.PriceBreakdown{SubtotalCents: 1200, DeliveryFeeCents: 399, TotalCents: 1599}, moved.Price)
+	assert.Equal(t, 40, moved.Estimate.TravelMinutes)
+	assert.Equal(t, moved.Estimate.DueAt(), moved.DueAt)
+	require.Len(t, moved.AddressHistory, 2)
+	assert.Equal(t, 399, moved.AddressHistory[1].DeliveryFeeCents)
+	assert.Len(t, unchanged.AddressHistory, 1, "earlier snapshots keep their history")
+}
+
+func TestUpdateAddressRejected(t *testing.T) {
+	AddRestaurant(zonedRestaurant)
+	order, err := CreateOrder(context.Background(), models.Order{Email: "locked@example.com", Address: "10115 Berlin", RestaurantID: "r-zoned", Items: []string{"Ramen"}})
+	require.NoError(t, err)
+	_, err = ConfirmOrder(context.Background(), "locked@example.com", order.ID, payments.MethodVisa)
+	require.NoError(t, err)
+
+	_, err = UpdateAddress(context.Background(), order.ID, models.OrderPatch{Email: "locked@example.com", Address: "12049 Berlin", ConfirmTotalCents: 1599})
+	assert.EqualError(t, err, "address change rejected: the new address changes the total of a paid order")
+
+	for _, status := range []models.OrderStatus{models.StatusPreparing, models.StatusOutForDelivery} {
+		_, err = AdvanceOrder(context.Background(), order.ID, status)
+		require.NoError(t, err)
+	}
+	_, err = UpdateAddress(context.Background(), order.ID, models.OrderPatch{Email: "locked@example.com", Address: "Mitte"})
+	assert.EqualError(t, err, "address change rejected: the order is already out_for_delivery")
+
+	_, err = UpdateAddress(context.Background(), order.ID, models.OrderPatch{Email: "locked@example.com", Address: " "})
+	assert.ErrorIs(t, err, ErrInvalidOrder)
+}
+
+func useGazetteer(t *testing.T) {
+	previous := Geocoder
+	Geocoder = geo.NewGazetteer(
+		geo.Place{Country: "DE", PostalCode: "10115", City: "Berlin", Location: models.LatLng{Lat: 52.5323, Lng: 13.3846}},
+		geo.Place{Country: "DE", PostalCode: "10119", City: "Berlin", Location: models.LatLng{Lat: 52.5300, Lng: 13.4050}},
+		geo.Place{Country: "DE", PostalCode: "12049", City: "Berlin", Location: models.LatLng{Lat: 52.4770, Lng: 13.4250}},
+	)
+	t.Cleanup(func() { Geocoder = previous })
+}
+
+func TestCreateOrderWithDeliveryAddress(t *testing.T) {
+	useGazetteer(t)
+	AddRestaurant(zonedRestaurant)
+
+	address := &models.Address{Street: " Invalidenstraße 43 ", Unit: "3rd floor", City: "Berlin", PostalCode: "10115", Country: "de", Instructions: "ring twice"}
+	order, err := CreateOrder(context.Background(), models.Order{Email: "structured@example.com", DeliveryAddress: address, RestaurantID: "r-zoned", Items: []string{"Ramen"}})
+	require.NoError(t, err)
+	assert.Equal(t, "Invalidenstraße 43, 3rd floor, 10115 Berlin, DE", order.Address)
+	require.NotNil(t, order.DeliveryAddress.Location)
+	assert.Equal(t, models.LatLng{Lat: 52.5323, Lng: 13.3846}, *order.DeliveryAddress.Location)
+	assert.Equal(t, "ring twice", order.DeliveryAddress.Instructions)
+	assert.Equal(t, 199, order.Price.DeliveryFeeCents, "the zone is found from the postal code")
+	assert.Nil(t, address.Location, "the caller's address is left untouched")
+
+	// a location given by the customer refines the one of the postal code
+	pinned := models.LatLng{Lat: 52.52, Lng: 13.40}
+	order, err = CreateOrder(context.Background(), models.Order{Email: "structured@example.com", DeliveryAddress: &models.Address{Street: "Torstraße 1", City: "Berlin", PostalCode: "10119", Country: "DE", Location: &pinned}})
+	require.NoError(t, err)
+	assert.Equal(t, pinned, *order.DeliveryAddress.Location)
+
+	// but the postal code is geocoded all the same, and the location must be near it
+	_, err = CreateOrder(context.Background(), models.Order{Email: "structured@example.com", DeliveryAddress: &models.Address{Street: "Torstraße 1", City: "Berlin", PostalCode: "10117", Country: "DE", Location: &pinned}})
+	assert.ErrorIs(t, err, ErrInvalidOrder)
+	assert.ErrorIs(t, err, geo.ErrNotFound)
+	_, err = CreateOrder(context.Background(), models.Order{Email: "structured@example.com", DeliveryAddress: &models.Address{Street: "Sonnenallee 5", City: "Berlin", PostalCode: "12049", Country: "DE", Location: &pinned}, RestaurantID: "r-zoned", Items: []string{"Ramen"}})
+	assert.EqualError(t, err, "invalid order: delivery_address.location is 5072 metres from postal code 12049")
+
+	_, err = CreateOrder(context.Background(), models.Order{Email: "structured@example.com", DeliveryAddress: &models.Address{Street: "Torstraße 1", City: "Berlin", Country: "DE"}})
+	assert.EqualError(t, err, "invalid order: delivery_address.postal_code is required")
+	_, err = CreateOrder(context.Background(), models.Order{Email: "structured@example.com", DeliveryAddress: &models.Address{Street: "Torstraße 1", City: "Berlin", PostalCode: "10115", Country: "Germany"}})
+	assert.ErrorIs(t, err, ErrInvalidOrder)
+	_, err = CreateOrder(context.Background(), models.Order{Email: "structured@example.com", DeliveryAddress: &models.Address{Street: "Torstraße 1", City: "Berlin", PostalCode: "10115", Country: "DE", Location: &models.LatLng{Lat: 91}}})
+	assert.ErrorIs(t, err, ErrInvalidOrder)
+}
+
+func TestUpdateAddressWithDeliveryAddress(t *testing.T) {
+	useGazetteer(t)
+	AddRestaurant(zonedRestaurant)
+	order, err := CreateOrder(context.Background(), models.Order{Email: "restructured@example.com", Address: "Torstraße 1, 10115 Berlin", RestaurantID: "r-zoned", Items: []string{"Ramen"}})
+	require.NoError(t, err)
+
+	moved, err := UpdateAddress(context.Background(), order.ID, models.OrderPatch{
+		Email:             "restructured@example.com",
+		DeliveryAddress:   &models.Address{Street: "Sonnenallee 5", City: "Berlin", PostalCode: "12049", Country: "DE"},
+		ConfirmTotalCents: 1599,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Sonnenallee 5, 12049 Berlin, DE", moved.Address)
+	assert.Equal(t, models.LatLng{Lat: 52.4770, Lng: 13.4250}, *moved.DeliveryAddress.Location)
+	assert.Equal(t, "Sonnenallee 5, 12049 Berlin, DE", moved.AddressHistory[0].To)
+
+	// free text replaces the structured address entirely
+	moved, err = UpdateAddress(context.Background(), order.ID, models.OrderPatch{Email: "restructured@example.com", Address: "Sonnenallee 7, 12049 Berlin"})
+	require.NoError(t, err)
+	assert.Nil(t, moved.DeliveryAddress)
+
+	_, err = UpdateAddress(context.Background(), order.ID, models.OrderPatch{Email: "restructured@example.com", DeliveryAddress: &models.Address{Street: "Nowhere 1", City: "Berlin", PostalCode: "99999", Country: "DE"}})
+	assert.ErrorIs(t, err, geo.ErrNotFound)
+}
+
+func TestPolygonZones(t *testing.T) {
+	useGazetteer(t)
+	AddRestaurant(models.Restaurant{
+		ID:   "r-polygon",
+		Name: "Polygon Pizza",
+		Menu: []models.MenuItem{{Name: "Pizza", PriceCents: 900}},
+		Zones: []models.DeliveryZone{
+			{Name: "mitte", Boundary: mitteBoundary, DeliveryFeeCents: 150, DeliveryMinutes: 15, MinimumOrderCents: 1500},
+			{Name: "neukoelln", Areas: []string{"12049"}, DeliveryFeeCents: 350, DeliveryMinutes: 35},
+		},
+	})
+	mitte := &models.Address{Street: "Invalidenstraße 43", City: "Berlin", PostalCode: "10115", Country: "DE"}
+
+	_, err := CreateOrder(context.Background(), models.Order{Email: "polygon@example.com", DeliveryAddress: mitte, RestaurantID: "r-polygon", Items: []string{"Pizza"}})
+	assert.ErrorIs(t, err, ErrBelowMinimumOrder)
+	assert.EqualError(t, err, "order is below the minimum for the delivery zone: Polygon Pizza delivers to mitte from 1500 cents, and the order is 900 cents")
+
+	order, err := CreateOrder(context.Background(), models.Order{Email: "polygon@example.com", DeliveryAddress: mitte, RestaurantID: "r-polygon", Items: []string{"Pizza", "Pizza"}})
+	require.NoError(t, err)
+	assert.Equal(t, "mitte", order.DeliveryZone)
+	assert.Equal(t, 150, order.Price.DeliveryFeeCents)
+	assert.Equal(t, 15, order.Estimate.TravelMinutes)
+
+	// free text cannot be placed inside a polygon
+	_, err = CreateOrder(context.Background(), models.Order{Email: "polygon@example.com", Address: "Invalidenstraße 43, 10115 Berlin", RestaurantID: "r-polygon", Items: []string{"Pizza", "Pizza"}})
+	assert.ErrorIs(t, err, ErrOutsideDeliveryZone)
+
+	// a location outside the polygon is refused even though the postal code is inside
+	_, err = UpdateAddress(context.Background(), order.ID, models.OrderPatch{Email: "polygon@example.com", DeliveryAddress: &models.Address{
+		Street: "Invalidenstraße 43", City: "Berlin", PostalCode: "10115", Country: "DE", Location: &models.LatLng{Lat: 52.552, Lng: 13.3846},
+	}})
+	assert.ErrorIs(t, err, ErrOutsideDeliveryZone)
+
+	moved, err := UpdateAddress(context.Background(), order.ID, models.OrderPatch{
+		Email:             "polygon@example.com",
+		DeliveryAddress:   &models.Address{Street: "Sonnenallee 5", City: "Berlin", PostalCode: "12049", Country: "DE"},
+		ConfirmTotalCents: 2150,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "neukoelln", moved.DeliveryZone)
+
+	// moving a small order into the zone with a minimum is refused
+	small, err := CreateOrder(context.Background(), models.Order{Email: "polygon@example.com", Address: "Sonnenallee 5, 12049 Berlin", RestaurantID: "r-polygon", Items: []string{"Pizza"}})
+	require.NoError(t, err)
+	_, err = UpdateAddress(context.Background(), small.ID, models.OrderPatch{Email: "polygon@example.com", DeliveryAddress: mitte, ConfirmTotalCents: 1050})
+	assert.ErrorIs(t, err, ErrBelowMinimumOrder)
+}
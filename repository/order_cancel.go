@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+	"weservefood/models"
+)
+
+// ErrCancellationRejected is returned when the cancellation policy does not
+// allow an order to be cancelled
+var ErrCancellationRejected = errors.New("cancellation rejected")
+
+// CancellationRule lets an initiator cancel orders in one of Statuses until
+// Cutoff before they are due; a zero Cutoff sets no time limit
+type CancellationRule struct {
+	Statuses []models.OrderStatus
+	Cutoff   time.Duration
+}
+
+// CancellationPolicy holds the rule of every initiator
+var CancellationPolicy = map[models.CancelInitiator]CancellationRule{
+	models.InitiatorCustomer: {
+		Statuses: []models.OrderStatus{models.StatusPlaced, models.StatusConfirmed, models.StatusPreparing},
+		Cutoff:   10 * time.Minute,
+	},
+	models.InitiatorRestaurant: {
+		Statuses: []models.OrderStatus{models.StatusPlaced, models.StatusConfirmed, models.StatusPreparing},
+	},
+	models.InitiatorSupport: {
+		Statuses: []models.OrderStatus{models.StatusPlaced, models.StatusConfirmed, models.StatusPreparing, models.StatusOutForDelivery},
+	},
+}
+
+// dueAt is when an order is expected to be delivered. Orders saved before
+// DueAt was recorded are due DeliveryOffset after they were placed.
+func dueAt(order models.Order) time.Time {
+	if !order.DueAt.IsZero() {
+		return order.DueAt
+	}
+	return order.CreatedAt.Add(DeliveryOffset)
+}
+
+// requestedBy reports whether the cancellation comes from someone the order
+// belongs to. Support may cancel any order.
+func requestedBy(order models.Order, request models.OrderCancellation) bool {
+	switch request.Initiator {
+	case models.InitiatorCustomer:
+		return order.Email == request.Email
+	case models.InitiatorRestaurant:
+		return order.RestaurantID != "" && order.RestaurantID == request.RestaurantID
+	default:
+		return true
+	}
+}
+
+// cancellable checks that an order is not cancelled yet and that the
+// initiator's rule lets it be cancelled now
+func cancellable(order models.Order, initiator models.CancelInitiator, now time.Time) error {
+	if order.Status == models.StatusCancelled {
+		return ErrOrderCancelled
+	}
+	return checkCancellation(order, initiator, now)
+}
+
+// checkCancellation applies the initiator's rule to an order, explaining why
+// the cancellation is rejected when it is
+func checkCancellation(order models.Order, initiator models.CancelInitiator, now time.Time) error {
+	rule := CancellationPolicy[initiator]
+	if !slices.Contains(rule.Statuses, order.Status) {
+		return fmt.Errorf("%w: %s cannot cancel an order that is %s", ErrCancellationRejected, initiator, order.Status)
+	}
+	if left := dueAt(order).Sub(now); rule.Cutoff > 0 && left < rule.Cutoff {
+		return fmt.Errorf("%w: %s must cancel at least %s before delivery, and the order is due in %s",
+			ErrCancellationRejected, initiator, rule.Cutoff, max(left, 0).Round(time.Second))
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+	"weservefood/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCancelOrderRecordsCancellation(t *testing.T) {
+	order, err := CreateOrder(context.Background(), models.Order{Email: "reason@example.com", Address: "1 Main St"})
+	require.NoError(t, err)
+
+	_, err = CancelOrder(context.Background(), order.ID, models.OrderCancellation{Email: "reason@example.com", Reason: "  "})
+	assert.ErrorIs(t, err, ErrInvalidOrder)
+	_, err = CancelOrder(context.Background(), order.ID, models.OrderCancellation{Initiator: "courier", Reason: "lost"})
+	assert.ErrorIs(t, err, ErrInvalidOrder)
+
+	_, err = CancelOrder(context.Background(), order.ID, models.OrderCancellation{Email: "reason@example.com", Reason: " ordered twice "})
+	require.NoError(t, err)
+	cancelled, _ := GetOrderByID(context.Background(), order.ID)
+	require.NotNil(t, cancelled.Cancellation)
+	assert.Equal(t, models.InitiatorCustomer, cancelled.Cancellation.Initiator)
+	assert.Equal(t, "ordered twice", cancelled.Cancellation.Reason)
+	cancelledAt, _ := cancelled.StatusAt(models.StatusCancelled)
+	assert.Equal(t, cancelledAt, cancelled.Cancellation.At)
+}
+
+func TestCancelOrderByRestaurant(t *testing.T) {
+	AddRestaurant(models.Restaurant{ID: "r-cancel", Name: "Cancel Kitchen", Menu: []models.MenuItem{{Name: "Stew", PriceCents: 900}}})
+	order, err := CreateOrder(context.Background(), models.Order{Email: "kitchen@example.com", Address: "1 Main St", RestaurantID: "r-cancel", Items: []string{"Stew"}})
+	require.NoError(t, err)
+
+	_, err = CancelOrder(context.Background(), order.ID, models.OrderCancellation{Initiator: models.InitiatorRestaurant, RestaurantID: "r-other", Reason: "out of stew"})
+	assert.ErrorIs(t, err, ErrOrderNotFound)
+
+	_, err = CancelOrder(context.Background(), order.ID, models.OrderCancellation{Initiator: models.InitiatorRestaurant, RestaurantID: "r-cancel", Reason: "out of stew"})
+	require.NoError(t, err)
+	cancelled, _ := GetOrderByID(context.Background(), order.ID)
+	assert.Equal(t, models.InitiatorRestaurant, cancelled.Cancellation.Initiator)
+}
+
+func TestCheckCancellation(t *testing.T) {
+	now := time.Now().UTC()
+	order := models.Order{Status: models.StatusPreparing, CreatedAt: now}
+
+	assert.NoError(t, checkCancellation(order, models.InitiatorCustomer, now))
+	// customers must cancel 10 minutes before the order is due
+	err := checkCancellation(order, models.InitiatorCustomer, now.Add(DeliveryOffset-5*time.Minute))
+	assert.ErrorIs(t, err, ErrCancellationRejected)
+	assert.EqualError(t, err, "cancellation rejected: customer must cancel at least 10m0s before delivery, and the order is due in 5m0s")
+	assert.NoError(t, checkCancellation(order, models.InitiatorRestaurant, now.Add(DeliveryOffset+time.Hour)))
+
+	order.Status = models.StatusOutForDelivery
+	assert.EqualError(t, checkCancellation(order, models.InitiatorCustomer, now), "cancellation rejected: customer cannot cancel an order that is out_for_delivery")
+	assert.ErrorIs(t, checkCancellation(order, models.InitiatorRestaurant, now), ErrCancellationRejected)
+	assert.NoError(t, checkCancellation(order, models.InitiatorSupport, now))
+}
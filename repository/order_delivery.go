@@ -0,0 +1,227 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+	"weservefood/blob"
+	"weservefood/models"
+	"weservefood/tracing"
+)
+
+var (
+	ErrInvalidProof  = errors.New("invalid proof of delivery")
+	ErrProofRequired = errors.New("proof of delivery required")
+	ErrProofNotFound = errors.New("proof of delivery not found")
+	ErrWrongPIN      = errors.New("delivery PIN does not match")
+	ErrPINLocked     = errors.New("delivery PIN locked after too many wrong attempts")
+	ErrNotAssigned   = errors.New("order is not assigned to this courier")
+)
+
+// DefaultProof is required of orders that do not choose their proof of delivery
+var DefaultProof = models.ProofPIN
+
+// Blobs stores the photos and signatures given as proof of delivery
+var Blobs blob.Store = blob.NewDir(filepath.Join(os.TempDir(), "weservefood", "blobs"))
+
+// maxPINAttempts is how many wrong PINs a courier may give for an order
+// before support has to step in
+const maxPINAttempts = 5
+
+// pinAttempts counts the wrong PINs given for each order; it is guarded by
+// the store mutex and saved with the orders
+var pinAttempts = make(map[string]int)
+
+// proofTypes are the image types accepted as photos and signatures, with the
+// extension they are stored under
+var proofTypes = map[string]string{"image/jpeg": ".jpg", "image/png": ".png"}
+
+// DeliverOrder completes an order out for delivery with the proof it requires:
+// the PIN shown to the customer, a photo of the handover or the customer's
+// signature. A photo or signature given beyond that is kept as well; either
+// reader may be nil. Only the courier carrying the order may deliver it.
+// Orders placed before proof of delivery was recorded require none.
+func DeliverOrder(ctx context.Context, orderID string, confirmation models.DeliveryConfirmation, photo, signature io.Reader) (_ models.Order, err error) {
+	ctx, span := tracer.Start(ctx, "repository.DeliverOrder", withOrderID(orderID))
+	defer func() { tracing.End(span, err) }()
+
+	if confirmation.CourierID == "" {
+		return models.Order{}, fmt.Errorf("%w: the courier is required", ErrInvalidProof)
+	}
+	if confirmation.Location != nil && !confirmation.Location.Valid() {
+		return models.Order{}, fmt.Errorf("%w: location is out of range", ErrInvalidProof)
+	}
+	store.Mutex.Lock()
+	err = deliverable(orderID, confirmation.CourierID)
+	store.Mutex.Unlock()
+	if err != nil {
+		return models.Order{}, err
+	}
+
+	// uploads are stored before the order is completed, so the store is not
+	// locked while they are written, and removed again if it cannot be
+	now := time.Now().UTC()
+	proof := models.DeliveryProof{CourierID: confirmation.CourierID, Location: confirmation.Location, DeliveredAt: now}
+	var stored []string
+	defer func() {
+		if err != nil {
+			for _, key := range stored {
+				Blobs.Delete(context.WithoutCancel(ctx), key)
+			}
+		}
+	}()
+	for _, upload := range []struct {
+		kind   models.ProofMethod
+		reader io.Reader
+		key    *string
+	}{{models.ProofPhoto, photo, &proof.PhotoKey}, {models.ProofSignature, signature, &proof.SignatureKey}} {
+		if upload.reader == nil {
+			continue
+		}
+		key, err := storeProof(ctx, orderID, upload.kind, upload.reader, now)
+		if err != nil {
+			return models.Order{}, err
+		}
+		stored = append(stored, key)
+		*upload.key = key
+	}
+
+	store.Mutex.Lock()
+	defer store.Mutex.Unlock()
+
+	// the order may have changed while the uploads were stored
+	if err := deliverable(orderID, confirmation.CourierID); err != nil {
+		return models.Order{}, err
+	}
+	order := store.Orders[orderID]
+	proof.Method = order.ProofRequired
+	switch order.ProofRequired {
+	case models.ProofPIN:
+		if confirmation.PIN == "" {
+			return models.Order{}, fmt.Errorf("%w: the order requires the customer's PIN", ErrProofRequired)
+		}
+		if pinAttempts[orderID] >= maxPINAttempts {
+			return models.Order{}, ErrPINLocked
+		}
+		if subtle.ConstantTimeCompare([]byte(confirmation.PIN), []byte(order.DeliveryPIN)) != 1 {
+			pinAttempts[orderID]++
+			return models.Order{}, fmt.Errorf("%w: %d attempts left", ErrWrongPIN, maxPINAttempts-pinAttempts[orderID])
+		}
+		proof.PINConfirmed = true
+	case models.ProofPhoto:
+		if proof.PhotoKey == "" {
+			return models.Order{}, fmt.Errorf("%w: the order requires a photo of the handover", ErrProofRequired)
+		}
+	case models.ProofSignature:
+		if proof.SignatureKey == "" {
+			return models.Order{}, fmt.Errorf("%w: the order requires the customer's signature", ErrProofRequired)
+		}
+	}
+
+	order.Delivery = &proof
+	order.SetStatus(models.StatusDelivered, now)
+	store.Orders[orderID] = order
+	delete(pinAttempts, orderID)
+	deliveredCount++
+	notifyWatchers(order)
+
+	return order, nil
+}
+
+// OpenProof returns the photo or signature given as proof of delivery of an
+// order, with its content type
+func OpenProof(ctx context.Context, orderID string, kind models.ProofMethod) (_ io.ReadCloser, _ string, err error) {
+	ctx, span := tracer.Start(ctx, "repository.OpenProof", withOrderID(orderID))
+	defer func() { tracing.End(span, err) }()
+
+	store.Mutex.Lock()
+	order, exist := store.Orders[orderID]
+	store.Mutex.Unlock()
+	if !exist {
+		return nil, "", ErrOrderNotFound
+	}
+
+	var key string
+	if order.Delivery != nil {
+		switch kind {
+		case models.ProofPhoto:
+			key = order.Delivery.PhotoKey
+		case models.ProofSignature:
+			key = order.Delivery.SignatureKey
+		}
+	}
+	if key == "" {
+		return nil, "", fmt.Errorf("%w: the order has no %s", ErrProofNotFound, kind)
+	}
+	file, err := Blobs.Open(ctx, key)
+	if err != nil {
+		return nil, "", err
+	}
+	for contentType, extension := range proofTypes {
+		if filepath.Ext(key) == extension {
+			return file, contentType, nil
+		}
+	}
+	return file, "application/octet-stream", nil
+}
+
+// deliverable checks that the courier may complete the order now; an order
+// whose payment is with the gateway has to wait. It must be called with the
+// store locked.
+func deliverable(orderID, courierID string) error {
+	order, exist := store.Orders[orderID]
+	switch {
+	case !exist:
+		return ErrOrderNotFound
+	case atGateway[orderID]:
+		return ErrPaymentInProgress
+	case order.Status == models.StatusCancelled:
+		return ErrOrderCancelled
+	case order.Status != models.StatusOutForDelivery:
+		return fmt.Errorf("%w: %s order cannot become %s", ErrStatusChange, order.Status, models.StatusDelivered)
+	case order.CourierID != courierID:
+		return ErrNotAssigned
+	}
+	return nil
+}
+
+// storeProof puts an uploaded image in the blob store and returns its key.
+// Only JPEG and PNG images are accepted, judged by their content.
+func storeProof(ctx context.Context, orderID string, kind models.ProofMethod, upload io.Reader, now time.Time) (string, error) {
+	head := make([]byte, 512)
+	n, err := io.ReadFull(upload, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	if n == 0 {
+		return "", fmt.Errorf("%w: the %s is empty", ErrInvalidProof, kind)
+	}
+	extension, ok := proofTypes[http.DetectContentType(head[:n])]
+	if !ok {
+		return "", fmt.Errorf("%w: the %s must be a JPEG or PNG image", ErrInvalidProof, kind)
+	}
+
+	key := fmt.Sprintf("orders/%s/%s-%d%s", orderID, kind, now.UnixNano(), extension)
+	if err := Blobs.Put(ctx, key, io.MultiReader(bytes.NewReader(head[:n]), upload)); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// newDeliveryPIN draws the four digit PIN a customer tells the courier
+func newDeliveryPIN() string {
+	n, err := rand.Int(rand.Reader, big.NewInt(10000))
+	if err != nil {
+		panic(err)
+	}
+	return fmt.Sprintf("%04d", n.Int64())
+}
@@ -0,0 +1,192 @@
+package repository
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"weservefood/blob"
+	"weservefood/models"
+	"weservefood/payments"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	pngImage  = "\x89PNG\r\n\x1a\n signature strokes"
+	jpegImage = "\xff\xd8\xff\xe0 doorstep photo"
+)
+
+// useBlobs stores the proofs of a test in a directory of its own, which it returns
+func useBlobs(t *testing.T) string {
+	previous, root := Blobs, t.TempDir()
+	Blobs = blob.NewDir(root)
+	t.Cleanup(func() { Blobs = previous })
+	return root
+}
+
+// outForDelivery places an order requiring the proof and has the courier ride it
+func outForDelivery(t *testing.T, email, courierID string, proof models.ProofMethod) models.Order {
+	t.Helper()
+	ctx := context.Background()
+	AddRestaurant(models.Restaurant{ID: "r-payment", Name: "Payment Grill", Menu: []models.MenuItem{{Name: "Burger", PriceCents: 1000}}})
+	AddCourier(models.Courier{ID: courierID, Name: "Dee", Available: true})
+	order, err := CreateOrder(ctx, models.Order{Email: email, Address: "1 Pay St", RestaurantID: "r-payment", Items: []string{"Burger"}, ProofRequired: proof})
+	require.NoError(t, err)
+	_, err = ConfirmOrder(ctx, email, order.ID, payments.MethodVisa)
+	require.NoError(t, err)
+	_, err = AssignCourier(ctx, order.ID, courierID)
+	require.NoError(t, err)
+	_, err = AdvanceOrder(ctx, order.ID, models.StatusPreparing)
+	require.NoError(t, err)
+	order, err = AdvanceOrder(ctx, order.ID, models.StatusOutForDelivery)
+	require.NoError(t, err)
+	return order
+}
+
+func TestCreateOrderProof(t *testing.T) {
+	order, err := CreateOrder(context.Background(), models.Order{Email: "proof-default@example.com", Address: "1 Proof St", DeliveryPIN: "0000"})
+	require.NoError(t, err)
+	assert.Equal(t, models.ProofPIN, order.ProofRequired, "orders require the default proof")
+	assert.Regexp(t, `^\d{4}$`, order.DeliveryPIN)
+
+	photo, err := CreateOrder(context.Background(), models.Order{Email: "proof-photo@example.com", Address: "1 Proof St", ProofRequired: models.ProofPhoto, DeliveryPIN: "0000"})
+	require.NoError(t, err)
+	assert.Empty(t, photo.DeliveryPIN, "the customer cannot set a PIN")
+
+	_, err = CreateOrder(context.Background(), models.Order{Email: "proof-selfie@example.com", Address: "1 Proof St", ProofRequired: "selfie"})
+	assert.ErrorIs(t, err, ErrInvalidOrder)
+}
+
+func TestDeliverOrderWithPIN(t *testing.T) {
+	ctx := context.Background()
+	order := outForDelivery(t, "deliver-pin@example.com", "c-deliver-pin", models.ProofPIN)
+	wrongPIN := "0000"
+	if order.DeliveryPIN == wrongPIN {
+		wrongPIN = "9999"
+	}
+
+	_, err := DeliverOrder(ctx, order.ID, models.DeliveryConfirmation{CourierID: "c-someone-else", PIN: order.DeliveryPIN}, nil, nil)
+	assert.ErrorIs(t, err, ErrNotAssigned)
+	_, err = DeliverOrder(ctx, order.ID, models.DeliveryConfirmation{CourierID: "c-deliver-pin"}, nil, nil)
+	assert.ErrorIs(t, err, ErrProofRequired)
+	_, err = DeliverOrder(ctx, order.ID, models.DeliveryConfirmation{CourierID: "c-deliver-pin", PIN: wrongPIN}, nil, nil)
+	assert.ErrorIs(t, err, ErrWrongPIN)
+	assert.ErrorContains(t, err, "4 attempts left")
+
+	location := &models.LatLng{Lat: 52.53, Lng: 13.38}
+	delivered, err := DeliverOrder(ctx, order.ID, models.DeliveryConfirmation{CourierID: "c-deliver-pin", PIN: order.DeliveryPIN, Location: location}, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusDelivered, delivered.Status)
+	require.NotNil(t, delivered.Delivery)
+	assert.Equal(t, models.ProofPIN, delivered.Delivery.Method)
+	assert.True(t, delivered.Delivery.PINConfirmed)
+	assert.Equal(t, location, delivered.Delivery.Location)
+	deliveredAt, ok := delivered.StatusAt(models.StatusDelivered)
+	assert.True(t, ok)
+	assert.Equal(t, deliveredAt, delivered.Delivery.DeliveredAt)
+
+	store.Mutex.Lock()
+	assert.Zero(t, activeOrdersOf("c-deliver-pin"), "a delivered order frees the courier's slot")
+	store.Mutex.Unlock()
+	_, err = DeliverOrder(ctx, order.ID, models.DeliveryConfirmation{CourierID: "c-deliver-pin", PIN: order.DeliveryPIN}, nil, nil)
+	assert.ErrorIs(t, err, ErrStatusChange)
+	_, err = CancelOrder(ctx, order.ID, models.OrderCancellation{Initiator: models.InitiatorSupport, Reason: "never arrived"})
+	assert.ErrorIs(t, err, ErrCancellationRejected)
+}
+
+func TestDeliverOrderPINLocked(t *testing.T) {
+	order := outForDelivery(t, "deliver-locked@example.com", "c-deliver-locked", models.ProofPIN)
+	wrongPIN := "0000"
+	if order.DeliveryPIN == wrongPIN {
+		wrongPIN = "9999"
+	}
+
+	for range maxPINAttempts {
+		_, err := DeliverOrder(context.Background(), order.ID, models.DeliveryConfirmation{CourierID: "c-deliver-locked", PIN: wrongPIN}, nil, nil)
+		require.ErrorIs(t, err, ErrWrongPIN)
+	}
+	_, err := DeliverOrder(context.Background(), order.ID, models.DeliveryConfirmation{CourierID: "c-deliver-locked", PIN: order.DeliveryPIN}, nil, nil)
+	assert.ErrorIs(t, err, ErrPINLocked, "the right PIN no longer helps once guessed at")
+}
+
+func TestDeliverOrderWithPhoto(t *testing.T) {
+	root := useBlobs(t)
+	ctx := context.Background()
+	order := outForDelivery(t, "deliver-photo@example.com", "c-deliver-photo", models.ProofPhoto)
+
+	_, err := DeliverOrder(ctx, order.ID, models.DeliveryConfirmation{CourierID: "c-deliver-photo"}, nil, strings.NewReader(pngImage))
+	assert.ErrorIs(t, err, ErrProofRequired, "a signature does not stand in for the photo")
+	_, err = DeliverOrder(ctx, order.ID, models.DeliveryConfirmation{CourierID: "c-deliver-photo"}, strings.NewReader("GIF89a"), nil)
+	assert.ErrorIs(t, err, ErrInvalidProof)
+	kept, err := filepath.Glob(filepath.Join(root, "orders", "*", "*"))
+	require.NoError(t, err)
+	assert.Empty(t, kept, "uploads of a refused delivery are not kept")
+	_, _, err = OpenProof(ctx, order.ID, models.ProofPhoto)
+	assert.ErrorIs(t, err, ErrProofNotFound)
+
+	delivered, err := DeliverOrder(ctx, order.ID, models.DeliveryConfirmation{CourierID: "c-deliver-photo"}, strings.NewReader(jpegImage), strings.NewReader(pngImage))
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusDelivered, delivered.Status)
+	assert.Equal(t, models.ProofPhoto, delivered.Delivery.Method)
+	assert.False(t, delivered.Delivery.PINConfirmed)
+
+	for kind, want := range map[models.ProofMethod]struct{ content, contentType string }{
+		models.ProofPhoto:     {jpegImage, "image/jpeg"},
+		models.ProofSignature: {pngImage, "image/png"},
+	} {
+		proof, contentType, err := OpenProof(ctx, order.ID, kind)
+		require.NoError(t, err)
+		content, err := io.ReadAll(proof)
+		proof.Close()
+		require.NoError(t, err)
+		assert.Equal(t, want.content, string(content))
+		assert.Equal(t, want.contentType, contentType)
+	}
+	_, _, err = OpenProof(ctx, "nonexistentID", models.ProofPhoto)
+	assert.ErrorIs(t, err, ErrOrderNotFound)
+}
+
+func TestDeliverOrderWithSignature(t *testing.T) {
+	useBlobs(t)
+	order := outForDelivery(t, "deliver-signature@example.com", "c-deliver-signature", models.ProofSignature)
+
+	_, err := DeliverOrder(context.Background(), order.ID, models.DeliveryConfirmation{CourierID: "c-deliver-signature"}, nil, nil)
+	assert.ErrorIs(t, err, ErrProofRequired)
+
+	delivered, err := DeliverOrder(context.Background(), order.ID, models.DeliveryConfirmation{CourierID: "c-deliver-signature"}, nil, strings.NewReader(pngImage))
+	require.NoError(t, err)
+	assert.Equal(t, models.ProofSignature, delivered.Delivery.Method)
+	assert.NotEmpty(t, delivered.Delivery.SignatureKey)
+}
+
+func TestDeliverOrderNotOutForDelivery(t *testing.T) {
+	order := confirmPricedOrder(t, "deliver-early@example.com")
+
+	_, err := DeliverOrder(context.Background(), order.ID, models.DeliveryConfirmation{CourierID: "c-any", PIN: order.DeliveryPIN}, nil, nil)
+	assert.ErrorIs(t, err, ErrStatusChange)
+	_, err = DeliverOrder(context.Background(), "nonexistentID", models.DeliveryConfirmation{CourierID: "c-any"}, nil, nil)
+	assert.ErrorIs(t, err, ErrOrderNotFound)
+	_, err = DeliverOrder(context.Background(), order.ID, models.DeliveryConfirmation{}, nil, nil)
+	assert.ErrorIs(t, err, ErrInvalidProof)
+}
+
+func TestDeliverOrderWaitsForGateway(t *testing.T) {
+	order := outForDelivery(t, "deliver-at-gateway@example.com", "c-at-gateway", models.ProofPIN)
+	confirmation := models.DeliveryConfirmation{CourierID: "c-at-gateway", PIN: order.DeliveryPIN}
+	store.Mutex.Lock()
+	atGateway[order.ID] = true
+	store.Mutex.Unlock()
+
+	_, err := DeliverOrder(context.Background(), order.ID, confirmation, nil, nil)
+	assert.ErrorIs(t, err, ErrPaymentInProgress)
+
+	store.Mutex.Lock()
+	delete(atGateway, order.ID)
+	store.Mutex.Unlock()
+	delivered, err := DeliverOrder(context.Background(), order.ID, confirmation, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusDelivered, delivered.Status)
+}
@@ -0,0 +1,254 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+	"weservefood/geo"
+	"weservefood/models"
+	"weservefood/routing"
+	"weservefood/tracing"
+)
+
+var ErrBatchNotFound = errors.New("batch not found")
+
+// Dispatch bounds which orders waiting at a restaurant may share a courier.
+// MaxStops is further bounded by the free slots of the couriers.
+var Dispatch = routing.BatchSettings{MaxStops: 3, RadiusMetres: 2000, Window: 20 * time.Minute}
+
+// batches holds the dispatched batches by ID; it and batchCount are guarded
+// by the store mutex
+var (
+	batches    = make(map[string]models.Batch)
+	batchCount int
+)
+
+// PlanBatches proposes how the orders being prepared would be batched and
+// which couriers would take them, without dispatching anything
+func PlanBatches(ctx context.Context) (_ []models.Batch, err error) {
+	_, span := tracer.Start(ctx, "repository.PlanBatches")
+	defer func() { tracing.End(span, err) }()
+
+	store.Mutex.Lock()
+	defer store.Mutex.Unlock()
+
+	return planBatches(time.Now().UTC()), nil
+}
+
+// DispatchBatches plans batches and hands each one a courier was found for to
+// that courier. The orders of a dispatched batch are re-estimated along its
+// route. Batches no courier is free for are left for the next dispatch.
+func DispatchBatches(ctx context.Context) (_ []models.Batch, err error) {
+	_, span := tracer.Start(ctx, "repository.DispatchBatches")
+	defer func() { tracing.End(span, err) }()
+
+	store.Mutex.Lock()
+	defer store.Mutex.Unlock()
+
+	now := time.Now().UTC()
+	dispatched := []models.Batch{}
+	for _, batch := range planBatches(now) {
+		if batch.CourierID == "" {
+			continue
+		}
+		batchCount++
+		batch.ID = fmt.Sprintf("b-%06d", batchCount)
+		batches[batch.ID] = batch
+		for _, stop := range batch.Stops {
+			order := store.Orders[stop.OrderID]
+			order.CourierID = batch.CourierID
+			order.BatchID = batch.ID
+			estimateDelivery(&order, now)
+			store.Orders[order.ID] = order
+			notifyWatchers(order)
+		}
+		dispatched = append(dispatched, batch)
+	}
+	return dispatched, nil
+}
+
+// GetBatch retrieves a dispatched batch by its ID
+func GetBatch(ctx context.Context, batchID string) (_ models.Batch, err error) {
+	_, span := tracer.Start(ctx, "repository.GetBatch")
+	defer func() { tracing.End(span, err) }()
+
+	store.Mutex.Lock()
+	defer store.Mutex.Unlock()
+
+	batch, exist := batches[batchID]
+	if !exist {
+		return models.Batch{}, ErrBatchNotFound
+	}
+	return batch, nil
+}
+
+// planBatches batches the orders being prepared that have no courier yet,
+// restaurant by restaurant, keeping the delivery times they were promised, and offers the batches due first to the courier
+// whose free slots fit them most tightly. Orders whose address or restaurant
+// is not located cannot be routed and are left to be assigned one by one. It
+// must be called with the store locked.
+func planBatches(now time.Time) []models.Batch {
+	waiting := make(map[string][]routing.Stop)
+	for _, order := range store.Orders {
+		if order.Status != models.StatusPreparing || order.CourierID != "" {
+			continue
+		}
+		location := addressLocation(order.DeliveryAddress)
+		if location == nil {
+			continue
+		}
+		restaurant, err := GetRestaurant(order.RestaurantID)
+		if err != nil || restaurant.Location == nil {
+			continue
+		}
+		waiting[restaurant.ID] = append(waiting[restaurant.ID], routing.Stop{
+			OrderID:  order.ID,
+			Location: *location,
+			ReadyAt:  readyAt(restaurant, order),
+			DueAt:    promisedAt(order),
+		})
+	}
+
+	free := freeSlots()
+	settings := Dispatch
+	if most := mostFreeSlots(free); most > 0 {
+		settings.MaxStops = min(settings.MaxStops, most)
+	}
+	planner := routePlanner()
+
+	planned := []models.Batch{}
+	for restaurantID, stops := range waiting {
+		restaurant, _ := GetRestaurant(restaurantID)
+		// orders due at the same time must batch the same way on every run
+		sort.Slice(stops, func(i, j int) bool { return stops[i].OrderID < stops[j].OrderID })
+		for _, route := range planner.Batch(*restaurant.Location, now, stops, settings) {
+			planned = append(planned, toBatch(restaurantID, route, now))
+		}
+	}
+	sort.Slice(planned, func(i, j int) bool {
+		first, other := planned[i].Stops[0], planned[j].Stops[0]
+		if !first.DueAt.Equal(other.DueAt) {
+			return first.DueAt.Before(other.DueAt)
+		}
+		return first.OrderID < other.OrderID
+	})
+
+	for i := range planned {
+		if courierID := bestFit(free, len(planned[i].Stops)); courierID != "" {
+			planned[i].CourierID = courierID
+			free[courierID] -= len(planned[i].Stops)
+		}
+	}
+	return planned
+}
+
+// batchRide returns how long a dispatched order takes from leaving the
+// kitchen to reaching its stop, counting the wait for the rest of its batch
+// and the stops made before it. It must be called with the store locked.
+func batchRide(restaurant models.Restaurant, order models.Order, now time.Time) (time.Duration, bool) {
+	batch, exist := batches[order.BatchID]
+	if !exist {
+		return 0, false
+	}
+	for _, stop := range batch.Stops {
+		if stop.OrderID != order.ID {
+			continue
+		}
+		if order.Status == models.StatusOutForDelivery {
+			return stop.ArriveAt.Sub(batch.DepartAt), true
+		}
+		ready := readyAt(restaurant, order)
+		if ready.Before(now) {
+			ready = now
+		}
+		return max(stop.ArriveAt.Sub(ready), 0), true
+	}
+	return 0, false
+}
+
+// readyAt returns when an order being prepared leaves the kitchen
+func readyAt(restaurant models.Restaurant, order models.Order) time.Time {
+	preparingAt, _ := order.StatusAt(models.StatusPreparing)
+	return preparingAt.Add(Estimator.PrepTimeOf(prepTimes(restaurant, order.Items)))
+}
+
+// promisedAt is the delivery time an order was promised. Orders saved before
+// promises were recorded are held to their due time.
+func promisedAt(order models.Order) time.Time {
+	if !order.PromisedAt.IsZero() {
+		return order.PromisedAt
+	}
+	return dueAt(order)
+}
+
+// routePlanner times rides the way the delivery estimates do
+func routePlanner() routing.Planner {
+	return routing.Planner{
+		Distance: func(from, to models.LatLng) float64 {
+			return geo.Distance(from, to) * float64(Estimator.DetourPercent) / 100
+		},
+		MetresPerSecond: float64(max(Estimator.CourierSpeedKmh, 1)) / 3.6,
+		Handover:        Estimator.Handover,
+	}
+}
+
+// freeSlots returns how many more orders each available courier can carry.
+// It must be called with the store locked.
+func freeSlots() map[string]int {
+	free := make(map[string]int)
+	for _, courier := range GetCouriers() {
+		if slots := SlotCapacity - activeOrdersOf(courier.ID); courier.Available && slots > 0 {
+			free[courier.ID] = slots
+		}
+	}
+	return free
+}
+
+// mostFreeSlots returns the most orders any one courier can still take
+func mostFreeSlots(free map[string]int) int {
+	most := 0
+	for _, slots := range free {
+		most = max(most, slots)
+	}
+	return most
+}
+
+// bestFit picks the courier with the fewest free slots that still fit the
+// stops, so couriers with room to spare stay free for larger batches
+func bestFit(free map[string]int, stops int) string {
+	best := ""
+	for courierID, slots := range free {
+		if slots < stops {
+			continue
+		}
+		if best == "" || slots < free[best] || slots == free[best] && courierID < best {
+			best = courierID
+		}
+	}
+	return best
+}
+
+// toBatch turns a planned route into a batch
+func toBatch(restaurantID string, route routing.Route, now time.Time) models.Batch {
+	batch := models.Batch{
+		RestaurantID:   restaurantID,
+		DepartAt:       route.DepartAt,
+		DistanceMetres: int(math.Round(route.DistanceMetres)),
+		Stops:          make([]models.BatchStop, len(route.Stops)),
+		CreatedAt:      now,
+	}
+	for i, stop := range route.Stops {
+		batch.Stops[i] = models.BatchStop{
+			OrderID:   stop.OrderID,
+			Location:  stop.Location,
+			ArriveAt:  route.Arrivals[i],
+			DueAt:     stop.DueAt,
+			LegMetres: int(math.Round(route.LegMetres[i])),
+			OnTime:    !route.Arrivals[i].After(stop.DueAt),
+		}
+	}
+	return batch
+}
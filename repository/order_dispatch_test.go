@@ -0,0 +1,170 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+	"weservefood/models"
+	"weservefood/payments"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// useDispatchFloor leaves the couriers and payments of other tests out of the
+// batches planned by a test, and cancels the orders it places once it ends so
+// they are not batched by later tests
+func useDispatchFloor(t *testing.T) {
+	store.Mutex.Lock()
+	existing := make(map[string]bool, len(store.Orders))
+	for id := range store.Orders {
+		existing[id] = true
+	}
+	store.Mutex.Unlock()
+	catalog.Mutex.Lock()
+	couriers := catalog.Couriers
+	catalog.Couriers = make(map[string]models.Courier)
+	catalog.Mutex.Unlock()
+	capacity, gateway := SlotCapacity, PaymentGateway
+	SlotCapacity, PaymentGateway = 3, payments.NewFakeGateway()
+
+	t.Cleanup(func() {
+		store.Mutex.Lock()
+		for id, order := range store.Orders {
+			if !existing[id] {
+				order.SetStatus(models.StatusCancelled, time.Now().UTC())
+				store.Orders[id] = order
+			}
+		}
+		store.Mutex.Unlock()
+		catalog.Mutex.Lock()
+		catalog.Couriers = couriers
+		catalog.Mutex.Unlock()
+		SlotCapacity, PaymentGateway = capacity, gateway
+	})
+}
+
+// batchesOf keeps the batches of one restaurant
+func batchesOf(restaurantID string, batches []models.Batch) []models.Batch {
+	var kept []models.Batch
+	for _, batch := range batches {
+		if batch.RestaurantID == restaurantID {
+			kept = append(kept, batch)
+		}
+	}
+	return kept
+}
+
+func TestDispatchBatches(t *testing.T) {
+	useGazetteer(t)
+	useDispatchFloor(t)
+	AddRestaurant(models.Restaurant{
+		ID:       "r-dispatch",
+		Name:     "Batch Bistro",
+		Location: &models.LatLng{Lat: 52.5323, Lng: 13.3846},
+		Menu:     []models.MenuItem{{Name: "Soup", PriceCents: 900, PrepMinutes: 8}},
+	})
+	ctx := context.Background()
+	// orders are promised while no courier is free, which leaves room to batch them
+	prepare := func(email string, address models.Address) models.Order {
+		order, err := CreateOrder(ctx, models.Order{Email: email, DeliveryAddress: &address, RestaurantID: "r-dispatch", Items: []string{"Soup"}})
+		require.NoError(t, err)
+		_, err = ConfirmOrder(ctx, email, order.ID, payments.MethodVisa)
+		require.NoError(t, err)
+		order, err = AdvanceOrder(ctx, order.ID, models.StatusPreparing)
+		require.NoError(t, err)
+		return order
+	}
+	near := prepare("dispatch-near@example.com", models.Address{Street: "Torstraße 1", City: "Berlin", PostalCode: "10119", Country: "DE", Location: &models.LatLng{Lat: 52.5290, Lng: 13.4010}})
+	nearer := prepare("dispatch-nearer@example.com", models.Address{Street: "Chausseestraße 9", City: "Berlin", PostalCode: "10115", Country: "DE", Location: &models.LatLng{Lat: 52.5300, Lng: 13.3900}})
+	far := prepare("dispatch-far@example.com", models.Address{Street: "Sonnenallee 5", City: "Berlin", PostalCode: "12049", Country: "DE"})
+
+	// the busy courier's two free slots fit the pair of nearby orders most tightly
+	AddCourier(models.Courier{ID: "c-batch-busy", Name: "Bea", Available: true})
+	AddCourier(models.Courier{ID: "c-batch-free", Name: "Fritz", Available: true})
+	AddCourier(models.Courier{ID: "c-batch-off", Name: "Otto"})
+	busy, err := CreateOrder(ctx, models.Order{Email: "dispatch-busy@example.com", Address: "1 Busy St"})
+	require.NoError(t, err)
+	_, err = AssignCourier(ctx, busy.ID, "c-batch-busy")
+	require.NoError(t, err)
+
+	planned, err := PlanBatches(ctx)
+	require.NoError(t, err)
+	planned = batchesOf("r-dispatch", planned)
+	require.Len(t, planned, 2)
+	pair, single := planned[0], planned[1]
+	assert.Empty(t, pair.ID, "planned batches are not dispatched")
+	assert.Equal(t, "r-dispatch", pair.RestaurantID)
+	assert.Equal(t, "c-batch-busy", pair.CourierID)
+	require.Len(t, pair.Stops, 2)
+	assert.Equal(t, nearer.ID, pair.Stops[0].OrderID, "the closer drop-off comes first")
+	assert.Equal(t, near.ID, pair.Stops[1].OrderID)
+	for _, stop := range pair.Stops {
+		assert.True(t, stop.OnTime)
+	}
+	assert.Equal(t, pair.Stops[0].LegMetres+pair.Stops[1].LegMetres, pair.DistanceMetres)
+	assert.Equal(t, "c-batch-free", single.CourierID)
+	require.Len(t, single.Stops, 1)
+	assert.Equal(t, far.ID, single.Stops[0].OrderID)
+
+	unchanged, err := GetOrderByID(ctx, near.ID)
+	require.NoError(t, err)
+	assert.Empty(t, unchanged.CourierID)
+
+	dispatched, err := DispatchBatches(ctx)
+	require.NoError(t, err)
+	dispatched = batchesOf("r-dispatch", dispatched)
+	require.Len(t, dispatched, 2)
+	assert.NotEmpty(t, dispatched[0].ID)
+
+	batch, err := GetBatch(ctx, dispatched[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, dispatched[0], batch)
+	last, err := GetOrderByID(ctx, near.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "c-batch-busy", last.CourierID)
+	assert.Equal(t, batch.ID, last.BatchID)
+	// the last stop is reached after the first one, not straight from the restaurant
+	assert.WithinDuration(t, batch.Stops[1].ArriveAt.Add(Estimator.Handover), last.DueAt, time.Minute)
+	assert.False(t, last.DueAt.After(last.PromisedAt))
+
+	again, err := DispatchBatches(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, batchesOf("r-dispatch", again), "dispatched orders are not batched again")
+
+	// a courier taking the order over rides it on its own
+	reassigned, err := AssignCourier(ctx, near.ID, "c-batch-free")
+	require.NoError(t, err)
+	assert.Empty(t, reassigned.BatchID)
+
+	_, err = GetBatch(ctx, "b-missing")
+	assert.ErrorIs(t, err, ErrBatchNotFound)
+}
+
+func TestPlanBatchesWithoutCourier(t *testing.T) {
+	useGazetteer(t)
+	useDispatchFloor(t)
+	AddRestaurant(models.Restaurant{ID: "r-dispatch-idle", Name: "Idle Inn", Location: &models.LatLng{Lat: 52.5323, Lng: 13.3846}, Menu: []models.MenuItem{{Name: "Pie", PriceCents: 700}}})
+	ctx := context.Background()
+	order, err := CreateOrder(ctx, models.Order{
+		Email:           "dispatch-idle@example.com",
+		DeliveryAddress: &models.Address{Street: "Invalidenstraße 43", City: "Berlin", PostalCode: "10115", Country: "DE"},
+		RestaurantID:    "r-dispatch-idle",
+		Items:           []string{"Pie"},
+	})
+	require.NoError(t, err)
+	_, err = ConfirmOrder(ctx, "dispatch-idle@example.com", order.ID, payments.MethodVisa)
+	require.NoError(t, err)
+	_, err = AdvanceOrder(ctx, order.ID, models.StatusPreparing)
+	require.NoError(t, err)
+
+	planned, err := PlanBatches(ctx)
+	require.NoError(t, err)
+	planned = batchesOf("r-dispatch-idle", planned)
+	require.Len(t, planned, 1)
+	assert.Empty(t, planned[0].CourierID)
+
+	dispatched, err := DispatchBatches(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, batchesOf("r-dispatch-idle", dispatched), "batches wait for a free courier")
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"time"
+	"weservefood/eta"
+	"weservefood/models"
+)
+
+// Estimator estimates when orders placed against a restaurant arrive
+var Estimator = eta.Default()
+
+// estimateDelivery estimates when an order placed against a restaurant
+// arrives and moves its due time to match. Orders without a restaurant, whose
+// restaurant is gone or that are done with keep their due time. It must be called with the
+// store locked.
+func estimateDelivery(order *models.Order, now time.Time) {
+	if order.RestaurantID == "" || order.Status.Final() {
+		return
+	}
+	restaurant, err := GetRestaurant(order.RestaurantID)
+	if err != nil {
+		return
+	}
+	_, travel, err := deliveryTerms(restaurant, order.Address, order.DeliveryAddress)
+	if err != nil {
+		// the zone was removed after the order was placed
+		travel = DeliveryOffset
+	}
+	from, to := restaurant.Location, addressLocation(order.DeliveryAddress)
+	if ride, batched := batchRide(restaurant, *order, now); batched {
+		// the courier rides the batch's route rather than straight to the customer
+		from, to, travel = nil, nil, ride
+	}
+
+	since, _ := order.StatusAt(order.Status)
+	estimate := Estimator.Estimate(eta.Order{
+		Status:       order.Status,
+		StatusSince:  since,
+		PrepTimes:    prepTimes(restaurant, order.Items),
+		QueueAhead:   kitchenQueue(restaurant, *order, now),
+		From:         from,
+		To:           to,
+		Travel:       travel,
+		CourierReady: order.CourierID != "" || courierFree(),
+	}, now)
+	order.Estimate = &estimate
+	order.DueAt = estimate.DueAt()
+	order.DeliveryTime = order.DueAt.Format(time.TimeOnly)
+}
+
+// prepTimes returns the preparation time of each item on the restaurant's menu
+func prepTimes(restaurant models.Restaurant, items []string) []time.Duration {
+	prepTimes := make([]time.Duration, 0, len(items))
+	for _, item := range items {
+		for _, menuItem := range restaurant.Menu {
+			if menuItem.Name == item {
+				prepTimes = append(prepTimes, time.Duration(menuItem.PrepMinutes)*time.Minute)
+				break
+			}
+		}
+	}
+	return prepTimes
+}
+
+// kitchenQueue returns the preparation time left of each order ahead of a
+// waiting order in its restaurant's kitchen: the orders being prepared and the
+// ones confirmed before it. Orders that are only placed have not been paid
+// for, so they do not hold up the kitchen. It must be called with the store
+// locked.
+func kitchenQueue(restaurant models.Restaurant, order models.Order, now time.Time) []time.Duration {
+	if order.Status != models.StatusPlaced && order.Status != models.StatusConfirmed {
+		return nil
+	}
+	confirmedAt, _ := order.StatusAt(models.StatusConfirmed)
+
+	var queue []time.Duration
+	for _, other := range store.Orders {
+		if other.ID == order.ID || other.RestaurantID != order.RestaurantID {
+			continue
+		}
+		prepTime := Estimator.PrepTimeOf(prepTimes(restaurant, other.Items))
+		switch other.Status {
+		case models.StatusPreparing:
+			since, _ := other.StatusAt(models.StatusPreparing)
+			queue = append(queue, max(prepTime-now.Sub(since), 0))
+		case models.StatusConfirmed:
+			if otherAt, _ := other.StatusAt(models.StatusConfirmed); order.Status == models.StatusPlaced || otherAt.Before(confirmedAt) {
+				queue = append(queue, prepTime)
+			}
+		}
+	}
+	return queue
+}
+
+// courierFree reports whether an available courier has a free delivery slot.
+// It must be called with the store locked.
+func courierFree() bool {
+	for _, courier := range GetCouriers() {
+		if courier.Available && activeOrdersOf(courier.ID) < SlotCapacity {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+	"weservefood/models"
+	"weservefood/payments"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEstimateDelivery(t *testing.T) {
+	useGazetteer(t)
+	previous := Estimator
+	// couriers are shared by every test, so their wait is left out here
+	Estimator.CourierWait = 0
+	t.Cleanup(func() { Estimator = previous })
+	AddRestaurant(models.Restaurant{
+		ID:       "r-eta",
+		Name:     "Estimated Eats",
+		Location: &models.LatLng{Lat: 52.5323, Lng: 13.3846},
+		Menu:     []models.MenuItem{{Name: "Stew", PriceCents: 1400, PrepMinutes: 12}, {Name: "Bread", PriceCents: 300}},
+	})
+	place := func(email string) models.Order {
+		order, err := CreateOrder(context.Background(), models.Order{
+			Email:           email,
+			DeliveryAddress: &models.Address{Street: "Sonnenallee 5", City: "Berlin", PostalCode: "12049", Country: "DE"},
+			RestaurantID:    "r-eta",
+			Items:           []string{"Stew", "Bread"},
+		})
+		require.NoError(t, err)
+		return order
+	}
+
+	first := place("eta-first@example.com")
+	require.NotNil(t, first.Estimate)
+	assert.Zero(t, first.Estimate.QueueMinutes)
+	assert.Equal(t, 12, first.Estimate.PrepMinutes, "the slowest dish sets the preparation time")
+	assert.InDelta(t, 8700, first.Estimate.DistanceMetres, 150)
+	assert.Equal(t, 35, first.Estimate.TravelMinutes)
+	assert.Equal(t, first.Estimate.DueAt(), first.DueAt)
+
+	first, err := ConfirmOrder(context.Background(), "eta-first@example.com", first.ID, payments.MethodVisa)
+	require.NoError(t, err)
+
+	// the confirmed order is ahead in the kitchen, which has two slots
+	second := place("eta-second@example.com")
+	assert.Equal(t, 6, second.Estimate.QueueMinutes)
+
+	first, err = AdvanceOrder(context.Background(), first.ID, models.StatusPreparing)
+	require.NoError(t, err)
+	assert.Zero(t, first.Estimate.QueueMinutes)
+	assert.Equal(t, 12, first.Estimate.PrepMinutes)
+
+	first, err = AdvanceOrder(context.Background(), first.ID, models.StatusOutForDelivery)
+	require.NoError(t, err)
+	assert.Zero(t, first.Estimate.PrepMinutes)
+	assert.Equal(t, 35, first.Estimate.TravelMinutes)
+	assert.WithinDuration(t, time.Now().Add(38*time.Minute), first.DueAt, time.Minute)
+
+	// orders without a restaurant keep the fixed delivery offset
+	plain, err := CreateOrder(context.Background(), models.Order{Email: "eta-plain@example.com", Address: "1 Plain St"})
+	require.NoError(t, err)
+	assert.Nil(t, plain.Estimate)
+	assert.Equal(t, plain.CreatedAt.Add(DeliveryOffset), plain.DueAt)
+}
+
+func TestEstimateDeliveryTime(t *testing.T) {
+	previous := Estimator
+	Estimator.CourierWait = 0
+	t.Cleanup(func() { Estimator = previous })
+	AddRestaurant(models.Restaurant{ID: "r-eta-clock", Name: "Clockwork Kitchen", Menu: []models.MenuItem{{Name: "Stew", PriceCents: 1400, PrepMinutes: 12}}})
+
+	now := time.Date(2024, time.May, 1, 11, 58, 30, 0, time.UTC)
+	order := models.Order{ID: "eta-clock", RestaurantID: "r-eta-clock", Items: []string{"Stew"}, Status: models.StatusPlaced}
+	store.Mutex.Lock()
+	estimateDelivery(&order, now)
+	store.Mutex.Unlock()
+
+	// 12 minutes in the kitchen, the 30 minute ride and 3 minutes handing over
+	assert.Equal(t, time.Date(2024, time.May, 1, 12, 43, 30, 0, time.UTC), order.DueAt)
+	assert.Equal(t, "12:43:30", order.DeliveryTime)
+}
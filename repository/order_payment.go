@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+	"weservefood/models"
+	"weservefood/payments"
+	"weservefood/tracing"
+)
+
+var (
+	ErrOrderConfirmed    = errors.New("order is already confirmed")
+	ErrPaymentInProgress = errors.New("payment is already being authorized")
+)
+
+// PaymentGateway collects the money for orders
+var PaymentGateway payments.PaymentGateway = payments.NewFakeGateway()
+
+// LateRefundPercent is the share of the payment refunded when an order is
+// cancelled while it is being prepared
+var LateRefundPercent = 50
+
+// atGateway holds the orders whose payment is with the gateway; it is guarded
+// by the store mutex
+var atGateway = make(map[string]bool)
+
+// ConfirmOrder authorizes the total of a placed order and confirms it. A
+// declined authorization leaves the order placed with the failure recorded on
+// its payment, so it can be retried with another payment method.
+func ConfirmOrder(ctx context.Context, email, orderID, paymentMethod string) (_ models.Order, err error) {
+	ctx, span := tracer.Start(ctx, "repository.ConfirmOrder", withOrderID(orderID))
+	defer func() { tracing.End(span, err) }()
+
+	store.Mutex.Lock()
+	defer store.Mutex.Unlock()
+
+	order, exist := store.Orders[orderID]
+	switch {
+	case !exist:
+		return models.Order{}, ErrOrderNotFound
+	case order.Email != email:
+		return models.Order{}, ErrEmailMismatch
+	case order.Status == models.StatusCancelled:
+		return models.Order{}, ErrOrderCancelled
+	case order.Status != models.StatusPlaced:
+		return models.Order{}, ErrOrderConfirmed
+	case order.Price.TotalCents <= 0:
+		return models.Order{}, fmt.Errorf("%w: order has no price to pay", ErrInvalidOrder)
+	case atGateway[orderID]:
+		return models.Order{}, ErrPaymentInProgress
+	}
+	intent := payments.NewIntent(orderID, order.Price.TotalCents)
+	if order.Payment != nil {
+		intent.CreatedAt = order.Payment.CreatedAt
+	}
+
+	order, authErr := callGateway(orderID, func() error {
+		return payments.Authorize(ctx, PaymentGateway, intent, paymentMethod)
+	})
+
+	order.Payment = intent
+	if authErr == nil {
+		now := time.Now().UTC()
+		order.SetStatus(models.StatusConfirmed, now)
+		estimateDelivery(&order, now)
+	}
+	store.Orders[orderID] = order
+	notifyWatchers(order)
+
+	return order, authErr
+}
+
+// callGateway runs call, which works with the gateway, without holding the
+// store. It must be called with the store locked, and locks it again before
+// returning the order as it is after the call, along with the call's error.
+// The order stays in atGateway meanwhile, so no other payment operation or
+// status change starts on it.
+func callGateway(orderID string, call func() error) (models.Order, error) {
+	atGateway[orderID] = true
+	store.Mutex.Unlock()
+
+	err := call()
+
+	store.Mutex.Lock()
+	delete(atGateway, orderID)
+	return store.Orders[orderID], err
+}
+
+// settlementOf tells whether cancelling an order moves money at the gateway:
+// an authorized payment is voided, and a captured one is refunded as
+// refundFor allows. The amount and reason are those of the refund.
+func settlementOf(order models.Order) (amount int, reason string, settles bool) {
+	if order.Payment == nil {
+		return 0, "", false
+	}
+	switch order.Payment.Status {
+	case models.PaymentAuthorized:
+		return 0, "", true
+	case models.PaymentCaptured:
+		amount, reason = refundFor(order)
+		return amount, reason, amount > 0
+	default:
+		return 0, "", false
+	}
+}
+
+// settlePayment returns the money of an order being cancelled, as
+// settlementOf tells. Only payments the gateway authorized for the order are
+// settled. It returns the settled payment, or nil when no money moves. It
+// calls the gateway, so it must run through callGateway.
+func settlePayment(ctx context.Context, order models.Order) (*models.PaymentIntent, error) {
+	amount, reason, settles := settlementOf(order)
+	if !settles {
+		return nil, nil
+	}
+
+	intent := *order.Payment
+	if err := payments.Verify(ctx, PaymentGateway, &intent, order.ID); err != nil {
+		return nil, err
+	}
+	var err error
+	if intent.Status == models.PaymentAuthorized {
+		err = payments.Void(ctx, PaymentGateway, &intent)
+	} else {
+		err = payments.Refund(ctx, PaymentGateway, &intent, amount, reason)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &intent, nil
+}
+
+// refundFor applies the refund policy to a cancelled order's captured payment:
+// everything before preparation starts, LateRefundPercent while it is being
+// prepared, and nothing once it is out for delivery
+func refundFor(order models.Order) (amountCents int, reason string) {
+	left := order.Payment.CapturedCents - order.Payment.RefundedCents
+	switch order.Status {
+	case models.StatusPlaced, models.StatusConfirmed:
+		return left, "cancelled before preparation"
+	case models.StatusPreparing:
+		return min(left, order.Payment.CapturedCents*LateRefundPercent/100), "cancelled during preparation"
+	default:
+		return 0, ""
+	}
+}
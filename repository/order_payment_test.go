@@ -0,0 +1,345 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"weservefood/models"
+	"weservefood/payments"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func createPricedOrder(t *testing.T, email string) models.Order {
+	t.Helper()
+	AddRestaurant(models.Restaurant{ID: "r-payment", Name: "Payment Grill", Menu: []models.MenuItem{{Name: "Burger", PriceCents: 1000}}})
+	order, err := CreateOrder(context.Background(), models.Order{Email: email, Address: "1 Pay St", RestaurantID: "r-payment", Items: []string{"Burger"}})
+	require.NoError(t, err)
+	return order
+}
+
+func TestConfirmOrder(t *testing.T) {
+	order := createPricedOrder(t, "confirm@example.com")
+
+	confirmed, err := ConfirmOrder(context.Background(), "confirm@example.com", order.ID, payments.MethodVisa)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusConfirmed, confirmed.Status)
+	require.NotNil(t, confirmed.Payment)
+	assert.Equal(t, models.PaymentAuthorized, confirmed.Payment.Status)
+	assert.Equal(t, order.Price.TotalCents, confirmed.Payment.AmountCents)
+	_, ok := confirmed.StatusAt(models.StatusConfirmed)
+	assert.True(t, ok)
+
+	_, err = ConfirmOrder(context.Background(), "confirm@example.com", order.ID, payments.MethodVisa)
+	assert.ErrorIs(t, err, ErrOrderConfirmed)
+}
+
+func TestConfirmOrderDeclined(t *testing.T) {
+	order := createPricedOrder(t, "declined@example.com")
+
+	declined, err := ConfirmOrder(context.Background(), "declined@example.com", order.ID, payments.MethodDeclined)
+	assert.ErrorIs(t, err, payments.ErrDeclined)
+	assert.Equal(t, models.StatusPlaced, declined.Status)
+	assert.Equal(t, models.PaymentFailed, declined.Payment.Status)
+
+	stored, _ := GetOrderByID(context.Background(), order.ID)
+	assert.Equal(t, models.PaymentFailed, stored.Payment.Status)
+	assert.NotEmpty(t, stored.Payment.FailureReason)
+
+	confirmed, err := ConfirmOrder(context.Background(), "declined@example.com", order.ID, payments.MethodMastercard)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusConfirmed, confirmed.Status)
+	assert.Equal(t, declined.Payment.CreatedAt, confirmed.Payment.CreatedAt)
+}
+
+func TestConfirmOrderRejected(t *testing.T) {
+	order := createPricedOrder(t, "rejected@example.com")
+	unpriced, _ := CreateOrder(context.Background(), models.Order{Email: "rejected@example.com", Address: "1 Pay St"})
+
+	_, err := ConfirmOrder(context.Background(), "rejected@example.com", "nonexistentID", payments.MethodVisa)
+	assert.ErrorIs(t, err, ErrOrderNotFound)
+	_, err = ConfirmOrder(context.Background(), "wrong@example.com", order.ID, payments.MethodVisa)
+	assert.ErrorIs(t, err, ErrEmailMismatch)
+	_, err = ConfirmOrder(context.Background(), "rejected@example.com", unpriced.ID, payments.MethodVisa)
+	assert.ErrorIs(t, err, ErrInvalidOrder)
+
+	_, err = CancelOrder(context.Background(), order.ID, models.OrderCancellation{Email: "rejected@example.com", Reason: "changed my mind"})
+	require.NoError(t, err)
+	_, err = ConfirmOrder(context.Background(), "rejected@example.com", order.ID, payments.MethodVisa)
+	assert.ErrorIs(t, err, ErrOrderCancelled)
+}
+
+func TestCancelConfirmedOrderVoidsPayment(t *testing.T) {
+	order := createPricedOrder(t, "void@example.com")
+	confirmed, err := ConfirmOrder(context.Background(), "void@example.com", order.ID, payments.MethodVisa)
+	require.NoError(t, err)
+
+	_, err = CancelOrder(context.Background(), order.ID, models.OrderCancellation{Email: "void@example.com", Reason: "changed my mind"})
+	require.NoError(t, err)
+
+	cancelled, _ := GetOrderByID(context.Background(), order.ID)
+	assert.Equal(t, models.StatusCancelled, cancelled.Status)
+	assert.Equal(t, models.PaymentVoided, cancelled.Payment.Status)
+	// the confirmed snapshot keeps its own copy of the payment
+	assert.Equal(t, models.PaymentAuthorized, confirmed.Payment.Status)
+
+	auth, ok := PaymentGateway.(*payments.FakeGateway).Authorization(confirmed.Payment.AuthorizationID)
+	require.True(t, ok)
+	assert.True(t, auth.Voided)
+}
+
+func confirmPricedOrder(t *testing.T, email string) models.Order {
+	t.Helper()
+	order := createPricedOrder(t, email)
+	confirmed, err := ConfirmOrder(context.Background(), email, order.ID, payments.MethodVisa)
+	require.NoError(t, err)
+	return confirmed
+}
+
+func TestAdvanceOrder(t *testing.T) {
+	order := confirmPricedOrder(t, "advance@example.com")
+
+	_, err := AdvanceOrder(context.Background(), order.ID, models.StatusOutForDelivery)
+	assert.ErrorIs(t, err, ErrStatusChange)
+
+	preparing, err := AdvanceOrder(context.Background(), order.ID, models.StatusPreparing)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusPreparing, preparing.Status)
+	assert.Equal(t, models.PaymentCaptured, preparing.Payment.Status)
+	assert.Equal(t, order.Price.TotalCents, preparing.Payment.CapturedCents)
+	assert.Equal(t, models.PaymentAuthorized, order.Payment.Status)
+
+	outForDelivery, err := AdvanceOrder(context.Background(), order.ID, models.StatusOutForDelivery)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusOutForDelivery, outForDelivery.Status)
+
+	_, err = AdvanceOrder(context.Background(), "nonexistentID", models.StatusPreparing)
+	assert.ErrorIs(t, err, ErrOrderNotFound)
+	placed := createPricedOrder(t, "advance@example.com")
+	_, err = AdvanceOrder(context.Background(), placed.ID, models.StatusPreparing)
+	assert.ErrorIs(t, err, ErrStatusChange, "an unpaid order cannot be prepared")
+}
+
+func TestCancelOrderRefunds(t *testing.T) {
+	previous := LateRefundPercent
+	LateRefundPercent = 40
+	t.Cleanup(func() { LateRefundPercent = previous })
+
+	cancelAt := func(statuses ...models.OrderStatus) models.PaymentIntent {
+		order := confirmPricedOrder(t, "refund@example.com")
+		for _, status := range statuses {
+			_, err := AdvanceOrder(context.Background(), order.ID, status)
+			require.NoError(t, err)
+		}
+		// support, as customers cannot cancel orders out for delivery
+		_, err := CancelOrder(context.Background(), order.ID, models.OrderCancellation{Initiator: models.InitiatorSupport, Reason: "courier had an accident"})
+		require.NoError(t, err)
+		cancelled, _ := GetOrderByID(context.Background(), order.ID)
+		return *cancelled.Payment
+	}
+
+	total := 1000 + DeliveryFeeCents
+
+	voided := cancelAt()
+	assert.Equal(t, models.PaymentVoided, voided.Status)
+	assert.Empty(t, voided.Refunds)
+
+	partial := cancelAt(models.StatusPreparing)
+	assert.Equal(t, models.PaymentCaptured, partial.Status)
+	assert.Equal(t, total*40/100, partial.RefundedCents)
+	require.Len(t, partial.Refunds, 1)
+	assert.Equal(t, "cancelled during preparation", partial.Refunds[0].Reason)
+	auth, _ := PaymentGateway.(*payments.FakeGateway).Authorization(partial.AuthorizationID)
+	assert.Equal(t, total*40/100, auth.RefundedCents)
+
+	none := cancelAt(models.StatusPreparing, models.StatusOutForDelivery)
+	assert.Equal(t, models.PaymentCaptured, none.Status)
+	assert.Zero(t, none.RefundedCents)
+	assert.Empty(t, none.Refunds)
+}
+
+func TestRefundFor(t *testing.T) {
+	order := models.Order{Status: models.StatusConfirmed, Payment: &models.PaymentIntent{CapturedCents: 1000, RefundedCents: 100}}
+	amount, _ := refundFor(order)
+	assert.Equal(t, 900, amount)
+
+	order.Status = models.StatusPreparing
+	amount, _ = refundFor(order)
+	assert.Equal(t, 1000*LateRefundPercent/100, amount)
+
+	order.Status = models.StatusOutForDelivery
+	amount, _ = refundFor(order)
+	assert.Zero(t, amount)
+}
+
+func TestCreateOrderIgnoresClientPayment(t *testing.T) {
+	victim := confirmPricedOrder(t, "victim@example.com")
+	victim, err := AdvanceOrder(context.Background(), victim.ID, models.StatusPreparing)
+	require.NoError(t, err)
+
+	forged := *victim.Payment
+	AddRestaurant(models.Restaurant{ID: "r-payment", Name: "Payment Grill", Menu: []models.MenuItem{{Name: "Burger", PriceCents: 1000}}})
+	order, err := CreateOrder(context.Background(), models.Order{
+		Email: "attacker@example.com", Address: "1 Pay St", RestaurantID: "r-payment", Items: []string{"Burger"},
+		Payment:         &forged,
+		Cancellation:    &models.Cancellation{Initiator: models.InitiatorSupport, Reason: "forged"},
+		BatchID:         "b-forged",
+		CourierPosition: &models.LocationPing{Location: models.LatLng{Lat: 52.5, Lng: 13.4}},
+	})
+	require.NoError(t, err)
+	assert.Nil(t, order.Payment)
+	assert.Nil(t, order.Cancellation)
+	assert.Empty(t, order.BatchID)
+	assert.Nil(t, order.CourierPosition)
+
+	_, err = CancelOrder(context.Background(), order.ID, models.OrderCancellation{Email: "attacker@example.com", Reason: "changed my mind"})
+	require.NoError(t, err)
+	auth, _ := PaymentGateway.(*payments.FakeGateway).Authorization(victim.Payment.AuthorizationID)
+	assert.Zero(t, auth.RefundedCents)
+	assert.False(t, auth.Voided)
+}
+
+func TestCancelOrderSettlesOnlyItsOwnPayment(t *testing.T) {
+	victim := confirmPricedOrder(t, "own-victim@example.com")
+	order := confirmPricedOrder(t, "own@example.com")
+
+	for name, forge := range map[string]func(*models.PaymentIntent){
+		"intent":        func(intent *models.PaymentIntent) { *intent = *victim.Payment },
+		"authorization": func(intent *models.PaymentIntent) { intent.AuthorizationID = victim.Payment.AuthorizationID },
+	} {
+		t.Run(name, func(t *testing.T) {
+			// as a tampered storage file would load it
+			store.Mutex.Lock()
+			stored := store.Orders[order.ID]
+			forged := *order.Payment
+			forge(&forged)
+			stored.Payment = &forged
+			store.Orders[order.ID] = stored
+			store.Mutex.Unlock()
+
+			_, err := CancelOrder(context.Background(), order.ID, models.OrderCancellation{Email: "own@example.com", Reason: "changed my mind"})
+			assert.ErrorIs(t, err, payments.ErrForeignPayment)
+			auth, _ := PaymentGateway.(*payments.FakeGateway).Authorization(victim.Payment.AuthorizationID)
+			assert.False(t, auth.Voided)
+		})
+	}
+
+	store.Mutex.Lock()
+	stored := store.Orders[order.ID]
+	stored.Payment = order.Payment
+	store.Orders[order.ID] = stored
+	store.Mutex.Unlock()
+	_, err := CancelOrder(context.Background(), order.ID, models.OrderCancellation{Email: "own@example.com", Reason: "changed my mind"})
+	require.NoError(t, err)
+	_, err = CancelOrder(context.Background(), victim.ID, models.OrderCancellation{Email: "own-victim@example.com", Reason: "changed my mind"})
+	require.NoError(t, err)
+}
+
+// unlockedGateway fails the test when the gateway is called with the store locked
+type unlockedGateway struct {
+	*payments.FakeGateway
+	t *testing.T
+}
+
+func (g unlockedGateway) checkUnlocked() {
+	if !store.Mutex.TryLock() {
+		g.t.Error("gateway called with the store locked")
+		return
+	}
+	store.Mutex.Unlock()
+}
+
+func (g unlockedGateway) Authorize(ctx context.Context, req payments.AuthorizeRequest) (string, error) {
+	g.checkUnlocked()
+	return g.FakeGateway.Authorize(ctx, req)
+}
+
+func (g unlockedGateway) Capture(ctx context.Context, authorizationID string, amountCents int) error {
+	g.checkUnlocked()
+	return g.FakeGateway.Capture(ctx, authorizationID, amountCents)
+}
+
+func (g unlockedGateway) Void(ctx context.Context, authorizationID string) error {
+	g.checkUnlocked()
+	return g.FakeGateway.Void(ctx, authorizationID)
+}
+
+func (g unlockedGateway) Refund(ctx context.Context, authorizationID string, amountCents int) (string, error) {
+	g.checkUnlocked()
+	return g.FakeGateway.Refund(ctx, authorizationID, amountCents)
+}
+
+func TestGatewayCalledWithoutStoreLock(t *testing.T) {
+	previous := PaymentGateway
+	PaymentGateway = unlockedGateway{FakeGateway: payments.NewFakeGateway(), t: t}
+	t.Cleanup(func() { PaymentGateway = previous })
+
+	voided := confirmPricedOrder(t, "unlocked@example.com")
+	_, err := CancelOrder(context.Background(), voided.ID, models.OrderCancellation{Email: "unlocked@example.com", Reason: "changed my mind"})
+	require.NoError(t, err)
+
+	refunded := confirmPricedOrder(t, "unlocked@example.com")
+	_, err = AdvanceOrder(context.Background(), refunded.ID, models.StatusPreparing)
+	require.NoError(t, err)
+	_, err = CancelOrder(context.Background(), refunded.ID, models.OrderCancellation{Initiator: models.InitiatorSupport, Reason: "kitchen closed"})
+	require.NoError(t, err)
+}
+
+// refusingGateway fails the test whenever the gateway is called
+type refusingGateway struct {
+	payments.PaymentGateway
+	t *testing.T
+}
+
+func (g refusingGateway) Reference(ctx context.Context, authorizationID string) (string, error) {
+	g.t.Error("gateway called")
+	return "", errors.New("gateway called")
+}
+
+func TestCancelOrderWithoutRefundSkipsGateway(t *testing.T) {
+	order := outForDelivery(t, "no-refund@example.com", "c-no-refund", models.ProofPIN)
+	previous := PaymentGateway
+	PaymentGateway = refusingGateway{t: t}
+	t.Cleanup(func() { PaymentGateway = previous })
+
+	_, err := CancelOrder(context.Background(), order.ID, models.OrderCancellation{Initiator: models.InitiatorSupport, Reason: "customer not home"})
+	require.NoError(t, err)
+
+	cancelled, err := GetOrderByID(context.Background(), order.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusCancelled, cancelled.Status)
+	assert.Equal(t, *order.Payment, *cancelled.Payment)
+}
+
+// movingGateway lets the order move on while its authorization is voided
+type movingGateway struct {
+	payments.PaymentGateway
+	orderID string
+	status  models.OrderStatus
+}
+
+func (g movingGateway) Void(ctx context.Context, authorizationID string) error {
+	store.Mutex.Lock()
+	order := store.Orders[g.orderID]
+	order.Status = g.status
+	store.Orders[g.orderID] = order
+	store.Mutex.Unlock()
+	return g.PaymentGateway.Void(ctx, authorizationID)
+}
+
+func TestCancelOrderRecheckedAfterGateway(t *testing.T) {
+	order := confirmPricedOrder(t, "moved-on@example.com")
+	previous := PaymentGateway
+	PaymentGateway = movingGateway{PaymentGateway: previous, orderID: order.ID, status: models.StatusOutForDelivery}
+	t.Cleanup(func() { PaymentGateway = previous })
+
+	_, err := CancelOrder(context.Background(), order.ID, models.OrderCancellation{Email: "moved-on@example.com", Reason: "changed my mind"})
+	assert.ErrorIs(t, err, ErrCancellationRejected)
+
+	moved, err := GetOrderByID(context.Background(), order.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusOutForDelivery, moved.Status)
+	assert.Nil(t, moved.Cancellation)
+	assert.Equal(t, models.PaymentVoided, moved.Payment.Status)
+}
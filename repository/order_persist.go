@@ -0,0 +1,191 @@
+package repository
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+	"weservefood/models"
+)
+
+// persistence remembers the file orders are flushed to by the file storage
+// backend; it is empty for the memory backend
+var persistence struct {
+	sync.Mutex
+	path      string
+	lastFlush time.Time
+	lastErr   error
+}
+
+// savedOrder is an order as it is saved, with the delivery PIN and the wrong
+// PINs given so far, which are never sent to clients
+type savedOrder struct {
+	models.Order
+	DeliveryPIN string `json:"delivery_pin,omitempty"`
+	PINAttempts int    `json:"pin_attempts,omitempty"`
+}
+
+// savedDocument is what SaveOrders writes and LoadOrders reads back
+type savedDocument struct {
+	Orders []savedOrder                     `json:"orders"`
+	Trails map[string][]models.LocationPing `json:"trails,omitempty"`
+	// Batches are the dispatched batches the orders' batch_id refer to, and
+	// BatchCount numbers the next one
+	Batches    []models.Batch `json:"batches,omitempty"`
+	BatchCount int            `json:"batch_count,omitempty"`
+}
+
+// SaveOrders writes every order, oldest first, the delivery trails and the
+// dispatched batches as a JSON document LoadOrders reads back
+func SaveOrders(w io.Writer) error {
+	store.Mutex.Lock()
+	orders := make([]savedOrder, 0, len(store.Orders))
+	for _, order := range store.Orders {
+		orders = append(orders, savedOrder{Order: order, DeliveryPIN: order.DeliveryPIN, PINAttempts: pinAttempts[order.ID]})
+	}
+	// trails are only appended to or replaced, so sharing them is safe
+	savedTrails := make(map[string][]models.LocationPing, len(trails))
+	for orderID, trail := range trails {
+		savedTrails[orderID] = trail[:len(trail):len(trail)]
+	}
+	// batches are never changed once dispatched
+	savedBatches := make([]models.Batch, 0, len(batches))
+	for _, batch := range batches {
+		savedBatches = append(savedBatches, batch)
+	}
+	savedBatchCount := batchCount
+	store.Mutex.Unlock()
+
+	sort.Slice(savedBatches, func(i, j int) bool { return savedBatches[i].ID < savedBatches[j].ID })
+
+	sort.Slice(orders, func(i, j int) bool {
+		if !orders[i].CreatedAt.Equal(orders[j].CreatedAt) {
+			return orders[i].CreatedAt.Before(orders[j].CreatedAt)
+		}
+		return orders[i].ID < orders[j].ID
+	})
+
+	return json.NewEncoder(w).Encode(savedDocument{Orders: orders, Trails: savedTrails, Batches: savedBatches, BatchCount: savedBatchCount})
+}
+
+// LoadOrders adds the orders, trails and batches of a document written by
+// SaveOrders, replacing those with the same ID
+func LoadOrders(r io.Reader) error {
+	var document savedDocument
+	if err := json.NewDecoder(r).Decode(&document); err != nil {
+		return err
+	}
+
+	store.Mutex.Lock()
+	defer store.Mutex.Unlock()
+
+	for _, saved := range document.Orders {
+		order := saved.Order
+		order.DeliveryPIN = saved.DeliveryPIN
+		if saved.PINAttempts > 0 {
+			pinAttempts[order.ID] = saved.PINAttempts
+		} else {
+			delete(pinAttempts, order.ID)
+		}
+		if previous, exist := store.Orders[order.ID]; exist {
+			placedCount--
+			switch previous.Status {
+			case models.StatusDelivered:
+				deliveredCount--
+			case models.StatusCancelled:
+				cancelledCount--
+			}
+		}
+		store.Orders[order.ID] = order
+		placedCount++
+		switch order.Status {
+		case models.StatusDelivered:
+			deliveredCount++
+		case models.StatusCancelled:
+			cancelledCount++
+		}
+	}
+	for orderID, trail := range document.Trails {
+		trails[orderID] = trail
+	}
+	for _, batch := range document.Batches {
+		batches[batch.ID] = batch
+	}
+	batchCount = max(batchCount, document.BatchCount)
+
+	return nil
+}
+
+// OpenFile replays the orders saved at path, when the file exists, and makes
+// Flush save them back to it
+func OpenFile(path string) error {
+	file, err := os.Open(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return err
+	default:
+		defer file.Close()
+		if err := LoadOrders(file); err != nil {
+			return err
+		}
+	}
+
+	persistence.Lock()
+	persistence.path = path
+	persistence.Unlock()
+	return nil
+}
+
+// Flush saves the orders to the file given to OpenFile. The file is replaced
+// atomically, so a crash while flushing leaves the previous copy intact.
+// Without OpenFile there is nothing to flush.
+func Flush() error {
+	persistence.Lock()
+	defer persistence.Unlock()
+	if persistence.path == "" {
+		return nil
+	}
+
+	persistence.lastErr = writeFile(persistence.path, SaveOrders)
+	if persistence.lastErr == nil {
+		persistence.lastFlush = time.Now().UTC()
+	}
+	return persistence.lastErr
+}
+
+// FlushStatus returns the file orders are flushed to, when the last flush
+// succeeded and the error of the last flush, if it failed
+func FlushStatus() (path string, lastFlush time.Time, err error) {
+	persistence.Lock()
+	defer persistence.Unlock()
+	return persistence.path, persistence.lastFlush, persistence.lastErr
+}
+
+// writeFile replaces the file at path with what save writes, through a
+// temporary file renamed over it
+func writeFile(path string, save func(io.Writer) error) error {
+	temp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+
+	if err := save(temp); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Sync(); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(temp.Name(), path)
+}
@@ -1,39 +1,188 @@
 package repository
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"fmt"
-	"strconv"
+	"strings"
 	"time"
 	"weservefood/models"
+	"weservefood/payments"
+	"weservefood/tracing"
 
-	"math/rand"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+var (
+	ErrOrderNotFound  = errors.New("order not found")
+	ErrEmailMismatch  = errors.New("email does not match")
+	ErrInvalidOrder   = errors.New("invalid order")
+	ErrOrderCancelled = errors.New("order is already cancelled")
+	ErrCourierFull    = errors.New("courier has no free delivery slot")
+	ErrStatusChange   = errors.New("order cannot move to that status")
+)
+
+// DeliveryFeeCents is charged on every order placed against a restaurant
+var DeliveryFeeCents = 299
+
+// DeliveryOffset is how long after placing an order it is expected to arrive
+var DeliveryOffset = 30 * time.Minute
+
+// SlotCapacity is how many active orders a courier may carry at once, and so
+// how many stops a dispatched batch may have
+var SlotCapacity = 1
+
 var store = models.InMemoryStore{
 	Orders: make(map[string]models.Order),
 }
 
-// Generate a unique order ID using the current timestamp and a random number
+var tracer = tracing.Tracer("weservefood/repository")
+
+// placedCount, deliveredCount and cancelledCount are guarded by the store mutex
+var placedCount, deliveredCount, cancelledCount int
+
+// generateOrderID returns 8 random bytes in hex, too many for two orders to
+// share an ID even though cancelled orders are kept
 func generateOrderID() string {
-	return time.Now().Format("202402102150405") + strconv.Itoa(rand.Intn(100))
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(id)
+}
+
+// ValidateOrder checks that a new order can be placed: the contact and delivery
+// details are present, a structured address can be located and, when a
+// restaurant is given, it exists, serves every item and delivers to the address
+func ValidateOrder(ctx context.Context, newOrder models.Order) error {
+	_, err := prepareOrder(ctx, newOrder)
+	return err
 }
 
-// CreateOrder creates a new order and returns the order details
-func CreateOrder(newOrder models.Order) (models.Order, error) {
+// prepareOrder validates a new order and works out where it goes: a structured
+// delivery address is geocoded and its single line form becomes the address,
+// and orders placed against a menu are priced for the zone they are delivered
+// to. It returns the order with its address, zone, price and proof of
+// delivery filled in.
+func prepareOrder(ctx context.Context, newOrder models.Order) (models.Order, error) {
+	if strings.TrimSpace(newOrder.Email) == "" {
+		return models.Order{}, fmt.Errorf("%w: email is required", ErrInvalidOrder)
+	}
+	if newOrder.ProofRequired == "" {
+		newOrder.ProofRequired = DefaultProof
+	}
+	if !newOrder.ProofRequired.Valid() {
+		return models.Order{}, fmt.Errorf("%w: unknown proof of delivery %q", ErrInvalidOrder, newOrder.ProofRequired)
+	}
+	if newOrder.DeliveryAddress != nil {
+		address, err := locateAddress(ctx, *newOrder.DeliveryAddress)
+		if err != nil {
+			return models.Order{}, err
+		}
+		newOrder.DeliveryAddress = &address
+		newOrder.Address = address.String()
+	}
+	if strings.TrimSpace(newOrder.Address) == "" {
+		return models.Order{}, fmt.Errorf("%w: address is required", ErrInvalidOrder)
+	}
+	newOrder.Price = models.PriceBreakdown{}
+	newOrder.DeliveryZone = ""
+	if newOrder.RestaurantID == "" {
+		return newOrder, nil
+	}
+
+	restaurant, err := GetRestaurant(newOrder.RestaurantID)
+	if err != nil {
+		return models.Order{}, err
+	}
+	for _, item := range newOrder.Items {
+		if !restaurant.Serves(item) {
+			return models.Order{}, fmt.Errorf("%w: %q is not on the menu of %s", ErrInvalidOrder, item, restaurant.Name)
+		}
+	}
+	zone, _, err := deliveryTerms(restaurant, newOrder.Address, newOrder.DeliveryAddress)
+	if err != nil {
+		return models.Order{}, err
+	}
+	newOrder.Price = priceOrder(restaurant, newOrder.Items, zone.DeliveryFeeCents)
+	if err := checkMinimumOrder(restaurant, zone, newOrder.Price.SubtotalCents); err != nil {
+		return models.Order{}, err
+	}
+	newOrder.DeliveryZone = zone.Name
+
+	return newOrder, nil
+}
+
+// CreateOrder creates a new order and returns the order details. Orders placed
+// against a restaurant are due when their delivery estimate says, the others
+// DeliveryOffset after they are placed.
+func CreateOrder(ctx context.Context, newOrder models.Order) (_ models.Order, err error) {
+	ctx, span := tracer.Start(ctx, "repository.CreateOrder")
+	defer func() { tracing.End(span, err) }()
+
+	newOrder, err = prepareOrder(ctx, newOrder)
+	if err != nil {
+		return models.Order{}, err
+	}
+
+	now := time.Now().UTC()
+	newOrder.CreatedAt = now
+	newOrder.StatusHistory = nil
+	newOrder.SetStatus(models.StatusPlaced, now)
+	newOrder.AddressHistory = nil
+	// payments only come from the gateway, and couriers only through
+	// AssignCourier, which checks their slots
+	newOrder.Payment = nil
+	newOrder.Cancellation = nil
+	newOrder.CourierID = ""
+	newOrder.BatchID = ""
+	newOrder.CourierPosition = nil
+	newOrder.Estimate = nil
+	newOrder.Delivery = nil
+	newOrder.DeliveryPIN = ""
+	if newOrder.ProofRequired == models.ProofPIN {
+		newOrder.DeliveryPIN = newDeliveryPIN()
+	}
+	newOrder.DueAt = now.Add(DeliveryOffset)
+	newOrder.DeliveryTime = newOrder.DueAt.Format(time.TimeOnly)
+
 	newOrder.ID = generateOrderID()
-	newOrder.DeliveryTime = time.Now().Add(30 * time.Minute).Format("15:01:09")
 
 	store.Mutex.Lock()
+	estimateDelivery(&newOrder, now)
+	newOrder.PromisedAt = newOrder.DueAt
 	store.Orders[newOrder.ID] = newOrder
+	placedCount++
 	store.Mutex.Unlock()
 
+	span.SetAttributes(attribute.String("order.id", newOrder.ID))
 	return newOrder, nil
+}
 
+// GetOrderByID retrieves a single order by its ID
+func GetOrderByID(ctx context.Context, orderID string) (_ models.Order, err error) {
+	_, span := tracer.Start(ctx, "repository.GetOrderByID", withOrderID(orderID))
+	defer func() { tracing.End(span, err) }()
+
+	store.Mutex.Lock()
+	defer store.Mutex.Unlock()
+
+	order, exist := store.Orders[orderID]
+	if !exist {
+		return models.Order{}, ErrOrderNotFound
+	}
+
+	return order, nil
 }
 
 // GetOrderByEmail retrieves all orders for a given email
-func GetOrderByEmail(email string) ([]models.Order, error) {
+func GetOrderByEmail(ctx context.Context, email string) (_ []models.Order, err error) {
+	_, span := tracer.Start(ctx, "repository.GetOrderByEmail")
+	defer func() { tracing.End(span, err) }()
+
 	var userOrders []models.Order
 
 	store.Mutex.Lock()
@@ -53,53 +202,204 @@ func GetOrderByEmail(email string) ([]models.Order, error) {
 }
 
 // GetAllOrders retrieves all active orders
-func GetAllOrders() ([]models.Order, error) {
+func GetAllOrders(ctx context.Context) (_ []models.Order, err error) {
+	_, span := tracer.Start(ctx, "repository.GetAllOrders")
+	defer func() { tracing.End(span, err) }()
 
 	store.Mutex.Lock()
-	if len(store.Orders) == 0 {
-		store.Mutex.Unlock()
-		return nil, errors.New("no active orders found")
-	}
-
 	orders := make([]models.Order, 0, len(store.Orders))
 
 	for _, order := range store.Orders {
-		orders = append(orders, order)
+		if order.Status != models.StatusCancelled {
+			orders = append(orders, order)
+		}
 	}
 	store.Mutex.Unlock()
 
+	if len(orders) == 0 {
+		return nil, errors.New("no active orders found")
+	}
+
 	return orders, nil
 }
 
-// UpdateAddress updates the delivery address for a given order
-func UpdateAddress(email, orderID, newAddress string) (models.Order, error) {
+// AssignCourier assigns a courier from the catalog to deliver an order
+func AssignCourier(ctx context.Context, orderID, courierID string) (_ models.Order, err error) {
+	_, span := tracer.Start(ctx, "repository.AssignCourier", withOrderID(orderID))
+	defer func() { tracing.End(span, err) }()
+
+	if len(GetCouriersByIDs([]string{courierID})) == 0 {
+		return models.Order{}, ErrCourierNotFound
+	}
+
+	store.Mutex.Lock()
+	defer store.Mutex.Unlock()
+
+	order, exist := store.Orders[orderID]
+	if !exist {
+		return models.Order{}, ErrOrderNotFound
+	}
+	if order.Status == models.StatusCancelled {
+		return models.Order{}, ErrOrderCancelled
+	}
+	if order.Status == models.StatusDelivered {
+		return models.Order{}, fmt.Errorf("%w: the order is already delivered", ErrStatusChange)
+	}
+	if order.CourierID != courierID && activeOrdersOf(courierID) >= SlotCapacity {
+		return models.Order{}, ErrCourierFull
+	}
+
+	if order.CourierID != courierID {
+		// the new courier rides the order on its own
+		order.BatchID = ""
+	}
+	order.CourierID = courierID
+	estimateDelivery(&order, time.Now().UTC())
+	store.Orders[orderID] = order
+	notifyWatchers(order)
+
+	return order, nil
+}
+
+// nextStatus is the status each order status moves on to as it is fulfilled
+var nextStatus = map[models.OrderStatus]models.OrderStatus{
+	models.StatusConfirmed: models.StatusPreparing,
+	models.StatusPreparing: models.StatusOutForDelivery,
+}
+
+// AdvanceOrder moves a confirmed order on through its fulfilment. The payment
+// is captured when preparation starts.
+func AdvanceOrder(ctx context.Context, orderID string, status models.OrderStatus) (_ models.Order, err error) {
+	ctx, span := tracer.Start(ctx, "repository.AdvanceOrder", withOrderID(orderID))
+	defer func() { tracing.End(span, err) }()
+
 	store.Mutex.Lock()
 	defer store.Mutex.Unlock()
 
 	order, exist := store.Orders[orderID]
 	if !exist {
-		return models.Order{}, errors.New("order not found")
+		return models.Order{}, ErrOrderNotFound
+	}
+	if order.Status == models.StatusCancelled {
+		return models.Order{}, ErrOrderCancelled
+	}
+	if atGateway[orderID] {
+		return models.Order{}, ErrPaymentInProgress
 	}
-	if order.Email != email {
-		return models.Order{}, errors.New("email does not match")
+	if nextStatus[order.Status] != status {
+		return models.Order{}, fmt.Errorf("%w: %s order cannot become %s", ErrStatusChange, order.Status, status)
 	}
 
-	order.Address = newAddress
+	if status == models.StatusPreparing && order.Payment != nil {
+		intent := *order.Payment
+		order, err = callGateway(orderID, func() error {
+			if err := payments.Verify(ctx, PaymentGateway, &intent, orderID); err != nil {
+				return err
+			}
+			return payments.Capture(ctx, PaymentGateway, &intent)
+		})
+		if err != nil {
+			return models.Order{}, err
+		}
+		order.Payment = &intent
+	}
+	now := time.Now().UTC()
+	order.SetStatus(status, now)
+	estimateDelivery(&order, now)
 	store.Orders[orderID] = order
+	notifyWatchers(order)
 
 	return order, nil
 }
 
-// CancelOrder cancels an order by order ID and email
-func CancelOrder(email, orderID string) (string, error) {
+// CancelOrder cancels an order for whoever asks, if the cancellation policy
+// allows it, and voids or refunds its payment. Cancelled orders are kept with
+// their status so they remain visible to exports.
+func CancelOrder(ctx context.Context, orderID string, request models.OrderCancellation) (_ string, err error) {
+	ctx, span := tracer.Start(ctx, "repository.CancelOrder", withOrderID(orderID))
+	defer func() { tracing.End(span, err) }()
+
+	if request.Initiator == "" {
+		request.Initiator = models.InitiatorCustomer
+	}
+	if !request.Initiator.Valid() {
+		return "", fmt.Errorf("%w: unknown cancellation initiator %q", ErrInvalidOrder, request.Initiator)
+	}
+	reason := strings.TrimSpace(request.Reason)
+	if reason == "" {
+		return "", fmt.Errorf("%w: a cancellation reason is required", ErrInvalidOrder)
+	}
+
 	store.Mutex.Lock()
+	defer store.Mutex.Unlock()
+
 	order, exist := store.Orders[orderID]
-	if exist && order.Email == email {
-		delete(store.Orders, orderID)
-		store.Mutex.Unlock()
-		return fmt.Sprintf("%s Order Cancelled Successfully", orderID), nil
-	} else {
-		store.Mutex.Unlock()
-		return "", errors.New("order not found")
+	if !exist || !requestedBy(order, request) {
+		return "", ErrOrderNotFound
+	}
+	if atGateway[orderID] {
+		return "", ErrPaymentInProgress
+	}
+	now := time.Now().UTC()
+	if err := cancellable(order, request.Initiator, now); err != nil {
+		return "", err
+	}
+	if _, _, settles := settlementOf(order); settles {
+		var settled *models.PaymentIntent
+		settling := order
+		order, err = callGateway(orderID, func() (err error) {
+			settled, err = settlePayment(ctx, settling)
+			return err
+		})
+		if err != nil {
+			return "", err
+		}
+		if settled != nil {
+			order.Payment = settled
+		}
+		// the order may have moved on while the store was unlocked; the money
+		// has moved either way, so the payment is kept when it is refused now
+		if err := cancellable(order, request.Initiator, now); err != nil {
+			store.Orders[orderID] = order
+			notifyWatchers(order)
+			return "", err
+		}
+	}
+
+	order.Cancellation = &models.Cancellation{Initiator: request.Initiator, Reason: reason, At: now}
+	order.SetStatus(models.StatusCancelled, now)
+	store.Orders[orderID] = order
+	cancelledCount++
+	notifyWatchers(order)
+
+	return fmt.Sprintf("%s Order Cancelled Successfully", orderID), nil
+}
+
+// activeOrdersOf counts the orders a courier is carrying, until they are
+// delivered or cancelled. It must be called with the store locked.
+func activeOrdersOf(courierID string) int {
+	count := 0
+	for _, order := range store.Orders {
+		if order.CourierID == courierID && !order.Status.Final() {
+			count++
+		}
+	}
+	return count
+}
+
+// withOrderID tags a repository span with the order it works on
+func withOrderID(orderID string) trace.SpanStartOption {
+	return trace.WithAttributes(attribute.String("order.id", orderID))
+}
+
+// priceOrder prices the items against the restaurant menu and adds the delivery fee
+func priceOrder(restaurant models.Restaurant, items []string, deliveryFeeCents int) models.PriceBreakdown {
+	var price models.PriceBreakdown
+	for _, item := range items {
+		cents, _ := restaurant.Price(item)
+		price.SubtotalCents += cents
 	}
+	price.DeliveryFeeCents = deliveryFeeCents
+	price.TotalCents = price.SubtotalCents + price.DeliveryFeeCents
+	return price
 }
@@ -1,10 +1,15 @@
 package repository
 
 import (
+	"context"
+	"fmt"
+	"path/filepath"
 	"testing"
+	"time"
 	"weservefood/models"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestCreateOrder(t *testing.T) {
@@ -13,13 +18,23 @@ func TestCreateOrder(t *testing.T) {
 		Address: "123 Test St",
 	}
 
-	createdOrder, err := CreateOrder(newOrder)
+	createdOrder, err := CreateOrder(context.Background(), newOrder)
 	assert.NoError(t, err)
 	assert.NotEmpty(t, createdOrder.ID)
 	assert.Equal(t, newOrder.Email, createdOrder.Email)
 	assert.Equal(t, newOrder.Address, createdOrder.Address)
 }
 
+func TestGenerateOrderID(t *testing.T) {
+	seen := make(map[string]bool)
+	for range 1000 {
+		id := generateOrderID()
+		assert.Regexp(t, `^[0-9a-f]{16}$`, id)
+		assert.False(t, seen[id], "duplicate order ID %s", id)
+		seen[id] = true
+	}
+}
+
 func TestGetOrderByEmail(t *testing.T) {
 	email := "test@example.com"
 	newOrder := models.Order{
@@ -27,10 +42,10 @@ func TestGetOrderByEmail(t *testing.T) {
 		Address: "123 Test St",
 	}
 
-	_, err := CreateOrder(newOrder)
+	_, err := CreateOrder(context.Background(), newOrder)
 	assert.NoError(t, err)
 
-	orders, err := GetOrderByEmail(email)
+	orders, err := GetOrderByEmail(context.Background(), email)
 	assert.NoError(t, err)
 	assert.NotEmpty(t, orders)
 	assert.Equal(t, email, orders[0].Email)
@@ -38,7 +53,7 @@ func TestGetOrderByEmail(t *testing.T) {
 
 func TestGetOrderByEmailNoOrders(t *testing.T) {
 	email := "noorders@example.com"
-	orders, err := GetOrderByEmail(email)
+	orders, err := GetOrderByEmail(context.Background(), email)
 	assert.Error(t, err)
 	assert.Nil(t, orders)
 }
@@ -49,17 +64,20 @@ func TestGetAllOrders(t *testing.T) {
 		Address: "123 Test St",
 	}
 
-	_, err := CreateOrder(newOrder)
+	_, err := CreateOrder(context.Background(), newOrder)
 	assert.NoError(t, err)
 
-	orders, err := GetAllOrders()
+	orders, err := GetAllOrders(context.Background())
 	assert.NoError(t, err)
 	assert.NotEmpty(t, orders)
 }
 
 func TestGetAllOrdersNoOrders(t *testing.T) {
+	previous := store.Orders
 	store.Orders = make(map[string]models.Order) // Clear the store
-	orders, err := GetAllOrders()
+	// order IDs repeat once forgotten, and payments are keyed by them
+	t.Cleanup(func() { store.Orders = previous })
+	orders, err := GetAllOrders(context.Background())
 	assert.Error(t, err)
 	assert.Nil(t, orders)
 }
@@ -71,11 +89,11 @@ func TestUpdateAddress(t *testing.T) {
 		Address: "123 Test St",
 	}
 
-	createdOrder, err := CreateOrder(newOrder)
+	createdOrder, err := CreateOrder(context.Background(), newOrder)
 	assert.NoError(t, err)
 
 	newAddress := "456 New St"
-	updatedOrder, err := UpdateAddress(email, createdOrder.ID, newAddress)
+	updatedOrder, err := UpdateAddress(context.Background(), createdOrder.ID, models.OrderPatch{Email: email, Address: newAddress})
 	assert.NoError(t, err)
 	assert.Equal(t, newAddress, updatedOrder.Address)
 }
@@ -83,7 +101,7 @@ func TestUpdateAddress(t *testing.T) {
 func TestUpdateAddressOrderNotFound(t *testing.T) {
 	email := "test@example.com"
 	newAddress := "456 New St"
-	_, err := UpdateAddress(email, "nonexistentID", newAddress)
+	_, err := UpdateAddress(context.Background(), "nonexistentID", models.OrderPatch{Email: email, Address: newAddress})
 	assert.Error(t, err)
 }
 
@@ -94,11 +112,11 @@ func TestUpdateAddressEmailMismatch(t *testing.T) {
 		Address: "123 Test St",
 	}
 
-	createdOrder, err := CreateOrder(newOrder)
+	createdOrder, err := CreateOrder(context.Background(), newOrder)
 	assert.NoError(t, err)
 
 	newAddress := "456 New St"
-	_, err = UpdateAddress("wrong@example.com", createdOrder.ID, newAddress)
+	_, err = UpdateAddress(context.Background(), createdOrder.ID, models.OrderPatch{Email: "wrong@example.com", Address: newAddress})
 	assert.Error(t, err)
 }
 
@@ -109,16 +127,341 @@ func TestCancelOrder(t *testing.T) {
 		Address: "123 Test St",
 	}
 
-	createdOrder, err := CreateOrder(newOrder)
+	createdOrder, err := CreateOrder(context.Background(), newOrder)
 	assert.NoError(t, err)
 
-	msg, err := CancelOrder(email, createdOrder.ID)
+	msg, err := CancelOrder(context.Background(), createdOrder.ID, models.OrderCancellation{Email: email, Reason: "changed my mind"})
 	assert.NoError(t, err)
 	assert.Contains(t, msg, "Order Cancelled Successfully")
 }
 
+func TestCancelOrderKeepsOrder(t *testing.T) {
+	email := "test@example.com"
+	createdOrder, err := CreateOrder(context.Background(), models.Order{Email: email, Address: "123 Test St"})
+	assert.NoError(t, err)
+
+	_, err = CancelOrder(context.Background(), createdOrder.ID, models.OrderCancellation{Email: email, Reason: "changed my mind"})
+	assert.NoError(t, err)
+
+	order, err := GetOrderByID(context.Background(), createdOrder.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.StatusCancelled, order.Status)
+	_, cancelled := order.StatusAt(models.StatusCancelled)
+	assert.True(t, cancelled)
+
+	_, err = CancelOrder(context.Background(), createdOrder.ID, models.OrderCancellation{Email: email, Reason: "changed my mind"})
+	assert.ErrorIs(t, err, ErrOrderCancelled)
+	_, err = UpdateAddress(context.Background(), createdOrder.ID, models.OrderPatch{Email: email, Address: "456 New St"})
+	assert.ErrorIs(t, err, ErrOrderCancelled)
+}
+
 func TestCancelOrderNotFound(t *testing.T) {
 	email := "test@example.com"
-	_, err := CancelOrder(email, "nonexistentID")
+	_, err := CancelOrder(context.Background(), "nonexistentID", models.OrderCancellation{Email: email, Reason: "changed my mind"})
 	assert.Error(t, err)
 }
+
+func TestGetOrderByID(t *testing.T) {
+	newOrder := models.Order{
+		Email:   "test@example.com",
+		Address: "123 Test St",
+	}
+
+	createdOrder, err := CreateOrder(context.Background(), newOrder)
+	assert.NoError(t, err)
+
+	order, err := GetOrderByID(context.Background(), createdOrder.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, createdOrder, order)
+}
+
+func TestCreateOrderIgnoresCourier(t *testing.T) {
+	order, err := CreateOrder(context.Background(), models.Order{Email: "test@example.com", Address: "123 Test St", CourierID: "nonexistent-courier"})
+	assert.NoError(t, err)
+	assert.Empty(t, order.CourierID)
+}
+
+func TestGetOrderByIDNotFound(t *testing.T) {
+	_, err := GetOrderByID(context.Background(), "nonexistentID")
+	assert.Error(t, err)
+}
+
+func TestWatchOrder(t *testing.T) {
+	email := "test@example.com"
+	createdOrder, err := CreateOrder(context.Background(), models.Order{Email: email, Address: "123 Test St"})
+	assert.NoError(t, err)
+
+	updates, stop, err := WatchOrder(createdOrder.ID)
+	assert.NoError(t, err)
+	defer stop()
+
+	assert.Equal(t, createdOrder, <-updates)
+
+	_, err = UpdateAddress(context.Background(), createdOrder.ID, models.OrderPatch{Email: email, Address: "456 New St"})
+	assert.NoError(t, err)
+	assert.Equal(t, "456 New St", (<-updates).Address)
+
+	_, err = CancelOrder(context.Background(), createdOrder.ID, models.OrderCancellation{Email: email, Reason: "changed my mind"})
+	assert.NoError(t, err)
+	assert.Equal(t, models.StatusCancelled, (<-updates).Status)
+
+	_, open := <-updates
+	assert.False(t, open)
+}
+
+func TestWatchCancelledOrder(t *testing.T) {
+	email := "test@example.com"
+	createdOrder, err := CreateOrder(context.Background(), models.Order{Email: email, Address: "123 Test St"})
+	assert.NoError(t, err)
+	_, err = CancelOrder(context.Background(), createdOrder.ID, models.OrderCancellation{Email: email, Reason: "changed my mind"})
+	assert.NoError(t, err)
+
+	updates, stop, err := WatchOrder(createdOrder.ID)
+	assert.NoError(t, err)
+	defer stop()
+
+	assert.Equal(t, models.StatusCancelled, (<-updates).Status)
+	_, open := <-updates
+	assert.False(t, open)
+}
+
+func TestSlowWatcherReceivesFinalStatus(t *testing.T) {
+	email := "slow@example.com"
+	createdOrder, err := CreateOrder(context.Background(), models.Order{Email: email, Address: "123 Test St"})
+	assert.NoError(t, err)
+
+	updates, stop, err := WatchOrder(createdOrder.ID)
+	assert.NoError(t, err)
+	defer stop()
+
+	// fill the buffer without reading any of it
+	for i := 0; i < watchBuffer; i++ {
+		_, err = UpdateAddress(context.Background(), createdOrder.ID, models.OrderPatch{Email: email, Address: fmt.Sprintf("%d New St", i)})
+		assert.NoError(t, err)
+	}
+	_, err = CancelOrder(context.Background(), createdOrder.ID, models.OrderCancellation{Email: email, Reason: "changed my mind"})
+	assert.NoError(t, err)
+
+	var last models.Order
+	for order := range updates {
+		last = order
+	}
+	assert.Equal(t, models.StatusCancelled, last.Status)
+}
+
+func TestStopWatchingForgetsOrder(t *testing.T) {
+	createdOrder, err := CreateOrder(context.Background(), models.Order{Email: "stop@example.com", Address: "123 Test St"})
+	assert.NoError(t, err)
+
+	_, stop, err := WatchOrder(createdOrder.ID)
+	assert.NoError(t, err)
+	stop()
+
+	watchers.Lock()
+	_, watched := watchers.byOrder[createdOrder.ID]
+	watchers.Unlock()
+	assert.False(t, watched)
+}
+
+func TestWatchOrderNotFound(t *testing.T) {
+	_, _, err := WatchOrder("nonexistentID")
+	assert.ErrorIs(t, err, ErrOrderNotFound)
+}
+
+func TestValidateOrder(t *testing.T) {
+	AddRestaurant(models.Restaurant{ID: "r-validate", Name: "Validate Bistro", Menu: []models.MenuItem{{Name: "Soup", PriceCents: 500}}})
+
+	assert.NoError(t, ValidateOrder(context.Background(), models.Order{Email: "test@example.com", Address: "123 Test St", RestaurantID: "r-validate", Items: []string{"Soup"}}))
+	assert.ErrorIs(t, ValidateOrder(context.Background(), models.Order{Address: "123 Test St"}), ErrInvalidOrder)
+	assert.ErrorIs(t, ValidateOrder(context.Background(), models.Order{Email: "test@example.com", Address: " "}), ErrInvalidOrder)
+	assert.ErrorIs(t, ValidateOrder(context.Background(), models.Order{Email: "test@example.com", Address: "123 Test St", RestaurantID: "r-validate", Items: []string{"Steak"}}), ErrInvalidOrder)
+	assert.ErrorIs(t, ValidateOrder(context.Background(), models.Order{Email: "test@example.com", Address: "123 Test St", RestaurantID: "r-missing"}), ErrRestaurantNotFound)
+}
+
+func TestCreateOrderPrice(t *testing.T) {
+	AddRestaurant(models.Restaurant{ID: "r-price", Name: "Price Bistro", Menu: []models.MenuItem{{Name: "Soup", PriceCents: 500}, {Name: "Bread", PriceCents: 150}}})
+
+	order, err := CreateOrder(context.Background(), models.Order{Email: "test@example.com", Address: "123 Test St", RestaurantID: "r-price", Items: []string{"Soup", "Bread", "Soup"}})
+	assert.NoError(t, err)
+	assert.Equal(t, models.PriceBreakdown{SubtotalCents: 1150, DeliveryFeeCents: DeliveryFeeCents, TotalCents: 1150 + DeliveryFeeCents}, order.Price)
+
+	placedAt, placed := order.StatusAt(models.StatusPlaced)
+	assert.True(t, placed)
+	assert.Equal(t, order.CreatedAt, placedAt)
+}
+
+func TestScanOrders(t *testing.T) {
+	AddRestaurant(models.Restaurant{ID: "r-scan", Name: "Scan Diner", Menu: []models.MenuItem{{Name: "Soup", PriceCents: 500}}})
+	from := time.Now().UTC()
+
+	first, err := CreateOrder(context.Background(), models.Order{Email: "scan@example.com", Address: "1 Scan St", RestaurantID: "r-scan"})
+	assert.NoError(t, err)
+	second, err := CreateOrder(context.Background(), models.Order{Email: "scan@example.com", Address: "2 Scan St", RestaurantID: "r-scan"})
+	assert.NoError(t, err)
+	_, err = CancelOrder(context.Background(), second.ID, models.OrderCancellation{Email: "scan@example.com", Reason: "changed my mind"})
+	assert.NoError(t, err)
+
+	var ids []string
+	for order := range ScanOrders(context.Background(), models.OrderFilter{From: from, RestaurantID: "r-scan"}) {
+		ids = append(ids, order.ID)
+	}
+	assert.Equal(t, []string{first.ID, second.ID}, ids)
+
+	ids = nil
+	for order := range ScanOrders(context.Background(), models.OrderFilter{From: from, Status: models.StatusCancelled, RestaurantID: "r-scan"}) {
+		ids = append(ids, order.ID)
+	}
+	assert.Equal(t, []string{second.ID}, ids)
+
+	for order := range ScanOrders(context.Background(), models.OrderFilter{To: from, RestaurantID: "r-scan"}) {
+		assert.NotEqual(t, first.ID, order.ID)
+	}
+}
+
+func TestGetOrderStats(t *testing.T) {
+	AddCourier(models.Courier{ID: "c-stats", Name: "Stan"})
+	before := GetOrderStats()
+
+	order, err := CreateOrder(context.Background(), models.Order{Email: "stats@example.com", Address: "1 Stats St"})
+	assert.NoError(t, err)
+	_, err = AssignCourier(context.Background(), order.ID, "c-stats")
+	assert.NoError(t, err)
+	t.Cleanup(func() {
+		CancelOrder(context.Background(), order.ID, models.OrderCancellation{Email: "stats@example.com", Reason: "changed my mind"})
+	})
+	cancelled, err := CreateOrder(context.Background(), models.Order{Email: "stats@example.com", Address: "2 Stats St"})
+	assert.NoError(t, err)
+	_, err = CancelOrder(context.Background(), cancelled.ID, models.OrderCancellation{Email: "stats@example.com", Reason: "changed my mind"})
+	assert.NoError(t, err)
+
+	stats := GetOrderStats()
+	assert.Equal(t, before.Placed+2, stats.Placed)
+	assert.Equal(t, before.Cancelled+1, stats.Cancelled)
+	assert.Equal(t, before.Active[models.StatusPlaced]+1, stats.Active[models.StatusPlaced])
+	assert.Equal(t, before.StoreSize+2, stats.StoreSize)
+	assert.Positive(t, stats.BusySlots)
+	assert.LessOrEqual(t, stats.BusySlots, stats.Slots)
+}
+
+func TestWatchersClosedOnShutdown(t *testing.T) {
+	order, err := CreateOrder(context.Background(), models.Order{Email: "shutdown@example.com", Address: "1 Main St"})
+	assert.NoError(t, err)
+	updates, stop, err := WatchOrder(order.ID)
+	assert.NoError(t, err)
+	defer stop()
+	<-updates
+
+	CloseWatchers()
+
+	_, open := <-updates
+	assert.False(t, open)
+}
+
+func TestOpenFileAndFlush(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "orders.json")
+	assert.NoError(t, OpenFile(path))
+	t.Cleanup(func() { persistence.path = "" })
+
+	order, err := CreateOrder(context.Background(), models.Order{Email: "persist@example.com", Address: "1 Main St"})
+	assert.NoError(t, err)
+	_, err = CancelOrder(context.Background(), order.ID, models.OrderCancellation{Email: "persist@example.com", Reason: "changed my mind"})
+	assert.NoError(t, err)
+	assert.NoError(t, Flush())
+
+	before := GetOrderStats()
+	store.Mutex.Lock()
+	delete(store.Orders, order.ID)
+	placedCount--
+	cancelledCount--
+	store.Mutex.Unlock()
+
+	assert.NoError(t, OpenFile(path))
+	replayed, err := GetOrderByID(context.Background(), order.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.StatusCancelled, replayed.Status)
+	assert.True(t, order.CreatedAt.Equal(replayed.CreatedAt))
+	after := GetOrderStats()
+	assert.Equal(t, before.Placed, after.Placed)
+	assert.Equal(t, before.Cancelled, after.Cancelled)
+
+	matches, _ := filepath.Glob(path + ".*")
+	assert.Empty(t, matches, "temporary files left behind")
+}
+
+func TestFlushKeepsDeliveryPIN(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "orders.json")
+	assert.NoError(t, OpenFile(path))
+	t.Cleanup(func() { persistence.path = "" })
+
+	order, err := CreateOrder(context.Background(), models.Order{Email: "persist-pin@example.com", Address: "1 Main St", ProofRequired: models.ProofPIN})
+	require.NoError(t, err)
+	require.NotEmpty(t, order.DeliveryPIN)
+	store.Mutex.Lock()
+	pinAttempts[order.ID] = 3
+	store.Mutex.Unlock()
+	assert.NoError(t, Flush())
+
+	store.Mutex.Lock()
+	replaced := store.Orders[order.ID]
+	replaced.DeliveryPIN = ""
+	store.Orders[order.ID] = replaced
+	delete(pinAttempts, order.ID)
+	store.Mutex.Unlock()
+
+	assert.NoError(t, OpenFile(path))
+	replayed, err := GetOrderByID(context.Background(), order.ID)
+	require.NoError(t, err)
+	assert.Equal(t, order.DeliveryPIN, replayed.DeliveryPIN)
+	store.Mutex.Lock()
+	assert.Equal(t, 3, pinAttempts[order.ID], "wrong PINs still count after a restart")
+	store.Mutex.Unlock()
+}
+
+func TestFlushKeepsBatches(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "orders.json")
+	assert.NoError(t, OpenFile(path))
+	t.Cleanup(func() { persistence.path = "" })
+
+	order, err := CreateOrder(context.Background(), models.Order{Email: "persist-batch@example.com", Address: "1 Main St"})
+	require.NoError(t, err)
+	now := time.Now().UTC().Round(time.Second)
+	store.Mutex.Lock()
+	batchCount++
+	count := batchCount
+	batch := models.Batch{
+		ID:           fmt.Sprintf("b-%06d", count),
+		RestaurantID: "r-persist-batch",
+		CourierID:    "c-persist-batch",
+		DepartAt:     now,
+		Stops:        []models.BatchStop{{OrderID: order.ID, ArriveAt: now.Add(10 * time.Minute), DueAt: now.Add(20 * time.Minute), OnTime: true}},
+		CreatedAt:    now,
+	}
+	batches[batch.ID] = batch
+	replaced := store.Orders[order.ID]
+	replaced.BatchID = batch.ID
+	store.Orders[order.ID] = replaced
+	store.Mutex.Unlock()
+	assert.NoError(t, Flush())
+
+	// a restart starts without batches
+	store.Mutex.Lock()
+	delete(batches, batch.ID)
+	batchCount = 0
+	store.Mutex.Unlock()
+
+	assert.NoError(t, OpenFile(path))
+	replayed, err := GetOrderByID(context.Background(), order.ID)
+	require.NoError(t, err)
+	restored, err := GetBatch(context.Background(), replayed.BatchID)
+	require.NoError(t, err)
+	assert.Equal(t, batch, restored)
+	store.Mutex.Lock()
+	assert.Equal(t, count, batchCount, "new batches do not reuse restored IDs")
+	store.Mutex.Unlock()
+}
+
+func TestOpenFileMissing(t *testing.T) {
+	assert.NoError(t, OpenFile(filepath.Join(t.TempDir(), "orders.json")))
+	t.Cleanup(func() { persistence.path = "" })
+	assert.NoError(t, Flush())
+}
@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"iter"
+	"sort"
+	"weservefood/models"
+	"weservefood/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ScanOrders yields the orders matching the filter, oldest first. Only the
+// matching IDs are collected up front; each order is read when it is yielded,
+// so a long export neither copies the whole store nor holds its lock.
+// Orders that stop matching before they are reached are skipped, and the scan
+// stops early once ctx is done.
+func ScanOrders(ctx context.Context, filter models.OrderFilter) iter.Seq[models.Order] {
+	return func(yield func(models.Order) bool) {
+		_, span := tracer.Start(ctx, "repository.ScanOrders")
+		var err error
+		defer func() { tracing.End(span, err) }()
+
+		type entry struct {
+			id        string
+			createdAt int64
+		}
+
+		store.Mutex.Lock()
+		var entries []entry
+		for id, order := range store.Orders {
+			if filter.Matches(order) {
+				entries = append(entries, entry{id: id, createdAt: order.CreatedAt.UnixNano()})
+			}
+		}
+		store.Mutex.Unlock()
+
+		sort.Slice(entries, func(i, j int) bool {
+			if entries[i].createdAt != entries[j].createdAt {
+				return entries[i].createdAt < entries[j].createdAt
+			}
+			return entries[i].id < entries[j].id
+		})
+
+		span.SetAttributes(attribute.Int("orders.matched", len(entries)))
+		for _, entry := range entries {
+			if err = ctx.Err(); err != nil {
+				return
+			}
+			store.Mutex.Lock()
+			order, exist := store.Orders[entry.id]
+			store.Mutex.Unlock()
+
+			if !exist || !filter.Matches(order) {
+				continue
+			}
+			if !yield(order) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package repository
+
+import "weservefood/models"
+
+// GetOrderStats summarises the orders and courier load for monitoring
+func GetOrderStats() models.OrderStats {
+	stats := models.OrderStats{Active: make(map[models.OrderStatus]int)}
+	carrying := make(map[string]int)
+
+	store.Mutex.Lock()
+	stats.Placed = placedCount
+	stats.Delivered = deliveredCount
+	stats.Cancelled = cancelledCount
+	stats.StoreSize = len(store.Orders)
+	for _, order := range store.Orders {
+		if order.Status.Final() {
+			continue
+		}
+		stats.Active[order.Status]++
+		if order.CourierID != "" {
+			carrying[order.CourierID]++
+		}
+	}
+	store.Mutex.Unlock()
+
+	catalog.Mutex.RLock()
+	stats.Slots = len(catalog.Couriers) * SlotCapacity
+	for courierID, count := range carrying {
+		if _, exist := catalog.Couriers[courierID]; exist {
+			stats.BusySlots += min(count, SlotCapacity)
+		}
+	}
+	catalog.Mutex.RUnlock()
+
+	return stats
+}
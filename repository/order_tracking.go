@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"time"
+	"weservefood/geo"
+	"weservefood/models"
+	"weservefood/tracing"
+)
+
+var ErrInvalidPing = errors.New("invalid location ping")
+
+// TrailLength is how many pings are kept for each delivery. Longer trails are
+// thinned so they still cover the whole ride.
+var TrailLength = 500
+
+// clockSkew is how far ahead of the server a courier's clock may run
+const clockSkew = time.Minute
+
+// trails holds the pings recorded for each order while it was out for
+// delivery, oldest first; it is guarded by the store mutex
+var trails = make(map[string][]models.LocationPing)
+
+// RecordPings adds a courier's pings to the trail of every order the courier
+// has out for delivery and moves the courier's position on those orders to
+// the latest one. Pings may arrive late and out of order; those older than
+// the last one recorded for an order are dropped. It returns the IDs of the
+// orders the pings were recorded against.
+func RecordPings(ctx context.Context, courierID string, pings []models.LocationPing) (_ []string, err error) {
+	_, span := tracer.Start(ctx, "repository.RecordPings")
+	defer func() { tracing.End(span, err) }()
+
+	if len(GetCouriersByIDs([]string{courierID})) == 0 {
+		return nil, ErrCourierNotFound
+	}
+	if len(pings) == 0 {
+		return nil, fmt.Errorf("%w: no pings", ErrInvalidPing)
+	}
+	now := time.Now().UTC()
+	for i, ping := range pings {
+		switch {
+		case !ping.Location.Valid():
+			return nil, fmt.Errorf("%w: ping %d is not a valid location", ErrInvalidPing, i)
+		case ping.RecordedAt.IsZero():
+			return nil, fmt.Errorf("%w: ping %d has no recorded_at", ErrInvalidPing, i)
+		case ping.RecordedAt.After(now.Add(clockSkew)):
+			return nil, fmt.Errorf("%w: ping %d is recorded in the future", ErrInvalidPing, i)
+		case ping.AccuracyMetres < 0:
+			return nil, fmt.Errorf("%w: ping %d has a negative accuracy", ErrInvalidPing, i)
+		}
+	}
+	pings = slices.Clone(pings)
+	sort.SliceStable(pings, func(i, j int) bool { return pings[i].RecordedAt.Before(pings[j].RecordedAt) })
+
+	store.Mutex.Lock()
+	defer store.Mutex.Unlock()
+
+	orderIDs := []string{}
+	for _, order := range store.Orders {
+		if order.CourierID != courierID || order.Status != models.StatusOutForDelivery {
+			continue
+		}
+		trail, added := trails[order.ID], false
+		for _, ping := range pings {
+			if len(trail) > 0 && !ping.RecordedAt.After(trail[len(trail)-1].RecordedAt) {
+				continue
+			}
+			ping.RecordedAt = ping.RecordedAt.UTC()
+			trail, added = append(trail, ping), true
+		}
+		if !added {
+			continue
+		}
+		trails[order.ID] = thinTrail(trail, TrailLength)
+
+		latest := trail[len(trail)-1]
+		order.CourierPosition = &latest
+		store.Orders[order.ID] = order
+		notifyWatchers(order)
+		orderIDs = append(orderIDs, order.ID)
+	}
+	sort.Strings(orderIDs)
+	return orderIDs, nil
+}
+
+// GetTrail returns the route the courier took with an order, to replay it
+func GetTrail(ctx context.Context, orderID string) (_ models.DeliveryTrail, err error) {
+	_, span := tracer.Start(ctx, "repository.GetTrail", withOrderID(orderID))
+	defer func() { tracing.End(span, err) }()
+
+	store.Mutex.Lock()
+	defer store.Mutex.Unlock()
+
+	order, exist := store.Orders[orderID]
+	if !exist {
+		return models.DeliveryTrail{}, ErrOrderNotFound
+	}
+
+	trail := models.DeliveryTrail{
+		OrderID:   orderID,
+		CourierID: order.CourierID,
+		Pings:     append([]models.LocationPing{}, trails[orderID]...),
+	}
+	var metres float64
+	for i := 1; i < len(trail.Pings); i++ {
+		metres += geo.Distance(trail.Pings[i-1].Location, trail.Pings[i].Location)
+	}
+	trail.DistanceMetres = int(math.Round(metres))
+	return trail, nil
+}
+
+// thinTrail drops every other ping until the trail fits the limit, always
+// keeping the first and the latest one, so a long ride is kept end to end at
+// a coarser resolution rather than losing its start
+func thinTrail(trail []models.LocationPing, limit int) []models.LocationPing {
+	for len(trail) > max(limit, 2) {
+		thinned := make([]models.LocationPing, 0, len(trail)/2+1)
+		for i := 0; i < len(trail)-1; i += 2 {
+			thinned = append(thinned, trail[i])
+		}
+		trail = append(thinned, trail[len(trail)-1])
+	}
+	return trail
+}
@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+	"weservefood/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordPings(t *testing.T) {
+	previous := SlotCapacity
+	SlotCapacity = 2
+	t.Cleanup(func() { SlotCapacity = previous })
+	ctx := context.Background()
+	AddCourier(models.Courier{ID: "c-track", Name: "Trudy", Available: true})
+	riding := confirmPricedOrder(t, "track-riding@example.com")
+	waiting := confirmPricedOrder(t, "track-waiting@example.com")
+	for _, order := range []models.Order{riding, waiting} {
+		_, err := AssignCourier(ctx, order.ID, "c-track")
+		require.NoError(t, err)
+		_, err = AdvanceOrder(ctx, order.ID, models.StatusPreparing)
+		require.NoError(t, err)
+	}
+	_, err := AdvanceOrder(ctx, riding.ID, models.StatusOutForDelivery)
+	require.NoError(t, err)
+
+	start := time.Now().UTC().Add(-3 * time.Minute).Truncate(time.Second)
+	ping := func(minutes int, lat float64) models.LocationPing {
+		return models.LocationPing{Location: models.LatLng{Lat: lat, Lng: 13.4}, RecordedAt: start.Add(time.Duration(minutes) * time.Minute)}
+	}
+	// buffered pings may arrive out of order
+	orderIDs, err := RecordPings(ctx, "c-track", []models.LocationPing{ping(2, 52.52), ping(0, 52.50), ping(1, 52.51)})
+	require.NoError(t, err)
+	assert.Equal(t, []string{riding.ID}, orderIDs, "only deliveries under way are tracked")
+
+	tracked, err := GetOrderByID(ctx, riding.ID)
+	require.NoError(t, err)
+	require.NotNil(t, tracked.CourierPosition)
+	assert.Equal(t, ping(2, 52.52), *tracked.CourierPosition)
+	untracked, err := GetOrderByID(ctx, waiting.ID)
+	require.NoError(t, err)
+	assert.Nil(t, untracked.CourierPosition)
+
+	// a ping older than the trail is too late to replay
+	orderIDs, err = RecordPings(ctx, "c-track", []models.LocationPing{ping(1, 52.60)})
+	require.NoError(t, err)
+	assert.Empty(t, orderIDs)
+
+	trail, err := GetTrail(ctx, riding.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "c-track", trail.CourierID)
+	assert.Equal(t, []models.LocationPing{ping(0, 52.50), ping(1, 52.51), ping(2, 52.52)}, trail.Pings)
+	assert.InDelta(t, 2224, trail.DistanceMetres, 5)
+
+	_, err = RecordPings(ctx, "c-track", []models.LocationPing{{Location: models.LatLng{Lat: 95}, RecordedAt: start}})
+	assert.ErrorIs(t, err, ErrInvalidPing)
+	_, err = RecordPings(ctx, "c-track", []models.LocationPing{ping(60, 52.5)})
+	assert.ErrorIs(t, err, ErrInvalidPing, "pings from the future are refused")
+	_, err = RecordPings(ctx, "c-track", nil)
+	assert.ErrorIs(t, err, ErrInvalidPing)
+	_, err = RecordPings(ctx, "c-missing", []models.LocationPing{ping(2, 52.5)})
+	assert.ErrorIs(t, err, ErrCourierNotFound)
+	_, err = GetTrail(ctx, "nonexistentID")
+	assert.ErrorIs(t, err, ErrOrderNotFound)
+}
+
+func TestThinTrail(t *testing.T) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	var trail []models.LocationPing
+	for i := range 10 {
+		trail = append(trail, models.LocationPing{RecordedAt: start.Add(time.Duration(i) * time.Second)})
+	}
+
+	thinned := thinTrail(trail, 4)
+	assert.LessOrEqual(t, len(thinned), 4)
+	assert.Equal(t, trail[0], thinned[0], "the ride keeps its start")
+	assert.Equal(t, trail[9], thinned[len(thinned)-1], "and its latest position")
+	assert.Equal(t, trail, thinTrail(trail, 10))
+}
@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"sync"
+	"weservefood/models"
+)
+
+// watchBuffer is how many pending updates a watcher may fall behind before its
+// oldest ones are dropped
+const watchBuffer = 16
+
+var watchers = struct {
+	sync.Mutex
+	byOrder map[string]map[chan models.Order]struct{}
+}{
+	byOrder: make(map[string]map[chan models.Order]struct{}),
+}
+
+// WatchOrder subscribes to changes of an order. The current state is sent first,
+// followed by every update; the channel is closed once the order is delivered
+// or cancelled.
+// The returned function stops the subscription.
+func WatchOrder(orderID string) (<-chan models.Order, func(), error) {
+	store.Mutex.Lock()
+	defer store.Mutex.Unlock()
+
+	order, exist := store.Orders[orderID]
+	if !exist {
+		return nil, nil, ErrOrderNotFound
+	}
+
+	updates := make(chan models.Order, watchBuffer)
+	updates <- order
+	if order.Status.Final() {
+		close(updates)
+		return updates, func() {}, nil
+	}
+
+	watchers.Lock()
+	if watchers.byOrder[orderID] == nil {
+		watchers.byOrder[orderID] = make(map[chan models.Order]struct{})
+	}
+	watchers.byOrder[orderID][updates] = struct{}{}
+	watchers.Unlock()
+
+	stop := func() {
+		watchers.Lock()
+		defer watchers.Unlock()
+		if _, ok := watchers.byOrder[orderID][updates]; ok {
+			delete(watchers.byOrder[orderID], updates)
+			close(updates)
+		}
+		if len(watchers.byOrder[orderID]) == 0 {
+			delete(watchers.byOrder, orderID)
+		}
+	}
+
+	return updates, stop, nil
+}
+
+// notifyWatchers publishes an order change to its watchers. It must be called
+// with the store locked so updates are delivered in the order they happened.
+func notifyWatchers(order models.Order) {
+	watchers.Lock()
+	defer watchers.Unlock()
+
+	for updates := range watchers.byOrder[order.ID] {
+		deliver(updates, order)
+		if order.Status.Final() {
+			close(updates)
+		}
+	}
+	if order.Status.Final() {
+		delete(watchers.byOrder, order.ID)
+	}
+}
+
+// deliver sends an update without blocking the publisher. A watcher that has
+// fallen behind loses its oldest pending update instead, so the latest state,
+// and the final one in particular, always reaches it.
+func deliver(updates chan models.Order, order models.Order) {
+	for {
+		select {
+		case updates <- order:
+			return
+		default:
+		}
+		// only the publisher sends, so this frees a slot for the next attempt
+		select {
+		case <-updates:
+		default:
+		}
+	}
+}
+
+// CloseWatchers ends every subscription, as though each watched order were
+// done with, so streaming clients finish before the server shuts down
+func CloseWatchers() {
+	watchers.Lock()
+	defer watchers.Unlock()
+
+	for orderID, subscribers := range watchers.byOrder {
+		for updates := range subscribers {
+			close(updates)
+		}
+		delete(watchers.byOrder, orderID)
+	}
+}
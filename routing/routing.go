@@ -0,0 +1,172 @@
+// Package routing plans multi-stop deliveries: it groups the orders waiting at
+// a restaurant into batches one courier can carry and orders the stops of each
+// batch, first by nearest neighbour and then by 2-opt improvement, so no
+// promised delivery time is missed that could have been kept.
+package routing
+
+import (
+	"slices"
+	"time"
+	"weservefood/models"
+)
+
+// Stop is an order to drop off
+type Stop struct {
+	OrderID  string
+	Location models.LatLng
+	// ReadyAt is when the order can leave the kitchen
+	ReadyAt time.Time
+	// DueAt is when the order was promised
+	DueAt time.Time
+}
+
+// Route is a courier's run from the pickup through its stops in order
+type Route struct {
+	Stops []Stop
+	// Arrivals holds when the courier reaches each stop
+	Arrivals []time.Time
+	// LegMetres holds the road distance ridden to reach each stop
+	LegMetres      []float64
+	DistanceMetres float64
+	// DepartAt is when the courier leaves the pickup: once every order is ready
+	DepartAt time.Time
+}
+
+// Lateness is the total time by which stops are reached after they are due
+func (r Route) Lateness() time.Duration {
+	var late time.Duration
+	for i, stop := range r.Stops {
+		late += max(r.Arrivals[i].Sub(stop.DueAt), 0)
+	}
+	return late
+}
+
+// OnTime reports whether every stop is reached by the time it is due
+func (r Route) OnTime() bool {
+	return r.Lateness() == 0
+}
+
+// Planner plans routes from a pickup point
+type Planner struct {
+	// Distance returns the road distance between two points in metres
+	Distance func(from, to models.LatLng) float64
+	// MetresPerSecond is the courier's average speed
+	MetresPerSecond float64
+	// Handover is spent at each stop before riding on
+	Handover time.Duration
+}
+
+// Plan orders the stops of one courier leaving from the pickup no earlier than
+// departAt: nearest neighbour first, then 2-opt moves while they make the
+// route less late, or as late and shorter
+func (p Planner) Plan(pickup models.LatLng, departAt time.Time, stops []Stop) Route {
+	for _, stop := range stops {
+		if stop.ReadyAt.After(departAt) {
+			departAt = stop.ReadyAt
+		}
+	}
+
+	best := p.route(pickup, departAt, p.nearestNeighbour(pickup, stops))
+	for improved := true; improved; {
+		improved = false
+		for i := 0; i < len(best.Stops)-1; i++ {
+			for j := i + 1; j < len(best.Stops); j++ {
+				candidate := slices.Clone(best.Stops)
+				slices.Reverse(candidate[i : j+1])
+				if route := p.route(pickup, departAt, candidate); better(route, best) {
+					best, improved = route, true
+				}
+			}
+		}
+	}
+	return best
+}
+
+// BatchSettings bound which orders may share a courier
+type BatchSettings struct {
+	// MaxStops is the most orders one courier carries
+	MaxStops int
+	// RadiusMetres is how far a drop-off may be from the batch's first one
+	RadiusMetres float64
+	// Window is how much later than the batch's first order another may be due
+	Window time.Duration
+}
+
+// Batch groups the stops into routes. Orders are taken in the order they are
+// due; each starts a batch, and the orders due after it that are close enough
+// join it as long as every stop stays on time. An order that cannot be
+// delivered on time even alone still gets a route of its own.
+func (p Planner) Batch(pickup models.LatLng, departAt time.Time, stops []Stop, settings BatchSettings) []Route {
+	waiting := slices.Clone(stops)
+	slices.SortStableFunc(waiting, func(a, b Stop) int { return a.DueAt.Compare(b.DueAt) })
+
+	var routes []Route
+	for len(waiting) > 0 {
+		seed := waiting[0]
+		batch := []Stop{seed}
+		route := p.Plan(pickup, departAt, batch)
+		rest := waiting[:0:0]
+		for _, stop := range waiting[1:] {
+			if len(batch) < settings.MaxStops &&
+				stop.DueAt.Sub(seed.DueAt) <= settings.Window &&
+				p.Distance(seed.Location, stop.Location) <= settings.RadiusMetres {
+				if trial := p.Plan(pickup, departAt, append(slices.Clone(batch), stop)); trial.OnTime() {
+					batch, route = append(batch, stop), trial
+					continue
+				}
+			}
+			rest = append(rest, stop)
+		}
+		routes = append(routes, route)
+		waiting = rest
+	}
+	return routes
+}
+
+// nearestNeighbour visits the closest stop not yet visited, starting from the pickup
+func (p Planner) nearestNeighbour(pickup models.LatLng, stops []Stop) []Stop {
+	left := slices.Clone(stops)
+	order := make([]Stop, 0, len(stops))
+	at := pickup
+	for len(left) > 0 {
+		closest := 0
+		for i := range left {
+			if p.Distance(at, left[i].Location) < p.Distance(at, left[closest].Location) {
+				closest = i
+			}
+		}
+		order = append(order, left[closest])
+		at = left[closest].Location
+		left = slices.Delete(left, closest, closest+1)
+	}
+	return order
+}
+
+// route times a run through the stops in the given order
+func (p Planner) route(pickup models.LatLng, departAt time.Time, stops []Stop) Route {
+	route := Route{
+		Stops:     stops,
+		Arrivals:  make([]time.Time, len(stops)),
+		LegMetres: make([]float64, len(stops)),
+		DepartAt:  departAt,
+	}
+	at, clock := pickup, departAt
+	for i, stop := range stops {
+		metres := p.Distance(at, stop.Location)
+		clock = clock.Add(time.Duration(metres / p.MetresPerSecond * float64(time.Second)))
+		route.Arrivals[i] = clock
+		route.LegMetres[i] = metres
+		route.DistanceMetres += metres
+		at, clock = stop.Location, clock.Add(p.Handover)
+	}
+	return route
+}
+
+// better prefers the route that is less late, then the shorter one
+func better(route, than Route) bool {
+	if late, thanLate := route.Lateness(), than.Lateness(); late != thanLate {
+		return late < thanLate
+	}
+	// a small margin keeps rounding from flipping between equal routes
+	return route.DistanceMetres < than.DistanceMetres-0.01
+}
@@ -0,0 +1,91 @@
+package routing
+
+import (
+	"math"
+	"testing"
+	"time"
+	"weservefood/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// grid measures distances on a flat grid of kilometre squares, and its
+// courier rides a metre a second
+var grid = Planner{
+	Distance:        func(from, to models.LatLng) float64 { return math.Hypot(from.Lat-to.Lat, from.Lng-to.Lng) * 1000 },
+	MetresPerSecond: 1,
+}
+
+var depart = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func stop(orderID string, lat, lng float64, due time.Duration) Stop {
+	return Stop{OrderID: orderID, Location: models.LatLng{Lat: lat, Lng: lng}, DueAt: depart.Add(due)}
+}
+
+func orderIDs(route Route) string {
+	ids := ""
+	for _, stop := range route.Stops {
+		ids += stop.OrderID
+	}
+	return ids
+}
+
+func TestPlanImprovesNearestNeighbour(t *testing.T) {
+	stops := []Stop{stop("A", 0, 3, 24*time.Hour), stop("B", 1, 2, 24*time.Hour), stop("C", 1, 3, 24*time.Hour), stop("D", 3, 1, 24*time.Hour)}
+
+	nearest := grid.route(models.LatLng{}, depart, grid.nearestNeighbour(models.LatLng{}, stops))
+	assert.Equal(t, "BCAD", orderIDs(nearest))
+
+	route := grid.Plan(models.LatLng{}, depart, stops)
+	assert.Equal(t, "ACBD", orderIDs(route))
+	assert.InDelta(t, 7236, route.DistanceMetres, 1)
+	assert.Less(t, route.DistanceMetres, nearest.DistanceMetres)
+	assert.Equal(t, []float64{3000, 1000, 1000}, route.LegMetres[:3])
+	assert.Equal(t, depart.Add(5*time.Second*1000), route.Arrivals[2])
+}
+
+func TestPlanKeepsPromises(t *testing.T) {
+	// B is further but due soon, so it goes first although the route is longer
+	stops := []Stop{stop("A", 1, 0, 3*time.Hour), stop("B", -2, 0, 35*time.Minute)}
+
+	route := grid.Plan(models.LatLng{}, depart, stops)
+	assert.Equal(t, "BA", orderIDs(route))
+	assert.True(t, route.OnTime())
+	assert.Equal(t, 5000.0, route.DistanceMetres)
+
+	// the courier only leaves once every order is ready, and hands each over
+	stops[0].ReadyAt = depart.Add(10 * time.Minute)
+	handover := grid
+	handover.Handover = 2 * time.Minute
+	route = handover.Plan(models.LatLng{}, depart, stops)
+	assert.Equal(t, depart.Add(10*time.Minute), route.DepartAt)
+	assert.Equal(t, depart.Add(10*time.Minute+2000*time.Second), route.Arrivals[0])
+	assert.Equal(t, depart.Add(12*time.Minute+5000*time.Second), route.Arrivals[1])
+	assert.False(t, route.OnTime())
+	assert.Equal(t, 10*time.Minute-100*time.Second, route.Lateness())
+}
+
+func TestBatch(t *testing.T) {
+	stops := []Stop{
+		stop("near-1", 1, 0, time.Hour),
+		stop("near-2", 1, 0.5, time.Hour+5*time.Minute),
+		stop("near-3", 1.2, 0.2, time.Hour+10*time.Minute),
+		stop("far", 8, 0, 4*time.Hour),
+		stop("later", 1, 0.1, 5*time.Hour),
+		stop("urgent", 1, 0.2, time.Minute),
+	}
+
+	routes := grid.Batch(models.LatLng{}, depart, stops, BatchSettings{MaxStops: 2, RadiusMetres: 1000, Window: 30 * time.Minute})
+	var batches []string
+	for _, route := range routes {
+		batches = append(batches, orderIDs(route))
+	}
+	// urgent cannot be reached in a minute, so it rides alone
+	assert.Equal(t, []string{"urgent", "near-1near-2", "near-3", "far", "later"}, batches)
+
+	routes = grid.Batch(models.LatLng{}, depart, stops[:3], BatchSettings{MaxStops: 3, RadiusMetres: 1000, Window: 30 * time.Minute})
+	require.Len(t, routes, 1)
+	assert.Len(t, routes[0].Stops, 3)
+	assert.True(t, routes[0].OnTime())
+}
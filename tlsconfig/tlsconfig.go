@@ -0,0 +1,189 @@
+// Package tlsconfig builds the TLS configuration of the HTTPS listener,
+// reloads its certificate when the files are rotated on disk, and redirects
+// plain HTTP requests to HTTPS.
+package tlsconfig
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"weservefood/config"
+)
+
+// Minimum versions accepted in tls.min_version
+var minVersions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// strictCipherSuites are the TLS 1.2 suites with forward secrecy and AEAD
+// encryption; TLS 1.3 suites are not configurable and are always allowed
+var strictCipherSuites = []uint16{
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+}
+
+// New returns the server TLS configuration, serving the reloader's current
+// certificate. Serving it with http.Server.ServeTLS negotiates HTTP/2.
+func New(settings config.TLS, reloader *Reloader) (*tls.Config, error) {
+	minVersion, ok := minVersions[settings.MinVersion]
+	if !ok {
+		return nil, fmt.Errorf("unknown TLS version %q", settings.MinVersion)
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:     minVersion,
+		GetCertificate: reloader.GetCertificate,
+		NextProtos:     []string{"h2", "http/1.1"},
+	}
+	switch settings.CipherPolicy {
+	case config.CipherPolicyDefault:
+	case config.CipherPolicyStrict:
+		tlsConfig.CipherSuites = strictCipherSuites
+	default:
+		return nil, fmt.Errorf("unknown cipher policy %q", settings.CipherPolicy)
+	}
+	return tlsConfig, nil
+}
+
+// Reloader serves a certificate and key pair from disk, loading it again
+// whenever either file changes
+type Reloader struct {
+	certFile, keyFile string
+
+	mu       sync.RWMutex
+	cert     *tls.Certificate
+	modTimes [2]time.Time
+	err      error
+}
+
+// NewReloader loads the certificate and key files; they must be valid at startup
+func NewReloader(certFile, keyFile string) (*Reloader, error) {
+	r := &Reloader{certFile: certFile, keyFile: keyFile}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// GetCertificate returns the current certificate, for tls.Config.GetCertificate
+func (r *Reloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+// Reload loads the files again. A pair that fails to load leaves the previous
+// certificate in place.
+func (r *Reloader) Reload() error {
+	modTimes, err := r.stat()
+	if err == nil {
+		var cert tls.Certificate
+		cert, err = tls.LoadX509KeyPair(r.certFile, r.keyFile)
+		if err == nil {
+			r.mu.Lock()
+			r.cert, r.modTimes, r.err = &cert, modTimes, nil
+			r.mu.Unlock()
+			return nil
+		}
+	}
+
+	r.mu.Lock()
+	r.err = err
+	r.mu.Unlock()
+	return err
+}
+
+// Watch reloads the certificate whenever the files change, checking at each
+// interval until ctx is done
+func (r *Reloader) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			modTimes, err := r.stat()
+			r.mu.RLock()
+			changed := err != nil || modTimes != r.modTimes
+			r.mu.RUnlock()
+			if !changed {
+				continue
+			}
+			if err := r.Reload(); err != nil {
+				slog.Error("unable to reload TLS certificate", slog.String("cert_file", r.certFile), slog.Any("error", err))
+				continue
+			}
+			slog.Info("TLS certificate reloaded", slog.String("cert_file", r.certFile))
+		}
+	}
+}
+
+// Check fails when the last reload failed or the certificate has expired
+func (r *Reloader) Check(ctx context.Context) (map[string]string, error) {
+	r.mu.RLock()
+	cert, err := r.cert, r.err
+	r.mu.RUnlock()
+
+	leaf, parseErr := x509.ParseCertificate(cert.Certificate[0])
+	if parseErr != nil {
+		return nil, parseErr
+	}
+	details := map[string]string{
+		"subject":   leaf.Subject.String(),
+		"not_after": leaf.NotAfter.UTC().Format(time.RFC3339),
+	}
+	switch {
+	case err != nil:
+		return details, fmt.Errorf("last reload failed: %w", err)
+	case time.Now().After(leaf.NotAfter):
+		return details, errors.New("certificate has expired")
+	}
+	return details, nil
+}
+
+func (r *Reloader) stat() ([2]time.Time, error) {
+	var modTimes [2]time.Time
+	for i, name := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(name)
+		if err != nil {
+			return modTimes, err
+		}
+		modTimes[i] = info.ModTime()
+	}
+	return modTimes, nil
+}
+
+// RedirectHandler permanently redirects every request to the same host and
+// path on the HTTPS address
+func RedirectHandler(httpsAddr string) http.Handler {
+	_, port, _ := net.SplitHostPort(httpsAddr)
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		host := strings.Trim(req.Host, "[]")
+		if h, _, err := net.SplitHostPort(req.Host); err == nil {
+			host = h
+		}
+		if port != "443" {
+			host = net.JoinHostPort(host, port)
+		}
+
+		target := "https://" + host + req.URL.RequestURI()
+		// 308 keeps the method and body, so a POST is not turned into a GET
+		http.Redirect(rw, req, target, http.StatusPermanentRedirect)
+	})
+}
@@ -0,0 +1,178 @@
+package tlsconfig
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"weservefood/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeCertificate writes a self-signed certificate for 127.0.0.1 and its key
+func writeCertificate(t *testing.T, dir, commonName string, notAfter time.Time) (certFile, keyFile string, cert *x509.Certificate) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              notAfter,
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile, keyFile = filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+	cert, err = x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return certFile, keyFile, cert
+}
+
+func currentCommonName(t *testing.T, r *Reloader) string {
+	cert, err := r.GetCertificate(nil)
+	require.NoError(t, err)
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+	return leaf.Subject.CommonName
+}
+
+func TestNew(t *testing.T) {
+	certFile, keyFile, _ := writeCertificate(t, t.TempDir(), "api", time.Now().Add(time.Hour))
+	reloader, err := NewReloader(certFile, keyFile)
+	require.NoError(t, err)
+
+	tlsConfig, err := New(config.Default().TLS, reloader)
+	require.NoError(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS12), tlsConfig.MinVersion)
+	assert.Nil(t, tlsConfig.CipherSuites)
+	assert.Contains(t, tlsConfig.NextProtos, "h2")
+
+	strict := config.TLS{MinVersion: "1.3", CipherPolicy: config.CipherPolicyStrict}
+	tlsConfig, err = New(strict, reloader)
+	require.NoError(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS13), tlsConfig.MinVersion)
+	assert.Equal(t, strictCipherSuites, tlsConfig.CipherSuites)
+
+	_, err = New(config.TLS{MinVersion: "1.0", CipherPolicy: config.CipherPolicyDefault}, reloader)
+	assert.Error(t, err)
+}
+
+func TestServeHTTP2(t *testing.T) {
+	certFile, keyFile, cert := writeCertificate(t, t.TempDir(), "api", time.Now().Add(time.Hour))
+	reloader, err := NewReloader(certFile, keyFile)
+	require.NoError(t, err)
+	tlsConfig, err := New(config.Default().TLS, reloader)
+	require.NoError(t, err)
+
+	server := &http.Server{
+		TLSConfig: tlsConfig,
+		Handler: http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.Write([]byte(req.Proto))
+		}),
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go server.ServeTLS(listener, "", "")
+	t.Cleanup(func() { server.Close() })
+
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}, ForceAttemptHTTP2: true}}
+	resp, err := client.Get("https://" + listener.Addr().String() + "/")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, 2, resp.ProtoMajor)
+}
+
+func TestReloaderWatchesRotation(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile, _ := writeCertificate(t, dir, "before", time.Now().Add(time.Hour))
+	reloader, err := NewReloader(certFile, keyFile)
+	require.NoError(t, err)
+	assert.Equal(t, "before", currentCommonName(t, reloader))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go reloader.Watch(ctx, 10*time.Millisecond)
+
+	writeCertificate(t, dir, "after", time.Now().Add(time.Hour))
+	// make the change visible on filesystems with coarse modification times
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(certFile, later, later))
+
+	assert.Eventually(t, func() bool {
+		return currentCommonName(t, reloader) == "after"
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestReloaderKeepsCertificateOnFailure(t *testing.T) {
+	certFile, keyFile, _ := writeCertificate(t, t.TempDir(), "kept", time.Now().Add(time.Hour))
+	reloader, err := NewReloader(certFile, keyFile)
+	require.NoError(t, err)
+	_, err = reloader.Check(context.Background())
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(keyFile, []byte("half-written key"), 0o600))
+	assert.Error(t, reloader.Reload())
+	assert.Equal(t, "kept", currentCommonName(t, reloader))
+
+	details, err := reloader.Check(context.Background())
+	assert.Error(t, err)
+	assert.Equal(t, "CN=kept", details["subject"])
+
+	_, err = NewReloader(certFile, keyFile)
+	assert.Error(t, err)
+}
+
+func TestCheckExpired(t *testing.T) {
+	certFile, keyFile, _ := writeCertificate(t, t.TempDir(), "old", time.Now().Add(-time.Minute))
+	reloader, err := NewReloader(certFile, keyFile)
+	require.NoError(t, err)
+
+	_, err = reloader.Check(context.Background())
+	assert.EqualError(t, err, "certificate has expired")
+}
+
+func TestRedirectHandler(t *testing.T) {
+	tests := []struct {
+		httpsAddr, host, target, want string
+	}{
+		{":8443", "api.example.com:8383", "/v1/orders?status=placed", "https://api.example.com:8443/v1/orders?status=placed"},
+		{":443", "api.example.com", "/ping", "https://api.example.com/ping"},
+		{"0.0.0.0:8443", "[::1]", "/", "https://[::1]:8443/"},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, tt.target, nil)
+		req.Host = tt.host
+		rr := httptest.NewRecorder()
+		RedirectHandler(tt.httpsAddr).ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusPermanentRedirect, rr.Code)
+		assert.Equal(t, tt.want, rr.Header().Get("Location"))
+	}
+}
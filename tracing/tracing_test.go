@@ -0,0 +1,75 @@
+package tracing_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"weservefood/handler"
+	"weservefood/models"
+	"weservefood/repository"
+	"weservefood/tracing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gorilla/mux/otelmux"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestSetupStdout(t *testing.T) {
+	var out bytes.Buffer
+	shutdown, err := tracing.Setup(context.Background(), tracing.ExporterStdout, &out)
+	require.NoError(t, err)
+
+	_, span := tracing.Tracer("test").Start(context.Background(), "stdout-span")
+	span.End()
+	require.NoError(t, shutdown(context.Background()))
+
+	assert.Contains(t, out.String(), "stdout-span")
+	assert.Contains(t, out.String(), tracing.ServiceName)
+}
+
+func TestSetupUnknownExporter(t *testing.T) {
+	_, err := tracing.Setup(context.Background(), "zipkin", nil)
+	assert.Error(t, err)
+}
+
+func TestTraceContextPropagation(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := tracing.NewProvider(exporter, sdktrace.WithSyncer(exporter))
+	otel.SetTracerProvider(provider)
+	_, err := tracing.Setup(context.Background(), tracing.ExporterNone, nil)
+	require.NoError(t, err)
+
+	order, err := repository.CreateOrder(context.Background(), models.Order{Email: "trace@example.com", Address: "1 Main St"})
+	require.NoError(t, err)
+	exporter.Reset()
+
+	router := mux.NewRouter()
+	router.Use(otelmux.Middleware(tracing.ServiceName))
+	router.HandleFunc("/v1/orders/{id}", handler.GetOrderV1).Methods("GET")
+
+	req, _ := http.NewRequest(http.MethodGet, "/v1/orders/"+order.ID, nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	spans := exporter.GetSpans()
+	byName := make(map[string]tracetest.SpanStub)
+	for _, span := range spans {
+		byName[span.Name] = span
+	}
+	server, ok := byName["/v1/orders/{id}"]
+	require.True(t, ok, "router span missing from %v", spans)
+	lookup, ok := byName["repository.GetOrderByID"]
+	require.True(t, ok, "repository span missing from %v", spans)
+
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", server.SpanContext.TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", server.Parent.SpanID().String())
+	assert.Equal(t, server.SpanContext.SpanID(), lookup.Parent.SpanID())
+}